@@ -0,0 +1,250 @@
+package main
+
+import (
+	"errors"
+	"time"
+
+	lua "github.com/yuin/gopher-lua"
+	luar "layeh.com/gopher-luar"
+
+	"github.com/zyedidia/micro/v2/internal/config"
+	ulua "github.com/zyedidia/micro/v2/internal/lua"
+	"github.com/zyedidia/micro/v2/internal/screen"
+	"github.com/zyedidia/micro/v2/internal/shell"
+)
+
+// A Task runs a Lua function in a coroutine started by micro.Async. The
+// function can wait without blocking the editor with micro.Sleep and
+// shell.Await, which yield the coroutine until the main loop resumes it.
+type Task struct {
+	co     *lua.LState
+	fn     *lua.LFunction
+	plugin *config.Plugin
+	done   bool
+	// cancel stops what the task is waiting for
+	cancel func()
+}
+
+// tasks are the tasks which have not finished, by coroutine
+var tasks = make(map[*lua.LState]*Task)
+
+// schedule runs f on the main loop
+func schedule(f func()) {
+	go func() {
+		timerChan <- f
+	}()
+}
+
+// startTask starts a task running fn with the given arguments on the main
+// loop
+func startTask(p *config.Plugin, fn *lua.LFunction, args ...lua.LValue) *Task {
+	co, cancel := ulua.L.NewThread()
+	if cancel != nil {
+		// the coroutine must not share the time limit of its creator
+		co.RemoveContext()
+		cancel()
+	}
+	t := &Task{co: co, fn: fn, plugin: p}
+	tasks[co] = t
+	schedule(func() { t.resume(args...) })
+	return t
+}
+
+// resume resumes the task on the main loop with the values which the
+// function it waits for returns
+func (t *Task) resume(args ...lua.LValue) {
+	if t.done {
+		return
+	}
+	t.cancel = nil
+
+	var state lua.ResumeState
+	var err error
+	if t.plugin != nil {
+		state, err = t.plugin.Resume("task", t.co, t.fn, args...)
+		if err != nil {
+			err = errors.New("Plugin " + t.plugin.Name + ": " + err.Error())
+		}
+	} else {
+		state, err, _ = ulua.L.Resume(t.co, t.fn, args...)
+	}
+	if err != nil {
+		screen.TermMessage(err)
+	}
+	if state != lua.ResumeYield || t.plugin != nil && t.plugin.Disabled {
+		t.Cancel()
+	}
+}
+
+// Cancel stops the task, which is not resumed anymore, and what it waits for
+func (t *Task) Cancel() {
+	if t.done {
+		return
+	}
+	t.done = true
+	delete(tasks, t.co)
+	if t.cancel != nil {
+		t.cancel()
+	}
+}
+
+// IsDone returns whether the task has finished or was canceled
+func (t *Task) IsDone() bool {
+	return t.done
+}
+
+// currentTask returns the task running in the given coroutine, and raises
+// an error if there is none
+func currentTask(L *lua.LState, fn string) *Task {
+	t, ok := tasks[L]
+	if !ok || t.done {
+		L.RaiseError("%s can only be called in a task started by micro.Async", fn)
+	}
+	return t
+}
+
+// A Timer runs a Lua function on the main loop after a delay, once or
+// repeatedly
+type Timer struct {
+	timer   *time.Timer
+	plugin  *config.Plugin
+	stopped bool
+}
+
+// timers are the timers which have not been stopped
+var timers = make(map[*Timer]bool)
+
+func startTimer(p *config.Plugin, d time.Duration, repeat bool, fn *lua.LFunction) *Timer {
+	t := &Timer{plugin: p}
+	timers[t] = true
+	var run func()
+	run = func() {
+		if t.stopped {
+			return
+		}
+		if !repeat {
+			t.Stop()
+		}
+		var err error
+		if p != nil {
+			_, err = p.CallFunction("timer", fn)
+			if err != nil {
+				err = errors.New("Plugin " + p.Name + ": " + err.Error())
+			}
+		} else {
+			err = ulua.L.CallByParam(lua.P{Fn: fn, NRet: 0, Protect: true})
+		}
+		if err != nil {
+			screen.TermMessage(err)
+		}
+		if p != nil && p.Disabled {
+			t.Stop()
+		}
+		if !t.stopped {
+			t.timer.Reset(d)
+		}
+	}
+	t.timer = time.AfterFunc(d, func() {
+		timerChan <- run
+	})
+	return t
+}
+
+// Stop stops the timer, whose function is not run anymore
+func (t *Timer) Stop() {
+	t.stopped = true
+	t.timer.Stop()
+	delete(timers, t)
+}
+
+// stopPluginAsync cancels the tasks and stops the timers of a plugin
+func stopPluginAsync(p *config.Plugin) {
+	for _, t := range tasks {
+		if t.plugin == p {
+			t.Cancel()
+		}
+	}
+	for t := range timers {
+		if t.plugin == p {
+			t.Stop()
+		}
+	}
+}
+
+// luaAsync returns the micro.Async function for the given plugin, which
+// starts a task running a function with the given arguments
+func luaAsync(p *config.Plugin) *lua.LFunction {
+	return ulua.L.NewFunction(func(L *lua.LState) int {
+		fn := L.CheckFunction(1)
+		var args []lua.LValue
+		for i := 2; i <= L.GetTop(); i++ {
+			args = append(args, L.Get(i))
+		}
+		L.Push(luar.New(L, startTask(p, fn, args...)))
+		return 1
+	})
+}
+
+// luaTimer returns the micro.SetTimeout or micro.SetInterval function for
+// the given plugin, which runs a function after a delay in milliseconds
+func luaTimer(p *config.Plugin, repeat bool) *lua.LFunction {
+	return ulua.L.NewFunction(func(L *lua.LState) int {
+		ms := L.CheckInt(1)
+		fn := L.CheckFunction(2)
+		if repeat && ms <= 0 {
+			L.ArgError(1, "the interval must be positive")
+		}
+		L.Push(luar.New(L, startTimer(p, time.Duration(ms)*time.Millisecond, repeat, fn)))
+		return 1
+	})
+}
+
+// luaSleep is micro.Sleep, which suspends the current task for the given
+// number of milliseconds
+func luaSleep(L *lua.LState) int {
+	ms := L.CheckInt(1)
+	t := currentTask(L, "micro.Sleep")
+	timer := time.AfterFunc(time.Duration(ms)*time.Millisecond, func() {
+		timerChan <- func() { t.resume() }
+	})
+	t.cancel = func() { timer.Stop() }
+	return L.Yield()
+}
+
+// luaAwait is shell.Await, which suspends the current task until the
+// process started by shell.Spawn exits, and returns its standard output,
+// exit code and standard error. The process is killed if the task is
+// canceled. As shell.Await(shell.Spawn(...)) is also passed the error of
+// Spawn, it returns an exit code of -1 and the error if there is one.
+func luaAwait(L *lua.LState) int {
+	if L.Get(1) == lua.LNil {
+		// the error of Spawn is a Go error wrapped by luar
+		errmsg := ""
+		if ud, ok := L.Get(2).(*lua.LUserData); ok {
+			if err, ok := ud.Value.(error); ok {
+				errmsg = err.Error()
+			}
+		} else if err := L.Get(2); err != lua.LNil {
+			errmsg = L.ToStringMeta(err).String()
+		}
+		L.Push(lua.LString(""))
+		L.Push(lua.LNumber(-1))
+		L.Push(lua.LString(errmsg))
+		return 3
+	}
+	ud := L.CheckUserData(1)
+	proc, ok := ud.Value.(*shell.Proc)
+	if !ok {
+		L.ArgError(1, "process expected")
+	}
+	t := currentTask(L, "shell.Await")
+	go func() {
+		<-proc.Done()
+		timerChan <- func() {
+			out, code, errout := proc.Result()
+			t.resume(lua.LString(out), lua.LNumber(code), lua.LString(errout))
+		}
+	}()
+	t.cancel = proc.Kill
+	return L.Yield()
+}
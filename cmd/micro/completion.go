@@ -0,0 +1,77 @@
+package main
+
+import (
+	lua "github.com/yuin/gopher-lua"
+	luar "layeh.com/gopher-luar"
+
+	"github.com/zyedidia/micro/v2/internal/action"
+	"github.com/zyedidia/micro/v2/internal/buffer"
+	"github.com/zyedidia/micro/v2/internal/config"
+	ulua "github.com/zyedidia/micro/v2/internal/lua"
+)
+
+// luaCompletionItems converts the items returned by the function of a
+// completion source of a plugin, a list of tables or of labels
+func luaCompletionItems(ret lua.LValue) []*buffer.CompletionItem {
+	list, ok := ret.(*lua.LTable)
+	if !ok {
+		return nil
+	}
+	var items []*buffer.CompletionItem
+	list.ForEach(func(_, v lua.LValue) {
+		if s, ok := v.(lua.LString); ok {
+			items = append(items, &buffer.CompletionItem{Label: string(s), Start: -1})
+			return
+		}
+		t, ok := v.(*lua.LTable)
+		if !ok {
+			return
+		}
+		str := func(key string) string {
+			if s, ok := t.RawGetString(key).(lua.LString); ok {
+				return string(s)
+			}
+			return ""
+		}
+		it := &buffer.CompletionItem{
+			Label:   str("label"),
+			Insert:  str("insert"),
+			Snippet: lua.LVAsBool(t.RawGetString("snippet")),
+			Kind:    str("kind"),
+			Detail:  str("detail"),
+			Doc:     str("doc"),
+			Start:   -1,
+		}
+		if n, ok := t.RawGetString("start").(lua.LNumber); ok {
+			it.Start = int(n)
+		}
+		if it.Label != "" {
+			items = append(items, it)
+		}
+	})
+	return items
+}
+
+// luaRegisterCompletionSource returns micro/buffer.RegisterCompletionSource
+// for a plugin, whose sources are unregistered when it is unloaded
+func luaRegisterCompletionSource(p *config.Plugin) *lua.LFunction {
+	return ulua.L.NewFunction(func(L *lua.LState) int {
+		name := L.CheckString(1)
+		fn := L.CheckFunction(2)
+		priority := L.OptInt(3, 50)
+		buffer.RegisterCompletionSource(&buffer.CompletionSource{
+			Name:     name,
+			Priority: priority,
+			Complete: func(b *buffer.Buffer, word string) []*buffer.CompletionItem {
+				ret, err := p.CallFunction(name, fn, luar.New(ulua.L, b), lua.LString(word))
+				if err != nil {
+					action.InfoBar.Error(err)
+					return nil
+				}
+				return luaCompletionItems(ret)
+			},
+		})
+		p.OnUnload(func() { buffer.UnregisterCompletionSource(name) })
+		return 0
+	})
+}
@@ -19,7 +19,85 @@ import (
 
 func init() {
 	ulua.L = lua.NewState()
-	ulua.L.SetGlobal("import", luar.New(ulua.L, LuaImport))
+	// Lua code which does not belong to a plugin gets no capability
+	ulua.L.SetGlobal("import", luar.New(ulua.L, func(pkg string) *lua.LTable {
+		return pluginImport(nil, pkg)
+	}))
+	config.PluginImporter = pluginImport
+	config.OnCapabilityDenied = func(msg string) {
+		log.Println(msg)
+		if buffer.LogBuf != nil {
+			buffer.WriteLog(msg + "\n")
+		}
+	}
+}
+
+// pluginImport imports the given package for a plugin, which can only
+// use the functions needing the capabilities it was granted
+func pluginImport(p *config.Plugin, pkg string) *lua.LTable {
+	tbl := LuaImport(pkg)
+	if tbl == nil {
+		return nil
+	}
+
+	name := "<unknown>"
+	granted := func(string) bool { return false }
+	if p != nil {
+		name = p.Name
+		granted = p.HasCapability
+	}
+	ulua.Restrict(pkg, tbl, name, granted, config.OnCapabilityDenied)
+
+	// what the plugin registers is undone when it is unloaded
+	if p != nil && pkg == "micro" {
+		ulua.L.SetField(tbl, "On", luaOn(p))
+		ulua.L.SetField(tbl, "NewPopup", luar.New(ulua.L, func(text, anchor string, width, height int) (*action.Popup, error) {
+			popup, err := action.NewPopup(text, anchor, width, height)
+			if err == nil {
+				p.OnUnload(popup.Close)
+			}
+			return popup, err
+		}))
+		ulua.L.SetField(tbl, "After", luar.New(ulua.L, func(t time.Duration, fn *lua.LFunction) {
+			startTimer(p, t, false, fn)
+		}))
+		ulua.L.SetField(tbl, "Async", luaAsync(p))
+		ulua.L.SetField(tbl, "SetTimeout", luaTimer(p, false))
+		ulua.L.SetField(tbl, "SetInterval", luaTimer(p, true))
+		p.OnUnload(func() { stopPluginAsync(p) })
+	}
+	if p != nil && pkg == "micro/buffer" {
+		ulua.L.SetField(tbl, "RegisterCompletionSource", luaRegisterCompletionSource(p))
+		ulua.L.SetField(tbl, "RegisterCompleter", luar.New(ulua.L, func(name string, completer buffer.Completer, priority int, kind string) {
+			if completer == nil {
+				return
+			}
+			buffer.RegisterCompletionSource(completer.Source(name, priority, kind))
+			p.OnUnload(func() { buffer.UnregisterCompletionSource(name) })
+		}))
+	}
+	if p != nil && pkg == "micro/config" {
+		ulua.L.SetField(tbl, "MakeCommand", luar.New(ulua.L, func(name string, fn *lua.LFunction, completer buffer.Completer, desc ...string) {
+			if fn == nil {
+				return
+			}
+			action.MakePluginCommand(p, name, func(bp *action.BufPane, args []string) {
+				_, err := p.CallFunction(name, fn, luar.New(ulua.L, bp), luar.New(ulua.L, args))
+				if err != nil {
+					screen.TermMessage("Plugin " + p.Name + ": " + err.Error())
+				}
+			}, completer, desc...)
+		}))
+		ulua.L.SetField(tbl, "TryBindKey", luar.New(ulua.L, func(k, v string, overwrite bool) (bool, error) {
+			return action.TryBindPluginKey(p, k, v, overwrite)
+		}))
+		ulua.L.SetField(tbl, "AddRuntimeFileFromMemory", luar.New(ulua.L, func(filetype config.RTFiletype, filename, data string) {
+			p.TrackRuntimeFiles(filetype, func() {
+				config.PluginAddRuntimeFileFromMemory(filetype, filename, data)
+			})
+		}))
+	}
+	return tbl
 }
 
 // LuaImport is meant to be called from lua by a plugin and will import the given micro package
@@ -43,8 +121,12 @@ func LuaImport(pkg string) *lua.LTable {
 func luaImportMicro() *lua.LTable {
 	pkg := ulua.L.NewTable()
 
-	ulua.L.SetField(pkg, "TermMessage", luar.New(ulua.L, screen.TermMessage))
-	ulua.L.SetField(pkg, "TermError", luar.New(ulua.L, screen.TermError))
+	ulua.L.SetField(pkg, "TermMessage", luar.New(ulua.L, func(msg ...interface{}) {
+		ulua.Unlimited(func() { screen.TermMessage(msg...) })
+	}))
+	ulua.L.SetField(pkg, "TermError", luar.New(ulua.L, func(filename string, lineNum int, err string) {
+		ulua.Unlimited(func() { screen.TermError(filename, lineNum, err) })
+	}))
 	ulua.L.SetField(pkg, "InfoBar", luar.New(ulua.L, action.GetInfoBar))
 	ulua.L.SetField(pkg, "Log", luar.New(ulua.L, log.Println))
 	ulua.L.SetField(pkg, "SetStatusInfoFn", luar.New(ulua.L, display.SetStatusInfoFnLua))
@@ -60,10 +142,34 @@ func luaImportMicro() *lua.LTable {
 			timerChan <- f
 		})
 	}))
+	ulua.L.SetField(pkg, "On", luaOn(nil))
+	ulua.L.SetField(pkg, "NewPopup", luar.New(ulua.L, action.NewPopup))
+	ulua.L.SetField(pkg, "Async", luaAsync(nil))
+	ulua.L.SetField(pkg, "Sleep", ulua.L.NewFunction(luaSleep))
+	ulua.L.SetField(pkg, "SetTimeout", luaTimer(nil, false))
+	ulua.L.SetField(pkg, "SetInterval", luaTimer(nil, true))
 
 	return pkg
 }
 
+// luaOn returns the micro.On function for the given plugin, which subscribes
+// a Lua function to an event with an optional priority and returns a
+// function which removes the subscription
+func luaOn(p *config.Plugin) *lua.LFunction {
+	return ulua.L.NewFunction(func(L *lua.LState) int {
+		event := L.CheckString(1)
+		fn := L.CheckFunction(2)
+		priority := L.OptInt(3, 0)
+		unsubscribe, err := config.SubscribeLua(p, event, priority, fn)
+		if err != nil {
+			L.ArgError(1, err.Error())
+			return 0
+		}
+		L.Push(luar.New(L, unsubscribe))
+		return 1
+	})
+}
+
 func luaImportMicroConfig() *lua.LTable {
 	pkg := ulua.L.NewTable()
 
@@ -84,6 +190,7 @@ func luaImportMicroConfig() *lua.LTable {
 	ulua.L.SetField(pkg, "RTColorscheme", luar.New(ulua.L, config.RTColorscheme))
 	ulua.L.SetField(pkg, "RTSyntax", luar.New(ulua.L, config.RTSyntax))
 	ulua.L.SetField(pkg, "RTHelp", luar.New(ulua.L, config.RTHelp))
+	ulua.L.SetField(pkg, "RTSnippet", luar.New(ulua.L, config.RTSnippet))
 	ulua.L.SetField(pkg, "RTPlugin", luar.New(ulua.L, config.RTPlugin))
 	ulua.L.SetField(pkg, "RegisterCommonOption", luar.New(ulua.L, config.RegisterCommonOptionPlug))
 	ulua.L.SetField(pkg, "RegisterGlobalOption", luar.New(ulua.L, config.RegisterGlobalOptionPlug))
@@ -98,14 +205,28 @@ func luaImportMicroConfig() *lua.LTable {
 func luaImportMicroShell() *lua.LTable {
 	pkg := ulua.L.NewTable()
 
-	ulua.L.SetField(pkg, "ExecCommand", luar.New(ulua.L, shell.ExecCommand))
-	ulua.L.SetField(pkg, "RunCommand", luar.New(ulua.L, shell.RunCommand))
+	// the commands run by these functions do not count in the time limit
+	// of the plugin's callback
+	ulua.L.SetField(pkg, "ExecCommand", luar.New(ulua.L, func(name string, arg ...string) (out string, err error) {
+		ulua.Unlimited(func() { out, err = shell.ExecCommand(name, arg...) })
+		return
+	}))
+	ulua.L.SetField(pkg, "RunCommand", luar.New(ulua.L, func(input string) (out string, err error) {
+		ulua.Unlimited(func() { out, err = shell.RunCommand(input) })
+		return
+	}))
 	ulua.L.SetField(pkg, "RunBackgroundShell", luar.New(ulua.L, shell.RunBackgroundShell))
-	ulua.L.SetField(pkg, "RunInteractiveShell", luar.New(ulua.L, shell.RunInteractiveShell))
+	ulua.L.SetField(pkg, "RunInteractiveShell", luar.New(ulua.L, func(input string, wait bool, getOutput bool) (out string, err error) {
+		ulua.Unlimited(func() { out, err = shell.RunInteractiveShell(input, wait, getOutput) })
+		return
+	}))
 	ulua.L.SetField(pkg, "JobStart", luar.New(ulua.L, shell.JobStart))
 	ulua.L.SetField(pkg, "JobSpawn", luar.New(ulua.L, shell.JobSpawn))
 	ulua.L.SetField(pkg, "JobStop", luar.New(ulua.L, shell.JobStop))
 	ulua.L.SetField(pkg, "JobSend", luar.New(ulua.L, shell.JobSend))
+	ulua.L.SetField(pkg, "Spawn", luar.New(ulua.L, shell.Spawn))
+	ulua.L.SetField(pkg, "SpawnWithStdin", luar.New(ulua.L, shell.SpawnWithStdin))
+	ulua.L.SetField(pkg, "Await", ulua.L.NewFunction(luaAwait))
 	ulua.L.SetField(pkg, "RunTermEmulator", luar.New(ulua.L, action.RunTermEmulator))
 	ulua.L.SetField(pkg, "TermEmuSupported", luar.New(ulua.L, action.TermEmuSupported))
 
@@ -120,6 +241,10 @@ func luaImportMicroBuffer() *lua.LTable {
 	ulua.L.SetField(pkg, "MTInfo", luar.New(ulua.L, buffer.MTInfo))
 	ulua.L.SetField(pkg, "MTWarning", luar.New(ulua.L, buffer.MTWarning))
 	ulua.L.SetField(pkg, "MTError", luar.New(ulua.L, buffer.MTError))
+	ulua.L.SetField(pkg, "NewOverlay", luar.New(ulua.L, buffer.NewOverlay))
+	ulua.L.SetField(pkg, "NewVirtualText", luar.New(ulua.L, buffer.NewVirtualText))
+	ulua.L.SetField(pkg, "VTInline", luar.New(ulua.L, buffer.VTInline))
+	ulua.L.SetField(pkg, "VTEndOfLine", luar.New(ulua.L, buffer.VTEndOfLine))
 	ulua.L.SetField(pkg, "Loc", luar.New(ulua.L, func(x, y int) buffer.Loc {
 		return buffer.Loc{x, y}
 	}))
@@ -141,6 +266,11 @@ func luaImportMicroBuffer() *lua.LTable {
 	ulua.L.SetField(pkg, "ByteOffset", luar.New(ulua.L, buffer.ByteOffset))
 	ulua.L.SetField(pkg, "Log", luar.New(ulua.L, buffer.WriteLog))
 	ulua.L.SetField(pkg, "LogBuf", luar.New(ulua.L, buffer.GetLogBuf))
+	ulua.L.SetField(pkg, "UnregisterCompletionSource", luar.New(ulua.L, buffer.UnregisterCompletionSource))
+	ulua.L.SetField(pkg, "CKWord", luar.New(ulua.L, buffer.CKWord))
+	ulua.L.SetField(pkg, "CKFile", luar.New(ulua.L, buffer.CKFile))
+	ulua.L.SetField(pkg, "CKSnippet", luar.New(ulua.L, buffer.CKSnippet))
+	ulua.L.SetField(pkg, "CKKeyword", luar.New(ulua.L, buffer.CKKeyword))
 
 	return pkg
 }
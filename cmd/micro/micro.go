@@ -11,8 +11,8 @@ import (
 	"regexp"
 	"runtime"
 	"runtime/pprof"
-	"sort"
 	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
@@ -31,20 +31,31 @@ import (
 
 var (
 	// Command line flags
-	flagVersion   = flag.Bool("version", false, "Show the version number and information")
-	flagConfigDir = flag.String("config-dir", "", "Specify a custom location for the configuration directory")
-	flagOptions   = flag.Bool("options", false, "Show all option help")
-	flagDebug     = flag.Bool("debug", false, "Enable debug mode (prints debug info to ./log.txt)")
-	flagProfile   = flag.Bool("profile", false, "Enable CPU profiling (writes profile info to ./micro.prof)")
-	flagPlugin    = flag.String("plugin", "", "Plugin command")
-	flagClean     = flag.Bool("clean", false, "Clean configuration directory")
-	optionFlags   map[string]*string
+	flagVersion    = flag.Bool("version", false, "Show the version number and information")
+	flagConfigDir  = flag.String("config-dir", "", "Specify a custom location for the configuration directory")
+	flagOptions    = flag.Bool("options", false, "Show all option help")
+	flagDebug      = flag.Bool("debug", false, "Enable debug mode (prints debug info to ./log.txt)")
+	flagProfile    = flag.Bool("profile", false, "Enable CPU profiling (writes profile info to ./micro.prof)")
+	flagPlugin     = flag.String("plugin", "", "Plugin command")
+	flagClean      = flag.Bool("clean", false, "Clean configuration directory")
+	flagExport     = flag.String("export", "", "Export the files with syntax highlighting as html or ansi")
+	flagExportNum  = flag.Bool("export-lines", false, "Add line numbers to exported files")
+	flagSchema     = flag.Bool("settings-schema", false, "Print a JSON Schema of settings.json")
+	flagTestPlugin = flag.String("test-plugin", "", "Run the Lua tests of a plugin directory")
+	optionFlags    map[string]*string
 
 	sighup chan os.Signal
 
 	timerChan chan func()
+
+	// idle is true once the Idle event was published, until the next event
+	idle bool
 )
 
+// idleDelay is the time without events after which the Idle event is
+// published
+const idleDelay = time.Second
+
 func InitFlags() {
 	flag.Usage = func() {
 		fmt.Println("Usage: micro [OPTIONS] [FILE]...")
@@ -57,6 +68,8 @@ func InitFlags() {
 		fmt.Println("    \tSpecify a line and column to start the cursor at when opening a buffer")
 		fmt.Println("-options")
 		fmt.Println("    \tShow all option help")
+		fmt.Println("-settings-schema")
+		fmt.Println("    \tPrint a JSON Schema of settings.json for editors to validate it")
 		fmt.Println("-debug")
 		fmt.Println("    \tEnable debug mode (enables logging to ./log.txt)")
 		fmt.Println("-profile")
@@ -64,6 +77,11 @@ func InitFlags() {
 		fmt.Println("    \tso it can be analyzed later with \"go tool pprof micro.prof\")")
 		fmt.Println("-version")
 		fmt.Println("    \tShow the version number and information")
+		fmt.Println("-export html|ansi [FILE]...")
+		fmt.Println("    \tPrint the files (or stdin) with syntax highlighting as HTML or")
+		fmt.Println("    \tANSI escape sequences instead of opening them")
+		fmt.Println("-export-lines")
+		fmt.Println("    \tAdd line numbers to the output of -export")
 
 		fmt.Print("\nMicro's plugins can be managed at the command line with the following commands.\n")
 		fmt.Println("-plugin install [PLUGIN]...")
@@ -78,6 +96,9 @@ func InitFlags() {
 		fmt.Println("    \tList installed plugins")
 		fmt.Println("-plugin available")
 		fmt.Println("    \tList available plugins")
+		fmt.Println("-test-plugin dir")
+		fmt.Println("    \tRun the *_test.lua files of a plugin directory and print")
+		fmt.Println("    \tthe results in the TAP format")
 
 		fmt.Print("\nMicro's options can also be set via command line arguments for quick\nadjustments. For real configuration, please use the settings.json\nfile (see 'help options').\n\n")
 		fmt.Println("-option value")
@@ -104,16 +125,18 @@ func InitFlags() {
 
 	if *flagOptions {
 		// If -options was passed
-		var keys []string
-		m := config.DefaultAllSettings()
-		for k := range m {
-			keys = append(keys, k)
-		}
-		sort.Strings(keys)
-		for _, k := range keys {
-			v := m[k]
-			fmt.Printf("-%s value\n", k)
-			fmt.Printf("    \tDefault value: '%v'\n", v)
+		for _, o := range config.Options() {
+			fmt.Printf("-%s value\n", o.Name)
+			if o.Help != "" {
+				for _, line := range strings.Split(o.Help, "\n") {
+					fmt.Printf("    \t%s\n", line)
+				}
+			}
+			fmt.Printf("    \tType: %s\n", o.Type)
+			if len(o.Choices) > 0 {
+				fmt.Printf("    \tPossible values: %s\n", strings.Join(o.Choices, ", "))
+			}
+			fmt.Printf("    \tDefault value: '%v'\n", o.Default)
 		}
 		exit(0)
 	}
@@ -123,23 +146,82 @@ func InitFlags() {
 	}
 }
 
-// DoPluginFlags parses and executes any flags that require LoadAllPlugins (-plugin and -clean)
+// DoPluginFlags parses and executes any flags that require LoadAllPlugins (-plugin, -clean and -settings-schema)
 func DoPluginFlags() {
-	if *flagClean || *flagPlugin != "" {
+	if *flagClean || *flagPlugin != "" || *flagSchema {
 		config.LoadAllPlugins()
 
 		if *flagPlugin != "" {
 			args := flag.Args()
 
-			config.PluginCommand(os.Stdout, *flagPlugin, args)
+			config.PluginCommand(os.Stdout, *flagPlugin, args, func(question string, yes func()) {
+				fmt.Println(question)
+				if shouldContinue() {
+					yes()
+				}
+			})
 		} else if *flagClean {
 			CleanConfig()
+		} else if *flagSchema {
+			// the plugins are loaded so that their options are included
+			schema, err := config.SettingsSchema()
+			if err != nil {
+				fmt.Println(err)
+				exit(1)
+			}
+			fmt.Println(string(schema))
 		}
 
 		exit(0)
 	}
 }
 
+// DoExportFlags prints the files given on the command line (or stdin) with
+// syntax highlighting if -export was passed, without starting the editor
+func DoExportFlags() {
+	if *flagExport == "" {
+		return
+	}
+	if *flagExport != "html" && *flagExport != "ansi" {
+		fmt.Println("Invalid export format:", *flagExport)
+		exit(1)
+	}
+
+	if err := config.InitColorscheme(); err != nil {
+		fmt.Println(err)
+	}
+
+	var buffers []*buffer.Buffer
+	args := flag.Args()
+	if len(args) == 0 {
+		input, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Println("Error reading from stdin:", err)
+			exit(1)
+		}
+		buffers = append(buffers, buffer.NewBufferFromString(string(input), "", buffer.BTDefault))
+	}
+	for _, a := range args {
+		b, err := buffer.NewBufferFromFile(a, buffer.BTDefault)
+		if err != nil {
+			fmt.Println(err)
+			exit(1)
+		}
+		buffers = append(buffers, b)
+	}
+
+	opts := buffer.ExportOptions{LineNumbers: *flagExportNum}
+	for _, b := range buffers {
+		if *flagExport == "html" {
+			fmt.Print(b.ExportHTML(b.Start(), b.End(), opts))
+		} else {
+			fmt.Print(b.ExportANSI(b.Start(), b.End(), opts))
+		}
+	}
+
+	exit(0)
+}
+
 // LoadInput determines which files should be loaded into buffers
 // based on the input stored in flag.Args()
 func LoadInput(args []string) []*buffer.Buffer {
@@ -260,7 +342,7 @@ func exit(rc int) {
 	}
 
 	if screen.Screen != nil {
-		screen.Screen.Fini()
+		screen.Fini()
 	}
 
 	os.Exit(rc)
@@ -296,6 +378,7 @@ func main() {
 		screen.TermMessage(err)
 	}
 
+	config.InitProjectDir()
 	config.InitRuntimeFiles(true)
 	config.InitPlugins()
 
@@ -332,6 +415,8 @@ func main() {
 	}
 
 	DoPluginFlags()
+	DoExportFlags()
+	DoTestPluginFlags()
 
 	err = screen.Init()
 	if err != nil {
@@ -345,7 +430,7 @@ func main() {
 	defer func() {
 		if err := recover(); err != nil {
 			if screen.Screen != nil {
-				screen.Screen.Fini()
+				screen.Fini()
 			}
 			if e, ok := err.(*lua.ApiError); ok {
 				fmt.Println("Lua API error:", e)
@@ -360,6 +445,9 @@ func main() {
 		}
 	}()
 
+	// the plugins may start timers and tasks as soon as they are loaded
+	timerChan = make(chan func())
+
 	err = config.LoadAllPlugins()
 	if err != nil {
 		screen.TermMessage(err)
@@ -391,7 +479,7 @@ func main() {
 
 	if len(b) == 0 {
 		// No buffers to open
-		screen.Screen.Fini()
+		screen.Fini()
 		runtime.Goexit()
 	}
 
@@ -407,11 +495,14 @@ func main() {
 		screen.TermMessage(err)
 	}
 
+	action.PromptProjectTrust()
+
 	if clipErr != nil {
 		log.Println(clipErr, " or change 'clipboard' option")
 	}
 
 	config.StartAutoSave()
+	config.StartConfigWatcher()
 	if a := config.GetGlobalOption("autosave").(float64); a > 0 {
 		config.SetAutoTime(a)
 	}
@@ -423,8 +514,6 @@ func main() {
 	signal.Notify(util.Sigterm, syscall.SIGTERM, syscall.SIGINT, syscall.SIGQUIT, syscall.SIGABRT)
 	signal.Notify(sighup, syscall.SIGHUP)
 
-	timerChan = make(chan func())
-
 	// Here is the event loop which runs in a separate thread
 	go func() {
 		for {
@@ -471,6 +560,12 @@ func DoEvent() {
 	action.InfoBar.Display()
 	screen.Screen.Show()
 
+	// the Idle event is published once when no events happen for idleDelay
+	var idleChan <-chan time.Time
+	if !idle {
+		idleChan = time.After(idleDelay)
+	}
+
 	// Check for new events
 	select {
 	case f := <-shell.Jobs:
@@ -480,9 +575,17 @@ func DoEvent() {
 		for _, b := range buffer.OpenBuffers {
 			b.AutoSave()
 		}
+	case path := <-config.ConfigFileChanged:
+		action.ReloadConfigFile(path)
 	case <-shell.CloseTerms:
 		action.Tabs.CloseTerms()
 	case event = <-screen.Events:
+		idle = false
+	case <-idleChan:
+		idle = true
+		if err := config.Publish(config.EvIdle, nil); err != nil {
+			screen.TermMessage(err)
+		}
 	case <-screen.DrawChan():
 		for len(screen.DrawChan()) > 0 {
 			<-screen.DrawChan()
@@ -507,11 +610,26 @@ func DoEvent() {
 		return
 	}
 
+	if e, ok := event.(*tcell.EventRaw); ok && (e.EscSeq() == screen.FocusInSeq || e.EscSeq() == screen.FocusOutSeq) {
+		ev := config.EvFocusGained
+		if e.EscSeq() == screen.FocusOutSeq {
+			ev = config.EvFocusLost
+		}
+		if err := config.Publish(ev, nil); err != nil {
+			screen.TermMessage(err)
+		}
+		event = nil
+	}
+
 	if event != nil {
 		_, resize := event.(*tcell.EventResize)
 		if resize {
 			action.InfoBar.HandleEvent(event)
 			action.Tabs.HandleEvent(event)
+			w, h := screen.Screen.Size()
+			if err := config.Publish(config.EvResize, map[string]interface{}{"width": w, "height": h}); err != nil {
+				screen.TermMessage(err)
+			}
 		} else if action.InfoBar.HasPrompt {
 			action.InfoBar.HandleEvent(event)
 		} else {
@@ -4,19 +4,24 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-errors/errors"
 	"github.com/stretchr/testify/assert"
+	lua "github.com/yuin/gopher-lua"
 	"github.com/zyedidia/micro/v2/internal/action"
 	"github.com/zyedidia/micro/v2/internal/buffer"
 	"github.com/zyedidia/micro/v2/internal/config"
+	ulua "github.com/zyedidia/micro/v2/internal/lua"
 	"github.com/zyedidia/micro/v2/internal/screen"
 	"github.com/micro-editor/tcell/v2"
 )
 
 var tempDir string
-var sim tcell.SimulationScreen
 
 func init() {
 	screen.Events = make(chan tcell.Event, 8)
@@ -101,52 +106,6 @@ func cleanup() {
 	os.RemoveAll(tempDir)
 }
 
-func handleEvent() {
-	screen.Lock()
-	e := screen.Screen.PollEvent()
-	screen.Unlock()
-	if e != nil {
-		screen.Events <- e
-	}
-
-	for len(screen.DrawChan()) > 0 || len(screen.Events) > 0 {
-		DoEvent()
-	}
-}
-
-func injectKey(key tcell.Key, r rune, mod tcell.ModMask) {
-	sim.InjectKey(key, r, mod)
-	handleEvent()
-}
-
-func injectMouse(x, y int, buttons tcell.ButtonMask, mod tcell.ModMask) {
-	sim.InjectMouse(x, y, buttons, mod)
-	handleEvent()
-}
-
-func injectString(str string) {
-	// the tcell simulation screen event channel can only handle
-	// 10 events at once, so we need to divide up the key events
-	// into chunks of 10 and handle the 10 events before sending
-	// another chunk of events
-	iters := len(str) / 10
-	extra := len(str) % 10
-
-	for i := 0; i < iters; i++ {
-		s := i * 10
-		e := i*10 + 10
-		sim.InjectKeyBytes([]byte(str[s:e]))
-		for i := 0; i < 10; i++ {
-			handleEvent()
-		}
-	}
-
-	sim.InjectKeyBytes([]byte(str[len(str)-extra:]))
-	for i := 0; i < extra; i++ {
-		handleEvent()
-	}
-}
-
 func openFile(file string) {
 	injectKey(tcell.KeyCtrlE, rune(tcell.KeyCtrlE), tcell.ModCtrl)
 	injectString(fmt.Sprintf("open %s", file))
@@ -182,6 +141,17 @@ func createTestFile(t *testing.T, content string) string {
 }
 
 func TestMain(m *testing.M) {
+	// TestPluginTests runs the plugin tests in a new process, as they start
+	// the editor again
+	if dir := os.Getenv("MICRO_TEST_PLUGIN"); dir != "" {
+		failed, err := runPluginTests(os.Stdout, dir)
+		if err != nil {
+			fmt.Println("Bail out!", err)
+			os.Exit(2)
+		}
+		os.Exit(failed)
+	}
+
 	var err error
 	sim, err = startup([]string{})
 	if err != nil {
@@ -325,6 +295,345 @@ func TestSearchAndReplace(t *testing.T) {
 	assert.Equal(t, srTest3, string(data))
 }
 
+func TestPopup(t *testing.T) {
+	file := createTestFile(t, "base content")
+
+	openFile(file)
+
+	if findBuffer(file) == nil {
+		t.Fatalf("Could not find buffer %s", file)
+	}
+
+	p, err := action.NewPopup("popup text", action.PopupCenter, 20, 2)
+	assert.NoError(t, err)
+	p.SetTitle("Title")
+	closed := false
+	p.OnClose(func() { closed = true })
+
+	// an unfocused popup doesn't receive the key events
+	injectString("x")
+	assert.Equal(t, "xbase content", string(findBuffer(file).LineBytes(0)))
+	assert.Equal(t, "popup text", string(p.Buf.LineBytes(0)))
+
+	redraw()
+	cells, w, h := sim.GetContents()
+	v := p.GetView()
+	row := ""
+	for x := 0; x < w; x++ {
+		row += cellString(cells[(v.Y-1)*w+x])
+	}
+	assert.Contains(t, row, " Title ")
+	assert.Less(t, v.Y, h)
+
+	p.Focus()
+	injectString("y")
+	assert.Equal(t, "ypopup text", string(p.Buf.LineBytes(0)))
+	assert.Equal(t, "xbase content", string(findBuffer(file).LineBytes(0)))
+
+	injectKey(tcell.KeyEscape, 0, tcell.ModNone)
+	assert.True(t, closed)
+	assert.False(t, p.IsOpen())
+	assert.Empty(t, action.MainTab().Popups)
+
+	// Escape only closes a focused popup
+	p, err = action.NewPopup("popup text", action.PopupCenter, 20, 2)
+	assert.NoError(t, err)
+	injectKey(tcell.KeyEscape, 0, tcell.ModNone)
+	assert.True(t, p.IsOpen())
+
+	// the split commands are disabled and quit closes the popup
+	panes := len(action.MainTab().Panes)
+	p.Focus()
+	p.HandleCommand("vsplit")
+	assert.Len(t, action.MainTab().Panes, panes)
+	assert.Contains(t, action.InfoBar.Msg, "not available in a popup")
+	p.HandleCommand("quit")
+	assert.False(t, p.IsOpen())
+	assert.Len(t, action.MainTab().Panes, panes)
+	assert.Equal(t, "xbase content", string(findBuffer(file).LineBytes(0)))
+
+	// the popups of a tab are closed with it
+	action.MainTab().CurPane().AddTab()
+	p, err = action.NewPopup("popup text", action.PopupCenter, 20, 2)
+	assert.NoError(t, err)
+	action.MainTab().CurPane().Quit()
+	assert.False(t, p.IsOpen())
+	for _, b := range buffer.OpenBuffers {
+		assert.NotEqual(t, p.Buf, b)
+	}
+
+	_, err = action.NewPopup("", "nowhere", 20, 2)
+	assert.Error(t, err)
+
+	injectKey(tcell.KeyCtrlS, rune(tcell.KeyCtrlS), tcell.ModCtrl)
+}
+
+func TestFinder(t *testing.T) {
+	file := createTestFile(t, "func alpha() {}\nfunc beta() {}\nfunc gamma() {}")
+
+	openFile(file)
+
+	b := findBuffer(file)
+	if b == nil {
+		t.Fatalf("Could not find buffer %s", file)
+	}
+
+	injectKey(tcell.KeyCtrlE, rune(tcell.KeyCtrlE), tcell.ModCtrl)
+	injectString("finder symbols")
+	injectKey(tcell.KeyEnter, rune(tcell.KeyEnter), tcell.ModNone)
+	assert.Equal(t, 1, len(action.MainTab().Popups))
+
+	// the up and down arrows select the matches instead of the history
+	injectKey(tcell.KeyDown, 0, tcell.ModNone)
+	injectKey(tcell.KeyEnter, rune(tcell.KeyEnter), tcell.ModNone)
+	assert.Empty(t, action.MainTab().Popups)
+	assert.Equal(t, 1, b.GetActiveCursor().Y)
+
+	injectKey(tcell.KeyCtrlE, rune(tcell.KeyCtrlE), tcell.ModCtrl)
+	injectString("finder symbols")
+	injectKey(tcell.KeyEnter, rune(tcell.KeyEnter), tcell.ModNone)
+	injectString("gam")
+	injectKey(tcell.KeyEscape, 0, tcell.ModNone)
+	assert.Empty(t, action.MainTab().Popups)
+	assert.Equal(t, 1, b.GetActiveCursor().Y)
+}
+
+func TestCommandPalette(t *testing.T) {
+	file := createTestFile(t, "foo\nbar")
+
+	openFile(file)
+
+	b := findBuffer(file)
+	if b == nil {
+		t.Fatalf("Could not find buffer %s", file)
+	}
+
+	injectKey(tcell.KeyCtrlE, rune(tcell.KeyCtrlE), tcell.ModCtrl)
+	injectString("finder palette")
+	injectKey(tcell.KeyEnter, rune(tcell.KeyEnter), tcell.ModNone)
+	injectString("SelectAll")
+	assert.Equal(t, 1, len(action.MainTab().Popups))
+
+	// the first match shows the description and key binding of the action
+	line := string(action.MainTab().Popups[0].Buf.LineBytes(0))
+	assert.True(t, strings.HasPrefix(line, "SelectAll"))
+	assert.Contains(t, line, "Select the whole buffer (Ctrl-a)")
+
+	injectKey(tcell.KeyEnter, rune(tcell.KeyEnter), tcell.ModNone)
+	assert.Empty(t, action.MainTab().Popups)
+	assert.Equal(t, "foo\nbar", string(b.GetActiveCursor().GetSelection()))
+}
+
+func TestExplorer(t *testing.T) {
+	file := createTestFile(t, "foo")
+
+	openFile(file)
+
+	if findBuffer(file) == nil {
+		t.Fatalf("Could not find buffer %s", file)
+	}
+
+	tab := action.MainTab()
+	tab.CurPane().ToggleExplorer()
+	assert.Equal(t, 2, len(tab.Panes))
+	_, ok := tab.Panes[0].(*action.ExplorerPane)
+	assert.True(t, ok)
+
+	injectKey(tcell.KeyRune, 'q', tcell.ModNone)
+	assert.Equal(t, 1, len(tab.Panes))
+	assert.Equal(t, file, tab.CurPane().Buf.Path)
+}
+
+func TestScrollBarMarks(t *testing.T) {
+	lines := make([]string, 200)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("line %d", i)
+	}
+	lines[150] = "needle"
+	file := createTestFile(t, strings.Join(lines, "\n"))
+
+	openFile(file)
+
+	b := findBuffer(file)
+	if b == nil {
+		t.Fatalf("Could not find buffer %s", file)
+	}
+	b.SetOptionNative("scrollbar", true)
+	b.SetOptionNative("hlsearch", true)
+
+	injectKey(tcell.KeyCtrlF, rune(tcell.KeyCtrlF), tcell.ModCtrl)
+	injectString("needle")
+	injectKey(tcell.KeyEnter, rune(tcell.KeyEnter), tcell.ModNone)
+	injectKey(tcell.KeyHome, 0, tcell.ModCtrl)
+	assert.Equal(t, 0, b.GetActiveCursor().Y)
+
+	// a click on the row of the scroll bar with the mark of the match
+	// jumps to the match itself
+	v := action.MainTab().CurPane().BufView()
+	x := v.X + v.Width
+	y := v.Y + 150*v.Height/b.LinesNum()
+	injectMouse(x, y, tcell.Button1, tcell.ModNone)
+	injectMouse(x, y, tcell.ButtonNone, tcell.ModNone)
+	assert.Equal(t, 150, b.GetActiveCursor().Y)
+	assert.False(t, b.GetActiveCursor().HasSelection())
+}
+
+func TestVirtualText(t *testing.T) {
+	file := createTestFile(t, "let x = 1\nfoo")
+
+	openFile(file)
+
+	b := findBuffer(file)
+	if b == nil {
+		t.Fatalf("Could not find buffer %s", file)
+	}
+
+	b.AddVirtualText(buffer.NewVirtualText("test", buffer.Loc{X: 5, Y: 0}, ": int", buffer.VTInline, "comment"))
+	b.AddVirtualText(buffer.NewVirtualText("test", buffer.Loc{X: 3, Y: 1}, "error", buffer.VTEndOfLine, "error"))
+
+	screenLine := func(y int) string {
+		cells, w, _ := sim.GetContents()
+		line := ""
+		for x := 0; x < w; x++ {
+			line += cellString(cells[y*w+x])
+		}
+		return line
+	}
+
+	// the cursor is drawn at the start of the inline virtual text, and skips
+	// over it
+	bp := action.MainTab().CurPane()
+	bp.Cursor.GotoLoc(buffer.Loc{X: 5, Y: 0})
+	redraw()
+	assert.Contains(t, screenLine(0), "let x: int = 1")
+	assert.Contains(t, screenLine(1), "foo error")
+	x, _, _ := sim.GetCursor()
+	assert.Equal(t, strings.Index(screenLine(0), ":"), x)
+
+	injectKey(tcell.KeyRight, 0, tcell.ModNone)
+	redraw()
+	x, _, _ = sim.GetCursor()
+	assert.Equal(t, strings.Index(screenLine(0), "= 1"), x)
+
+	// the virtual text moves along with the text
+	injectKey(tcell.KeyHome, 0, tcell.ModNone)
+	injectString("ab")
+	redraw()
+	assert.Contains(t, screenLine(0), "ablet x: int = 1")
+	b.ClearVirtualText("test")
+
+	injectKey(tcell.KeyCtrlS, rune(tcell.KeyCtrlS), tcell.ModCtrl)
+}
+
+func TestAsync(t *testing.T) {
+	timerChan = make(chan func())
+	defer func() { timerChan = nil }()
+
+	ulua.L.SetGlobal("micro", LuaImport("micro"))
+	ulua.L.SetGlobal("shell", LuaImport("micro/shell"))
+	err := ulua.L.DoString(`
+		result = {}
+		micro.Async(function(a)
+			micro.Sleep(10)
+			local out, code = shell.Await(shell.Spawn("echo", "hi"))
+			result.out, result.code, result.arg = out, code, a
+			-- a program reading its stdin exits with an empty one
+			result.grepcode = select(2, shell.Await(shell.Spawn("grep", "x")))
+			local p = shell.SpawnWithStdin("grep", "x")
+			p:Send("axb\n")
+			p:CloseStdin()
+			result.grepout = shell.Await(p)
+			result.missing = {shell.Await(shell.Spawn("micro-missing-program"))}
+		end, "arg")
+		sleeper = micro.Async(function()
+			micro.Sleep(10000)
+			result.woke = true
+		end)
+		ticks = 0
+		ticker = micro.SetInterval(1, function()
+			ticks = ticks + 1
+			if ticks == 3 then ticker:Stop() end
+		end)
+	`)
+	assert.NoError(t, err)
+
+	result := ulua.L.GetGlobal("result").(*lua.LTable)
+	done := func() bool {
+		return result.RawGetString("missing") != lua.LNil && ulua.L.GetGlobal("ticks") == lua.LNumber(3)
+	}
+	for i := 0; i < 100 && !done(); i++ {
+		select {
+		case f := <-timerChan:
+			f()
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+	assert.Equal(t, "hi\n", result.RawGetString("out").String())
+	assert.Equal(t, lua.LNumber(0), result.RawGetString("code"))
+	assert.Equal(t, "arg", result.RawGetString("arg").String())
+	assert.Equal(t, lua.LNumber(1), result.RawGetString("grepcode"))
+	assert.Equal(t, "axb\n", result.RawGetString("grepout").String())
+	if missing, ok := result.RawGetString("missing").(*lua.LTable); assert.True(t, ok) {
+		assert.Equal(t, "", missing.RawGetInt(1).String())
+		assert.Equal(t, lua.LNumber(-1), missing.RawGetInt(2))
+		assert.Contains(t, missing.RawGetInt(3).String(), "micro-missing-program")
+	}
+	assert.Equal(t, lua.LNumber(3), ulua.L.GetGlobal("ticks"))
+
+	assert.NoError(t, ulua.L.DoString("sleeper:Cancel()"))
+	assert.True(t, ulua.L.GetGlobal("sleeper").(*lua.LUserData).Value.(*Task).IsDone())
+	assert.Equal(t, lua.LNil, result.RawGetString("woke"))
+
+	assert.Error(t, ulua.L.DoString("micro.Sleep(1)"))
+}
+
+func TestPluginTests(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "greet")
+	os.MkdirAll(dir, 0755)
+	os.WriteFile(filepath.Join(dir, "greet.lua"), []byte(`
+VERSION = "1.0.0"
+function greet(name)
+    return "Hello " .. name
+end
+exports.greet = greet
+`), 0644)
+	os.WriteFile(filepath.Join(dir, "greet_test.lua"), []byte(`
+local test = import("micro/test")
+
+function test_greet()
+    test.Equal("Hello world", greet.greet("world"))
+end
+
+function test_type()
+    test.Type("abc")
+    test.Equal("abc", test.Text(), "typed text")
+end
+
+function test_fail()
+    test.Equal("Hello", greet.greet("you"), "greeting")
+end
+`), 0644)
+
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	cmd.Env = append(os.Environ(), "MICRO_TEST_PLUGIN="+dir)
+	out, err := cmd.Output()
+	exitErr, ok := err.(*exec.ExitError)
+	if assert.True(t, ok, "the tests must fail: %v", err) {
+		assert.Equal(t, 1, exitErr.ExitCode())
+	}
+
+	assert.Equal(t, strings.Join([]string{
+		"TAP version 13",
+		"ok 1 - greet_test.lua: test_greet",
+		"ok 2 - greet_test.lua: test_type",
+		"not ok 3 - greet_test.lua: test_fail",
+		`  # greet_test.lua:14: greeting: expected "Hello", got "Hello you"`,
+		"1..3",
+		"# 1 of 3 tests failed",
+		"",
+	}, "\n"), string(out))
+}
+
 func TestMultiCursor(t *testing.T) {
 	// TODO
 }
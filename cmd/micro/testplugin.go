@@ -0,0 +1,401 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/micro-editor/tcell/v2"
+	lua "github.com/yuin/gopher-lua"
+	"github.com/zyedidia/micro/v2/internal/action"
+	"github.com/zyedidia/micro/v2/internal/buffer"
+	"github.com/zyedidia/micro/v2/internal/config"
+	ulua "github.com/zyedidia/micro/v2/internal/lua"
+	"github.com/zyedidia/micro/v2/internal/screen"
+	luar "layeh.com/gopher-luar"
+)
+
+// sim is the simulation screen of the tests
+var sim tcell.SimulationScreen
+
+// handleEvent handles the next event of the simulation screen, and the
+// redraws and events which it causes
+func handleEvent() {
+	screen.Lock()
+	e := screen.Screen.PollEvent()
+	screen.Unlock()
+	if e != nil {
+		screen.Events <- e
+	}
+
+	for len(screen.DrawChan()) > 0 || len(screen.Events) > 0 {
+		DoEvent()
+	}
+}
+
+// redraw draws the editor on the simulation screen
+func redraw() {
+	screen.Redraw()
+	for len(screen.DrawChan()) > 0 || len(screen.Events) > 0 {
+		DoEvent()
+	}
+}
+
+func injectKey(key tcell.Key, r rune, mod tcell.ModMask) {
+	sim.InjectKey(key, r, mod)
+	handleEvent()
+}
+
+func injectMouse(x, y int, buttons tcell.ButtonMask, mod tcell.ModMask) {
+	sim.InjectMouse(x, y, buttons, mod)
+	handleEvent()
+}
+
+func injectString(str string) {
+	// the tcell simulation screen event channel can only handle
+	// 10 events at once, so we need to divide up the key events
+	// into chunks of 10 and handle the 10 events before sending
+	// another chunk of events
+	iters := len(str) / 10
+	extra := len(str) % 10
+
+	for i := 0; i < iters; i++ {
+		s := i * 10
+		e := i*10 + 10
+		sim.InjectKeyBytes([]byte(str[s:e]))
+		for i := 0; i < 10; i++ {
+			handleEvent()
+		}
+	}
+
+	sim.InjectKeyBytes([]byte(str[len(str)-extra:]))
+	for i := 0; i < extra; i++ {
+		handleEvent()
+	}
+}
+
+// DoTestPluginFlags runs the Lua tests of the plugin directory given with
+// -test-plugin and exits, with status 1 if any of them failed
+func DoTestPluginFlags() {
+	if *flagTestPlugin == "" {
+		return
+	}
+	failed, err := runPluginTests(os.Stdout, *flagTestPlugin)
+	if err != nil {
+		fmt.Println("Bail out!", err)
+		exit(1)
+	}
+	if failed > 0 {
+		exit(1)
+	}
+	exit(0)
+}
+
+// startPluginTests starts the editor with a simulation screen and a
+// temporary configuration directory containing only the given plugin
+func startPluginTests(configDir, plugDir string) error {
+	if err := config.InitConfigDir(configDir); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Join(configDir, "plug"), os.ModePerm); err != nil {
+		return err
+	}
+	if err := os.Symlink(plugDir, filepath.Join(configDir, "plug", filepath.Base(plugDir))); err != nil {
+		return err
+	}
+
+	// the configuration of the project in the working directory must not
+	// affect the tests
+	config.ProjectDir = ""
+	config.InitRuntimeFiles(true)
+	config.InitPlugins()
+	if err := config.ReadSettings(); err != nil {
+		return err
+	}
+	if err := config.InitGlobalSettings(); err != nil {
+		return err
+	}
+
+	screen.Events = make(chan tcell.Event, 8)
+	s, err := screen.InitSimScreen()
+	if err != nil {
+		return err
+	}
+	sim = s
+
+	if err := config.LoadAllPlugins(); err != nil {
+		return err
+	}
+	action.InitBindings()
+	action.InitCommands()
+	if err := config.InitColorscheme(); err != nil {
+		return err
+	}
+	if err := config.RunPluginFn("preinit"); err != nil {
+		return err
+	}
+	action.InitGlobals()
+	buffer.SetMessager(action.InfoBar)
+	action.InitTabs([]*buffer.Buffer{buffer.NewBufferFromString("", "", buffer.BTDefault)})
+	if err := config.RunPluginFn("init"); err != nil {
+		return err
+	}
+	if err := config.RunPluginFn("postinit"); err != nil {
+		return err
+	}
+
+	sim.InjectResize()
+	handleEvent()
+	return nil
+}
+
+// runPluginTests runs the test functions of the *_test.lua files of a
+// plugin directory, prints their results in the TAP format and returns the
+// number of failed tests
+func runPluginTests(out io.Writer, dir string) (int, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return 0, err
+	}
+	var files []string
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() && strings.HasSuffix(path, "_test.lua") {
+			files = append(files, path)
+		}
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	if len(files) == 0 {
+		return 0, errors.New("no *_test.lua files in " + dir)
+	}
+
+	configDir, err := os.MkdirTemp("", "micro-test-plugin")
+	if err != nil {
+		return 0, err
+	}
+	defer os.RemoveAll(configDir)
+	if err := startPluginTests(configDir, dir); err != nil {
+		return 0, err
+	}
+	name := filepath.Base(dir)
+	for _, p := range config.Plugins {
+		if p.DirName == name && !p.Default {
+			name = p.Name
+		}
+	}
+	if config.FindPlugin(name) == nil {
+		return 0, errors.New("the plugin " + name + " could not be loaded")
+	}
+
+	fmt.Fprintln(out, "TAP version 13")
+	n, failed := 0, 0
+	for _, file := range files {
+		rel, _ := filepath.Rel(dir, file)
+		err := runTestFile(file, func(test string, err error) {
+			n++
+			if err == nil {
+				fmt.Fprintf(out, "ok %d - %s: %s\n", n, rel, test)
+				return
+			}
+			failed++
+			fmt.Fprintf(out, "not ok %d - %s: %s\n", n, rel, test)
+			if e, ok := err.(*lua.ApiError); ok {
+				// without the stack trace
+				err = errors.New(e.Object.String())
+			}
+			tapDiagnostic(out, "  # ", err.Error())
+		})
+		if err != nil {
+			n++
+			failed++
+			fmt.Fprintf(out, "not ok %d - %s\n", n, rel)
+			tapDiagnostic(out, "  # ", err.Error())
+		}
+	}
+	fmt.Fprintf(out, "1..%d\n", n)
+	for _, msg := range screen.TermMessages {
+		tapDiagnostic(out, "# ", msg)
+	}
+
+	if failed > 0 {
+		fmt.Fprintf(out, "# %d of %d tests failed\n", failed, n)
+	}
+	return failed, nil
+}
+
+// tapDiagnostic prints a message as TAP diagnostic lines
+func tapDiagnostic(out io.Writer, prefix, msg string) {
+	for _, line := range strings.Split(msg, "\n") {
+		fmt.Fprintln(out, prefix+line)
+	}
+}
+
+// runTestFile runs the functions whose name starts with "test" of a test
+// file in the order in which they are defined, each one with a new empty
+// buffer in the current pane, and reports their result to report
+func runTestFile(file string, report func(test string, err error)) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+
+	// the names of the test functions are recorded when they are defined
+	var tests []string
+	env := ulua.NewEnvironment()
+	mt := ulua.L.GetMetatable(env).(*lua.LTable)
+	mt.RawSetString("__newindex", ulua.L.NewFunction(func(L *lua.LState) int {
+		t, k, v := L.CheckTable(1), L.Get(2), L.Get(3)
+		if name, ok := k.(lua.LString); ok && strings.HasPrefix(string(name), "test") && v.Type() == lua.LTFunction {
+			tests = append(tests, string(name))
+		}
+		t.RawSet(k, v)
+		return 0
+	}))
+	testPkg := luaTestPackage()
+	ulua.L.SetField(env, "import", luar.New(ulua.L, func(pkg string) *lua.LTable {
+		if pkg == "micro/test" {
+			return testPkg
+		}
+		return LuaImport(pkg)
+	}))
+
+	if err := ulua.LoadFile(env, filepath.Base(file), data); err != nil {
+		return err
+	}
+	for _, test := range tests {
+		action.MainTab().CurPane().OpenBuffer(buffer.NewBufferFromString("", "", buffer.BTDefault))
+		action.InfoBar.Reset()
+		redraw()
+
+		ulua.L.Push(env.RawGetString(test))
+		report(test, ulua.L.PCall(0, 0, nil))
+	}
+	return nil
+}
+
+// luaTestPackage returns the micro/test package, which drives the editor
+// and checks its state from the tests of a plugin
+func luaTestPackage() *lua.LTable {
+	L := ulua.L
+	pkg := L.NewTable()
+	curPane := func() *action.BufPane {
+		return action.MainTab().CurPane()
+	}
+
+	L.SetField(pkg, "CurPane", luar.New(L, curPane))
+	L.SetField(pkg, "NewBuffer", luar.New(L, func(text, path string) *buffer.Buffer {
+		b := buffer.NewBufferFromString(text, path, buffer.BTDefault)
+		curPane().OpenBuffer(b)
+		redraw()
+		return b
+	}))
+	L.SetField(pkg, "Open", L.NewFunction(func(L *lua.LState) int {
+		b, err := buffer.NewBufferFromFile(L.CheckString(1), buffer.BTDefault)
+		if err != nil {
+			L.RaiseError("%s", err)
+		}
+		curPane().OpenBuffer(b)
+		redraw()
+		L.Push(luar.New(L, b))
+		return 1
+	}))
+	L.SetField(pkg, "Key", L.NewFunction(func(L *lua.LState) int {
+		keys, err := action.KeyEvents(L.CheckString(1))
+		if err != nil {
+			L.RaiseError("%s", err)
+		}
+		for _, k := range keys {
+			injectKey(k.Key(), k.Rune(), k.Modifiers())
+		}
+		return 0
+	}))
+	L.SetField(pkg, "Type", luar.New(L, injectString))
+	L.SetField(pkg, "Command", luar.New(L, func(cmd string) {
+		curPane().HandleCommand(cmd)
+		redraw()
+	}))
+	L.SetField(pkg, "Text", luar.New(L, func() string {
+		return string(curPane().Buf.Bytes())
+	}))
+	L.SetField(pkg, "Cursor", luar.New(L, func() (int, int) {
+		c := curPane().Cursor
+		return c.X, c.Y
+	}))
+	L.SetField(pkg, "Message", luar.New(L, func() string {
+		return action.InfoBar.Msg
+	}))
+	L.SetField(pkg, "ScreenLine", luar.New(L, func(y int) string {
+		redraw()
+		cells, w, h := sim.GetContents()
+		if y < 0 || y >= h {
+			return ""
+		}
+		var line strings.Builder
+		for _, c := range cells[y*w : (y+1)*w] {
+			line.WriteString(cellString(c))
+		}
+		return strings.TrimRight(line.String(), " ")
+	}))
+	L.SetField(pkg, "Cell", luar.New(L, func(x, y int) string {
+		redraw()
+		cells, w, h := sim.GetContents()
+		if x < 0 || x >= w || y < 0 || y >= h {
+			return ""
+		}
+		return cellString(cells[y*w+x])
+	}))
+
+	L.SetField(pkg, "Equal", L.NewFunction(func(L *lua.LState) int {
+		expected, actual := L.Get(1), L.Get(2)
+		if expected.Type() != actual.Type() || expected.String() != actual.String() {
+			failTest(L, 3, "expected %s, got %s", luaRepr(expected), luaRepr(actual))
+		}
+		return 0
+	}))
+	L.SetField(pkg, "True", L.NewFunction(func(L *lua.LState) int {
+		if !lua.LVAsBool(L.Get(1)) {
+			failTest(L, 2, "expected a true value, got %s", luaRepr(L.Get(1)))
+		}
+		return 0
+	}))
+	L.SetField(pkg, "Contains", L.NewFunction(func(L *lua.LState) int {
+		s, sub := L.CheckString(1), L.CheckString(2)
+		if !strings.Contains(s, sub) {
+			failTest(L, 3, "%q does not contain %q", s, sub)
+		}
+		return 0
+	}))
+	return pkg
+}
+
+// cellString returns the text of a cell of the simulation screen
+func cellString(c tcell.SimCell) string {
+	if len(c.Runes) == 0 {
+		return " "
+	}
+	return string(c.Runes)
+}
+
+// failTest raises the error of a failed assertion, followed by its
+// optional message, which is the argument msgArg of the assertion
+func failTest(L *lua.LState, msgArg int, format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	if L.GetTop() >= msgArg {
+		msg = L.CheckString(msgArg) + ": " + msg
+	}
+	L.RaiseError("%s", msg)
+}
+
+// luaRepr returns a description of a Lua value for the assertions
+func luaRepr(v lua.LValue) string {
+	if s, ok := v.(lua.LString); ok {
+		return fmt.Sprintf("%q", string(s))
+	}
+	return v.String()
+}
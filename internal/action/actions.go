@@ -63,6 +63,13 @@ func (h *BufPane) MousePress(e *tcell.EventMouse) bool {
 	if my >= h.BufView().Y+h.BufView().Height {
 		return false
 	}
+	if line, ok := h.ScrollBarLine(mx, my); ok {
+		h.barClick = true
+		h.jumpToBarLine(line)
+		return true
+	}
+	h.barClick = false
+
 	mouseLoc := h.LocFromVisual(buffer.Loc{mx, my})
 	h.Cursor.Loc = mouseLoc
 
@@ -114,6 +121,12 @@ func (h *BufPane) MouseDrag(e *tcell.EventMouse) bool {
 	if my >= h.BufView().Y+h.BufView().Height {
 		return false
 	}
+	if h.barClick {
+		if line, ok := h.ScrollBarLine(mx, my); ok {
+			h.jumpToBarLine(line)
+		}
+		return true
+	}
 	h.Cursor.Loc = h.LocFromVisual(buffer.Loc{mx, my})
 
 	if h.tripleClick {
@@ -141,12 +154,28 @@ func (h *BufPane) MouseRelease(e *tcell.EventMouse) bool {
 	// 	h.Cursor.SetSelectionEnd(h.Cursor.Loc)
 	// }
 
+	if h.barClick {
+		h.barClick = false
+		return true
+	}
+
 	if h.Cursor.HasSelection() {
 		h.Cursor.CopySelection(clipboard.PrimaryReg)
 	}
 	return true
 }
 
+// jumpToBarLine moves the only cursor to the start of the given line,
+// clicked on the scroll bar or on the minimap
+func (h *BufPane) jumpToBarLine(line int) {
+	if h.Buf.NumCursors() > 1 {
+		h.Buf.ClearCursors()
+		h.Cursor = h.Buf.GetActiveCursor()
+	}
+	h.Cursor.ResetSelection()
+	h.GotoLoc(buffer.Loc{X: 0, Y: line})
+}
+
 // ScrollUpAction scrolls the view up
 func (h *BufPane) ScrollUpAction() bool {
 	h.ScrollUp(util.IntOpt(h.Buf.Settings["scrollspeed"]))
@@ -694,19 +723,28 @@ func (h *BufPane) InsertNewline() bool {
 		h.Cursor.ResetSelection()
 	}
 
+	// the line may end with a keyword which changes its indentation
+	if h.Buf.Settings["autoindent"].(bool) && h.Buf.ShouldReindent(h.Cursor, true) {
+		h.Buf.Reindent(h.Cursor.Y)
+	}
+
 	ws := util.GetLeadingWhitespace(h.Buf.LineBytes(h.Cursor.Y))
 	cx := h.Cursor.X
 	h.Buf.Insert(h.Cursor.Loc, "\n")
 	// h.Cursor.Right()
 
 	if h.Buf.Settings["autoindent"].(bool) {
-		if cx < len(ws) {
-			ws = ws[0:cx]
+		// use the indentation rules of the syntax file if it has any, and
+		// otherwise copy the indentation of the previous line
+		if !h.Buf.Reindent(h.Cursor.Y) {
+			if cx < len(ws) {
+				ws = ws[0:cx]
+			}
+			h.Buf.Insert(h.Cursor.Loc, string(ws))
+			// for i := 0; i < len(ws); i++ {
+			// 	h.Cursor.Right()
+			// }
 		}
-		h.Buf.Insert(h.Cursor.Loc, string(ws))
-		// for i := 0; i < len(ws); i++ {
-		// 	h.Cursor.Right()
-		// }
 
 		// Remove the whitespaces if keepautoindent setting is off
 		if util.IsSpacesOrTabs(h.Buf.LineBytes(h.Cursor.Y-1)) && !h.Buf.Settings["keepautoindent"].(bool) {
@@ -903,10 +941,22 @@ func (h *BufPane) OutdentSelection() bool {
 	return false
 }
 
-// Autocomplete cycles the suggestions and performs autocompletion if there are suggestions
+// Autocomplete cycles the suggestions and performs autocompletion if there are suggestions.
+// It also moves to the next tabstop of the snippet being expanded, or expands the snippet
+// whose prefix is the word before the cursor.
 func (h *BufPane) Autocomplete() bool {
 	b := h.Buf
 
+	if b.HasSuggestions && b.InSnippet() {
+		// cycle the choices of a tabstop
+		b.CycleAutocomplete(true)
+		return true
+	} else if b.NextTabstop() {
+		h.Cursor = b.GetActiveCursor()
+		h.Relocate()
+		return true
+	}
+
 	if h.Cursor.HasSelection() {
 		return false
 	}
@@ -925,11 +975,30 @@ func (h *BufPane) Autocomplete() bool {
 		b.CycleAutocomplete(true)
 		return true
 	}
-	return b.Autocomplete(buffer.BufferComplete)
+	if ok, err := b.ExpandSnippetPrefix(); err != nil {
+		InfoBar.Error(err)
+	} else if ok {
+		h.Cursor = b.GetActiveCursor()
+		h.Relocate()
+		return true
+	}
+	// unlike the fuzzy matches of OpenCompletion, only the items starting
+	// with the word are shown so that Tab inserts a tab after other words
+	return h.openCompletion(b.PrefixCompletions(b.CompletionItems()))
 }
 
-// CycleAutocompleteBack cycles back in the autocomplete suggestion list
+// CycleAutocompleteBack cycles back in the autocomplete suggestion list, or moves to the
+// previous tabstop of the snippet being expanded
 func (h *BufPane) CycleAutocompleteBack() bool {
+	if h.Buf.HasSuggestions && h.Buf.InSnippet() {
+		h.Buf.CycleAutocomplete(false)
+		return true
+	} else if h.Buf.PreviousTabstop() {
+		h.Cursor = h.Buf.GetActiveCursor()
+		h.Relocate()
+		return true
+	}
+
 	if h.Cursor.HasSelection() {
 		return false
 	}
@@ -1893,13 +1962,17 @@ func (h *BufPane) ClearInfo() bool {
 // ForceQuit closes the current tab or view even if there are unsaved changes
 // (no prompt)
 func (h *BufPane) ForceQuit() bool {
+	if h.popup != nil {
+		h.popup.Close()
+		return true
+	}
 	h.Buf.Close()
 	if len(MainTab().Panes) > 1 {
 		h.Unsplit()
 	} else if len(Tabs.List) > 1 {
 		Tabs.RemoveTab(h.splitID)
 	} else {
-		screen.Screen.Fini()
+		screen.Fini()
 		InfoBar.Close()
 		runtime.Goexit()
 	}
@@ -1950,7 +2023,7 @@ func (h *BufPane) QuitAll() bool {
 
 	quit := func() {
 		buffer.CloseOpenBuffers()
-		screen.Screen.Fini()
+		screen.Fini()
 		InfoBar.Close()
 		runtime.Goexit()
 	}
@@ -2180,7 +2253,7 @@ func (h *BufPane) SpawnMultiCursorUpN(n int) bool {
 	c := buffer.NewCursor(h.Buf, buffer.Loc{lastC.X, lastC.Y - n})
 	c.LastVisualX = lastC.LastVisualX
 	c.LastWrappedVisualX = lastC.LastWrappedVisualX
-	c.X = c.GetCharPosInLine(h.Buf.LineBytes(c.Y), c.LastVisualX)
+	c.X = c.LineCharPos(c.Y, c.LastVisualX)
 	c.Relocate()
 
 	h.Buf.AddCursor(c)
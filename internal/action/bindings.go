@@ -22,10 +22,13 @@ var Binder = map[string]func(e Event, action string){
 	"command":  InfoMapEvent,
 	"buffer":   BufMapEvent,
 	"terminal": TermMapEvent,
+	"explorer": ExplorerMapEvent,
 }
 
 func writeFile(name string, txt []byte) error {
-	return util.SafeWrite(name, txt, false)
+	err := util.SafeWrite(name, txt, false)
+	config.IgnoreConfigWrite(name)
+	return err
 }
 
 func createBindingsIfNotExist(fname string) {
@@ -34,24 +37,24 @@ func createBindingsIfNotExist(fname string) {
 	}
 }
 
+// the parsed bindings.json files of the user and of the project, which are
+// compared to the new ones when the files change
+var userBindings, projectBindings map[string]interface{}
+
 // InitBindings intializes the bindings map by reading from bindings.json
 func InitBindings() {
-	var parsed map[string]interface{}
-
 	filename := filepath.Join(config.ConfigDir, "bindings.json")
 	createBindingsIfNotExist(filename)
 
-	if _, e := os.Stat(filename); e == nil {
-		input, err := os.ReadFile(filename)
-		if err != nil {
-			screen.TermMessage("Error reading bindings.json file: " + err.Error())
-			return
-		}
+	var err error
+	userBindings, err = readBindings(filename)
+	if err != nil {
+		screen.TermMessage(err)
+	}
 
-		err = json5.Unmarshal(input, &parsed)
-		if err != nil {
-			screen.TermMessage("Error reading bindings.json:", err.Error())
-		}
+	projectBindings, err = readProjectBindings()
+	if err != nil {
+		screen.TermMessage(err)
 	}
 
 	for p, bind := range Binder {
@@ -62,6 +65,115 @@ func InitBindings() {
 		}
 	}
 
+	// the project's bindings take precedence over the user's
+	applyBindings(userBindings)
+	applyBindings(projectBindings)
+}
+
+// ReloadBindings applies the changes of the bindings.json files. The keys
+// which are no longer bound in the files get their default binding back.
+// If a file cannot be parsed, the bindings are left unchanged.
+func ReloadBindings() error {
+	user, err := readBindings(filepath.Join(config.ConfigDir, "bindings.json"))
+	if err != nil {
+		return err
+	}
+	project, err := readProjectBindings()
+	if err != nil {
+		return err
+	}
+
+	bound := bindingKeys(user, project)
+	for pane, keys := range bindingKeys(userBindings, projectBindings) {
+		for k := range keys {
+			if !bound[pane][k] {
+				resetBinding(pane, k)
+			}
+		}
+	}
+
+	userBindings, projectBindings = user, project
+	applyBindings(userBindings)
+	applyBindings(projectBindings)
+	return nil
+}
+
+// readProjectBindings reads the project's bindings.json. Its bindings can
+// run commands, so it is only read once the user trusts the project.
+func readProjectBindings() (map[string]interface{}, error) {
+	if config.ProjectDir == "" || !config.ProjectTrusted() {
+		return nil, nil
+	}
+	return readBindings(filepath.Join(config.ProjectDir, "bindings.json"))
+}
+
+// bindingKeys returns the keys bound by parsed bindings.json files for
+// each pane type
+func bindingKeys(files ...map[string]interface{}) map[string]map[string]bool {
+	keys := make(map[string]map[string]bool)
+	add := func(pane, k string) {
+		if keys[pane] == nil {
+			keys[pane] = make(map[string]bool)
+		}
+		keys[pane][k] = true
+	}
+	for _, parsed := range files {
+		for k, v := range parsed {
+			switch val := v.(type) {
+			case string:
+				add("buffer", k)
+			case map[string]interface{}:
+				for e := range val {
+					add(k, e)
+				}
+			}
+		}
+	}
+	return keys
+}
+
+// resetBinding binds a key of a pane type to its default action, or
+// unbinds it if it has none
+func resetBinding(pane, k string) {
+	if a, ok := DefaultBindings(pane)[k]; ok {
+		if bind := Binder[pane]; bind != nil {
+			BindKey(k, a, bind)
+		}
+		return
+	}
+	if pane != "buffer" {
+		return
+	}
+	if key, err := findEvent(k); err == nil {
+		if strings.HasPrefix(k, "\x1b") {
+			screen.UnregisterRawSeq(k)
+		}
+		BufUnmap(key)
+		delete(config.Bindings["buffer"], k)
+	}
+}
+
+// readBindings reads a bindings.json file, returning nil if it does not
+// exist
+func readBindings(filename string) (map[string]interface{}, error) {
+	var parsed map[string]interface{}
+
+	if _, e := os.Stat(filename); e == nil {
+		input, err := os.ReadFile(filename)
+		if err != nil {
+			return nil, errors.New("Error reading bindings.json file: " + err.Error())
+		}
+
+		err = json5.Unmarshal(input, &parsed)
+		if err != nil {
+			return nil, config.NewJSONError(filename, input, err)
+		}
+	}
+	return parsed, nil
+}
+
+// applyBindings binds the keys of a parsed bindings.json file
+func applyBindings(parsed map[string]interface{}) {
 	for k, v := range parsed {
 		switch val := v.(type) {
 		case string:
@@ -261,6 +373,33 @@ func eventsEqual(e1 Event, e2 Event) bool {
 	return e1 == e2
 }
 
+// KeyEvents parses a key, or a sequence of keys such as "<Ctrl-x><Ctrl-s>",
+// as written in bindings.json and returns the tcell events typing it
+func KeyEvents(k string) ([]*tcell.EventKey, error) {
+	e, err := findEvent(k)
+	if err != nil {
+		return nil, err
+	}
+	events := []Event{e}
+	if seq, ok := e.(KeySequenceEvent); ok {
+		events = seq.keys
+	}
+
+	var keys []*tcell.EventKey
+	for _, e := range events {
+		ke, ok := e.(KeyEvent)
+		if !ok || ke.any {
+			return nil, errors.New(k + " is not a key")
+		}
+		r := ke.r
+		if ke.code != tcell.KeyRune {
+			r = rune(ke.code)
+		}
+		keys = append(keys, tcell.NewEventKey(ke.code, r, ke.mod, ""))
+	}
+	return keys, nil
+}
+
 // TryBindKey tries to bind a key by writing to config.ConfigDir/bindings.json
 // Returns true if the keybinding already existed and a possible error
 func TryBindKey(k, v string, overwrite bool) (bool, error) {
@@ -277,7 +416,7 @@ func TryBindKey(k, v string, overwrite bool) (bool, error) {
 
 		err = json5.Unmarshal(input, &parsed)
 		if err != nil {
-			return false, errors.New("Error reading bindings.json: " + err.Error())
+			return false, config.NewJSONError(filename, input, err)
 		}
 
 		key, err := findEvent(k)
@@ -307,6 +446,7 @@ func TryBindKey(k, v string, overwrite bool) (bool, error) {
 		}
 
 		BindKey(k, v, Binder["buffer"])
+		userBindings = parsed
 
 		txt, _ := json.MarshalIndent(parsed, "", "    ")
 		txt = append(txt, '\n')
@@ -315,6 +455,54 @@ func TryBindKey(k, v string, overwrite bool) (bool, error) {
 	return false, e
 }
 
+// TryBindPluginKey binds a key like TryBindKey for the given plugin, and
+// restores the key's previous action when the plugin is unloaded if it is
+// still bound to v then
+func TryBindPluginKey(p *config.Plugin, k, v string, overwrite bool) (bool, error) {
+	key, err := findEvent(k)
+	if err != nil {
+		return false, err
+	}
+	name := key.Name()
+	prev, had := config.Bindings["buffer"][name]
+
+	ok, err := TryBindKey(k, v, overwrite)
+	if err != nil {
+		return ok, err
+	}
+	// the key is already bound to v in bindings.json, but the binding was
+	// removed when the plugin was previously unloaded
+	if config.Bindings["buffer"][name] != v && userBinding(key) == v {
+		BindKey(k, v, Binder["buffer"])
+	}
+	if config.Bindings["buffer"][name] == v {
+		p.OnUnload(func() {
+			if config.Bindings["buffer"][name] != v {
+				return
+			}
+			if had && prev != v {
+				BindKey(name, prev, Binder["buffer"])
+			} else {
+				resetBinding("buffer", name)
+			}
+		})
+	}
+	return ok, nil
+}
+
+// userBinding returns the action that the user's bindings.json binds to
+// the given event in the buffer pane
+func userBinding(key Event) string {
+	for ev, a := range userBindings {
+		if e, err := findEvent(ev); err == nil && eventsEqual(e, key) {
+			if s, ok := a.(string); ok {
+				return s
+			}
+		}
+	}
+	return ""
+}
+
 // UnbindKey removes the binding for a key from the bindings.json file
 func UnbindKey(k string) error {
 	var e error
@@ -330,7 +518,7 @@ func UnbindKey(k string) error {
 
 		err = json5.Unmarshal(input, &parsed)
 		if err != nil {
-			return errors.New("Error reading bindings.json: " + err.Error())
+			return config.NewJSONError(filename, input, err)
 		}
 
 		key, err := findEvent(k)
@@ -358,6 +546,7 @@ func UnbindKey(k string) error {
 			BufUnmap(key)
 			delete(config.Bindings["buffer"], k)
 		}
+		userBindings = parsed
 
 		txt, _ := json.MarshalIndent(parsed, "", "    ")
 		txt = append(txt, '\n')
@@ -91,6 +91,7 @@ func LuaAction(fn string, k Event) BufAction {
 // BufMapEvent maps an event to an action
 func BufMapEvent(k Event, action string) {
 	config.Bindings["buffer"][k.Name()] = action
+	binding := action
 
 	var actionfns []BufAction
 	var names []string
@@ -149,19 +150,7 @@ func BufMapEvent(k Event, action string) {
 	}
 	bufAction := func(h *BufPane, te *tcell.EventMouse) bool {
 		for i, a := range actionfns {
-			var success bool
-			if _, ok := MultiActions[names[i]]; ok {
-				success = true
-				for _, c := range h.Buf.GetCursors() {
-					h.Buf.SetCurCursor(c.Num)
-					h.Cursor = c
-					success = success && h.execAction(a, names[i], te)
-				}
-			} else {
-				h.Buf.SetCurCursor(0)
-				h.Cursor = h.Buf.GetActiveCursor()
-				success = h.execAction(a, names[i], te)
-			}
+			success := h.execActionOnCursors(a, names[i], te)
 
 			// if the action changed the current pane, update the reference
 			h = MainTab().CurPane()
@@ -179,23 +168,17 @@ func BufMapEvent(k Event, action string) {
 
 	switch e := k.(type) {
 	case KeyEvent, KeySequenceEvent, RawEvent:
-		BufBindings.RegisterKeyBinding(e, BufKeyActionGeneral(func(h *BufPane) bool {
+		BufBindings.RegisterNamedKeyBinding(e, binding, BufKeyActionGeneral(func(h *BufPane) bool {
 			return bufAction(h, nil)
 		}))
 	case MouseEvent:
-		BufBindings.RegisterMouseBinding(e, BufMouseActionGeneral(bufAction))
+		BufBindings.RegisterNamedMouseBinding(e, binding, BufMouseActionGeneral(bufAction))
 	}
 }
 
 // BufUnmap unmaps a key or mouse event from any action
 func BufUnmap(k Event) {
-	// TODO
-	// delete(BufKeyBindings, k)
-	//
-	// switch e := k.(type) {
-	// case MouseEvent:
-	// 	delete(BufMouseBindings, e)
-	// }
+	BufBindings.DeleteAllBindings(k)
 }
 
 // The BufPane connects the buffer and the window
@@ -239,6 +222,9 @@ type BufPane struct {
 	doubleClick bool
 	// Same here, just to keep track for mouse move events
 	tripleClick bool
+	// Was the mouse pressed on the scroll bar or on the minimap? Dragging
+	// it then scrolls the buffer
+	barClick bool
 
 	// Should the current multiple cursor selection search based on word or
 	// based on selection (false for selection, true for word)
@@ -247,6 +233,11 @@ type BufPane struct {
 	splitID uint64
 	tab     *Tab
 
+	// popup is the popup showing this pane, if it is shown in a popup
+	popup *Popup
+	// completion is the completion menu of the pane, if it is open
+	completion *completionMenu
+
 	// remember original location of a search in case the search is canceled
 	searchOrig buffer.Loc
 
@@ -294,6 +285,9 @@ func (h *BufPane) finishInitialize() {
 	if err != nil {
 		screen.TermMessage(err)
 	}
+	if err := config.Publish(config.EvBufPaneOpen, map[string]interface{}{"bufpane": h}); err != nil {
+		screen.TermMessage(err)
+	}
 }
 
 // Resize resizes the pane
@@ -463,6 +457,9 @@ func (h *BufPane) HandleEvent(event tcell.Event) {
 	case *tcell.EventKey:
 		ke := keyEvent(e)
 
+		if h.completion != nil && h.completion.handleKey(e) {
+			break
+		}
 		done := h.DoKeyEvent(ke)
 		if !done && e.Key() == tcell.KeyRune {
 			h.DoRuneInsert(e.Rune())
@@ -503,6 +500,10 @@ func (h *BufPane) HandleEvent(event tcell.Event) {
 	}
 	h.Buf.MergeCursors()
 
+	if h.completion != nil {
+		h.completion.update()
+	}
+
 	if h.IsActive() {
 		// Display any gutter messages for this line
 		c := h.Buf.GetActiveCursor()
@@ -559,6 +560,10 @@ func (h *BufPane) execAction(action BufAction, name string, te *tcell.EventMouse
 		h.Buf.HasSuggestions = false
 	}
 
+	if h.popup != nil && popupDisabledActions[name] {
+		return false
+	}
+
 	if !h.PluginCB("pre" + name) {
 		return false
 	}
@@ -583,6 +588,23 @@ func (h *BufPane) execAction(action BufAction, name string, te *tcell.EventMouse
 	return success
 }
 
+// execActionOnCursors executes the action with every cursor if it is a
+// multi cursor action, or with the first cursor otherwise
+func (h *BufPane) execActionOnCursors(action BufAction, name string, te *tcell.EventMouse) bool {
+	if _, ok := MultiActions[name]; ok {
+		success := true
+		for _, c := range h.Buf.GetCursors() {
+			h.Buf.SetCurCursor(c.Num)
+			h.Cursor = c
+			success = success && h.execAction(action, name, te)
+		}
+		return success
+	}
+	h.Buf.SetCurCursor(0)
+	h.Cursor = h.Buf.GetActiveCursor()
+	return h.execAction(action, name, te)
+}
+
 func (h *BufPane) completeAction(action string) {
 	h.PluginCB("on" + action)
 }
@@ -640,6 +662,9 @@ func (h *BufPane) DoRuneInsert(r rune) {
 			h.Buf.Replace(c.Loc, next, string(r))
 		} else {
 			h.Buf.Insert(c.Loc, string(r))
+			if h.Buf.Settings["autoindent"].(bool) && h.Buf.ShouldReindent(c, false) {
+				h.Buf.Reindent(c.Y)
+			}
 		}
 		if recordingMacro {
 			curmacro = append(curmacro, r)
@@ -699,6 +724,9 @@ func (h *BufPane) SetActive(b bool) {
 	}
 
 	h.BWindow.SetActive(b)
+	if !b {
+		h.CloseCompletion()
+	}
 	if b {
 		// Display any gutter messages for this line
 		c := h.Buf.GetActiveCursor()
@@ -718,6 +746,9 @@ func (h *BufPane) SetActive(b bool) {
 		if err != nil {
 			screen.TermMessage(err)
 		}
+		if err := config.Publish(config.EvPaneActivated, map[string]interface{}{"bufpane": h}); err != nil {
+			screen.TermMessage(err)
+		}
 	}
 }
 
@@ -790,6 +821,7 @@ var BufKeyActions = map[string]BufKeyAction{
 	"OutdentSelection":          (*BufPane).OutdentSelection,
 	"Autocomplete":              (*BufPane).Autocomplete,
 	"CycleAutocompleteBack":     (*BufPane).CycleAutocompleteBack,
+	"OpenCompletion":            (*BufPane).OpenCompletion,
 	"OutdentLine":               (*BufPane).OutdentLine,
 	"IndentLine":                (*BufPane).IndentLine,
 	"Paste":                     (*BufPane).Paste,
@@ -818,6 +850,12 @@ var BufKeyActions = map[string]BufKeyAction{
 	"ClearStatus":               (*BufPane).ClearStatus,
 	"ShellMode":                 (*BufPane).ShellMode,
 	"CommandMode":               (*BufPane).CommandMode,
+	"FindFile":                  (*BufPane).FindFile,
+	"FindBuffer":                (*BufPane).FindBuffer,
+	"FindRecent":                (*BufPane).FindRecent,
+	"FindCommand":               (*BufPane).FindCommand,
+	"FindSymbol":                (*BufPane).FindSymbol,
+	"ToggleExplorer":            (*BufPane).ToggleExplorer,
 	"ToggleOverwriteMode":       (*BufPane).ToggleOverwriteMode,
 	"Escape":                    (*BufPane).Escape,
 	"Quit":                      (*BufPane).Quit,
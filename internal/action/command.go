@@ -9,13 +9,17 @@ import (
 	"path/filepath"
 	"reflect"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 
+	luar "layeh.com/gopher-luar"
+
 	shellquote "github.com/kballard/go-shellquote"
 	"github.com/zyedidia/micro/v2/internal/buffer"
 	"github.com/zyedidia/micro/v2/internal/clipboard"
 	"github.com/zyedidia/micro/v2/internal/config"
+	ulua "github.com/zyedidia/micro/v2/internal/lua"
 	"github.com/zyedidia/micro/v2/internal/screen"
 	"github.com/zyedidia/micro/v2/internal/shell"
 	"github.com/zyedidia/micro/v2/internal/util"
@@ -23,59 +27,84 @@ import (
 
 // A Command contains information about how to execute a command
 // It has the action for that command as well as a completer function
+// and a one line description shown in the command palette
 type Command struct {
 	action    func(*BufPane, []string)
 	completer buffer.Completer
+	desc      string
 }
 
 var commands map[string]Command
 
 func InitCommands() {
 	commands = map[string]Command{
-		"set":        {(*BufPane).SetCmd, OptionValueComplete},
-		"reset":      {(*BufPane).ResetCmd, OptionValueComplete},
-		"setlocal":   {(*BufPane).SetLocalCmd, OptionValueComplete},
-		"show":       {(*BufPane).ShowCmd, OptionComplete},
-		"showkey":    {(*BufPane).ShowKeyCmd, nil},
-		"run":        {(*BufPane).RunCmd, nil},
-		"bind":       {(*BufPane).BindCmd, nil},
-		"unbind":     {(*BufPane).UnbindCmd, nil},
-		"quit":       {(*BufPane).QuitCmd, nil},
-		"goto":       {(*BufPane).GotoCmd, nil},
-		"jump":       {(*BufPane).JumpCmd, nil},
-		"save":       {(*BufPane).SaveCmd, nil},
-		"replace":    {(*BufPane).ReplaceCmd, nil},
-		"replaceall": {(*BufPane).ReplaceAllCmd, nil},
-		"vsplit":     {(*BufPane).VSplitCmd, buffer.FileComplete},
-		"hsplit":     {(*BufPane).HSplitCmd, buffer.FileComplete},
-		"tab":        {(*BufPane).NewTabCmd, buffer.FileComplete},
-		"help":       {(*BufPane).HelpCmd, HelpComplete},
-		"eval":       {(*BufPane).EvalCmd, nil},
-		"log":        {(*BufPane).ToggleLogCmd, nil},
-		"plugin":     {(*BufPane).PluginCmd, PluginComplete},
-		"reload":     {(*BufPane).ReloadCmd, nil},
-		"reopen":     {(*BufPane).ReopenCmd, nil},
-		"cd":         {(*BufPane).CdCmd, buffer.FileComplete},
-		"pwd":        {(*BufPane).PwdCmd, nil},
-		"open":       {(*BufPane).OpenCmd, buffer.FileComplete},
-		"tabmove":    {(*BufPane).TabMoveCmd, nil},
-		"tabswitch":  {(*BufPane).TabSwitchCmd, nil},
-		"term":       {(*BufPane).TermCmd, nil},
-		"memusage":   {(*BufPane).MemUsageCmd, nil},
-		"retab":      {(*BufPane).RetabCmd, nil},
-		"raw":        {(*BufPane).RawCmd, nil},
-		"textfilter": {(*BufPane).TextFilterCmd, nil},
+		"set":         {(*BufPane).SetCmd, OptionValueComplete, "Set a global option"},
+		"reset":       {(*BufPane).ResetCmd, OptionValueComplete, "Reset a global option to its default value"},
+		"setlocal":    {(*BufPane).SetLocalCmd, OptionValueComplete, "Set an option for the current buffer only"},
+		"show":        {(*BufPane).ShowCmd, OptionComplete, "Show the value of an option"},
+		"showkey":     {(*BufPane).ShowKeyCmd, nil, "Show the action bound to a key"},
+		"run":         {(*BufPane).RunCmd, nil, "Run a shell command in the background"},
+		"bind":        {(*BufPane).BindCmd, nil, "Bind a key to an action"},
+		"unbind":      {(*BufPane).UnbindCmd, nil, "Restore the default binding of a key"},
+		"quit":        {(*BufPane).QuitCmd, nil, "Quit micro"},
+		"goto":        {(*BufPane).GotoCmd, nil, "Go to a line and column"},
+		"jump":        {(*BufPane).JumpCmd, nil, "Jump a number of lines up or down"},
+		"save":        {(*BufPane).SaveCmd, nil, "Save the buffer, optionally under a new name"},
+		"replace":     {(*BufPane).ReplaceCmd, nil, "Replace a regular expression, asking at each match"},
+		"replaceall":  {(*BufPane).ReplaceAllCmd, nil, "Replace every match of a regular expression"},
+		"vsplit":      {(*BufPane).VSplitCmd, buffer.FileComplete, "Open a file in a vertical split"},
+		"hsplit":      {(*BufPane).HSplitCmd, buffer.FileComplete, "Open a file in a horizontal split"},
+		"tab":         {(*BufPane).NewTabCmd, buffer.FileComplete, "Open a file in a new tab"},
+		"help":        {(*BufPane).HelpCmd, HelpComplete, "Open a help topic"},
+		"eval":        {(*BufPane).EvalCmd, nil, "Evaluate a Lua expression"},
+		"log":         {(*BufPane).ToggleLogCmd, nil, "Toggle the log view"},
+		"plugin":      {(*BufPane).PluginCmd, PluginComplete, "Manage plugins"},
+		"reload":      {(*BufPane).ReloadCmd, nil, "Reload the runtime files and the settings"},
+		"reopen":      {(*BufPane).ReopenCmd, nil, "Reload the buffer from disk"},
+		"cd":          {(*BufPane).CdCmd, buffer.FileComplete, "Change the working directory"},
+		"pwd":         {(*BufPane).PwdCmd, nil, "Print the working directory"},
+		"open":        {(*BufPane).OpenCmd, buffer.FileComplete, "Open a file in the current pane"},
+		"tabmove":     {(*BufPane).TabMoveCmd, nil, "Move the current tab"},
+		"tabswitch":   {(*BufPane).TabSwitchCmd, nil, "Switch to a tab by number or name"},
+		"term":        {(*BufPane).TermCmd, nil, "Open a terminal emulator"},
+		"memusage":    {(*BufPane).MemUsageCmd, nil, "Show the memory usage"},
+		"retab":       {(*BufPane).RetabCmd, nil, "Convert the indentation to the tabstospaces setting"},
+		"reindent":    {(*BufPane).ReindentCmd, nil, "Re-indent the buffer or the selection"},
+		"raw":         {(*BufPane).RawCmd, nil, "Show the raw escape sequences of key presses"},
+		"textfilter":  {(*BufPane).TextFilterCmd, nil, "Pipe the selection through a shell command"},
+		"filetype":    {(*BufPane).FileTypeCmd, nil, "Set the filetype of the buffer"},
+		"colorscheme": {(*BufPane).ColorschemeCmd, ColorschemeComplete, "Set the colorscheme"},
+		"export":      {(*BufPane).ExportCmd, buffer.FileComplete, "Export the buffer as HTML or ANSI text"},
+		"finder":      {(*BufPane).FinderCmd, FinderComplete, "Open the fuzzy finder"},
 	}
 }
 
 // MakeCommand is a function to easily create new commands
 // This can be called by plugins in Lua so that plugins can define their own commands
-func MakeCommand(name string, action func(bp *BufPane, args []string), completer buffer.Completer) {
+// An optional description is shown in the command palette
+func MakeCommand(name string, action func(bp *BufPane, args []string), completer buffer.Completer, desc ...string) {
 	if action != nil {
-		commands[name] = Command{action, completer}
+		commands[name] = Command{action, completer, strings.Join(desc, " ")}
 	}
 }
 
+// MakePluginCommand creates a new command like MakeCommand, which is
+// removed, or restored to what it was before, when the plugin is unloaded
+func MakePluginCommand(p *config.Plugin, name string, action func(bp *BufPane, args []string), completer buffer.Completer, desc ...string) {
+	if action == nil {
+		return
+	}
+	prev, had := commands[name]
+	MakeCommand(name, action, completer, desc...)
+	p.OnUnload(func() {
+		if had {
+			commands[name] = prev
+		} else {
+			delete(commands, name)
+		}
+	})
+}
+
 // CommandEditAction returns a bindable function that opens a prompt with
 // the given string and executes the command when the user presses
 // enter
@@ -99,7 +128,7 @@ func CommandAction(cmd string) BufKeyAction {
 	}
 }
 
-var PluginCmds = []string{"install", "remove", "update", "available", "list", "search"}
+var PluginCmds = []string{"install", "remove", "update", "available", "list", "search", "sync", "reload", "disable"}
 
 // PluginCmd installs, removes, updates, lists, or searches for given plugins
 func (h *BufPane) PluginCmd(args []string) {
@@ -108,11 +137,91 @@ func (h *BufPane) PluginCmd(args []string) {
 		return
 	}
 
+	switch args[0] {
+	case "reload":
+		for _, name := range args[1:] {
+			if err := reloadPlugin(name); err != nil {
+				InfoBar.Error(err)
+				return
+			}
+		}
+		InfoBar.Message("Reloaded ", strings.Join(args[1:], " "))
+		return
+	case "disable":
+		for _, name := range args[1:] {
+			if findInstalledPlugin(name) == nil {
+				InfoBar.Error("Unknown plugin ", name)
+				return
+			}
+			if err := SetGlobalOptionNative(name, false); err != nil {
+				InfoBar.Error(err)
+				return
+			}
+		}
+		return
+	}
+
 	if h.Buf.Type != buffer.BTLog {
 		h.OpenLogBuf()
 	}
 
-	config.PluginCommand(buffer.LogBuf, args[0], args[1:])
+	config.PluginCommand(buffer.LogBuf, args[0], args[1:], func(question string, yes func()) {
+		InfoBar.YNPrompt(question+" (y,n)", func(ok, canceled bool) {
+			if ok && !canceled {
+				yes()
+			}
+		})
+	})
+}
+
+// reloadPlugin unloads the given plugin if it is loaded, then loads it
+// again from its files and runs its initialization callbacks, as well as
+// onBufferOpen for every open buffer
+func reloadPlugin(name string) error {
+	p := findInstalledPlugin(name)
+	if p == nil {
+		return errors.New("Unknown plugin " + name)
+	}
+	if err := p.Unload(); err != nil {
+		return errors.New("Plugin " + p.Name + ": " + err.Error())
+	}
+	if err := p.Load(); err != nil {
+		return err
+	}
+	if !p.IsLoaded() {
+		return errors.New("Plugin " + p.Name + " is disabled")
+	}
+	for _, fn := range []string{"preinit", "init", "postinit"} {
+		if _, err := p.Call(fn); err != nil && err != config.ErrNoSuchFunction {
+			return errors.New("Plugin " + p.Name + ": " + err.Error())
+		}
+	}
+	for _, b := range buffer.OpenBuffers {
+		if _, err := p.Call("onBufferOpen", luar.New(ulua.L, b)); err != nil && err != config.ErrNoSuchFunction {
+			return errors.New("Plugin " + p.Name + ": " + err.Error())
+		}
+	}
+	for _, t := range Tabs.List {
+		for _, pane := range t.Panes {
+			if bp, ok := pane.(*BufPane); ok {
+				if _, err := p.Call("onBufPaneOpen", luar.New(ulua.L, bp)); err != nil && err != config.ErrNoSuchFunction {
+					return errors.New("Plugin " + p.Name + ": " + err.Error())
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// findInstalledPlugin returns the plugin with the given name, whether it is
+// loaded or not
+func findInstalledPlugin(name string) *config.Plugin {
+	for _, p := range config.Plugins {
+		if p.Name == name {
+			return p
+		}
+	}
+	return nil
 }
 
 // RetabCmd changes all spaces to tabs or all tabs to spaces
@@ -121,6 +230,162 @@ func (h *BufPane) RetabCmd(args []string) {
 	h.Buf.Retab()
 }
 
+// ReindentCmd fixes the indentation of the selected lines, or of the whole
+// buffer, using the indentation rules of the syntax file
+func (h *BufPane) ReindentCmd(args []string) {
+	start, end := 0, h.Buf.LinesNum()-1
+	if h.Cursor.HasSelection() {
+		a, b := h.Cursor.CurSelection[0], h.Cursor.CurSelection[1]
+		if b.LessThan(a) {
+			a, b = b, a
+		}
+		start, end = a.Y, b.Y
+		if b.X == 0 && end > start {
+			end--
+		}
+	}
+
+	if h.Buf.SignificantIndent() {
+		InfoBar.Error("The indentation of ", h.Buf.FileType(), " is significant and cannot be fixed")
+		return
+	}
+	if !h.Buf.ReindentLines(start, end) {
+		InfoBar.Error("No indentation rules for filetype ", h.Buf.FileType())
+	}
+	h.Relocate()
+}
+
+// FileTypeCmd shows why the current filetype was chosen, or detects the
+// filetype again with `filetype detect`
+func (h *BufPane) FileTypeCmd(args []string) {
+	if len(args) > 0 {
+		if args[0] != "detect" {
+			InfoBar.Error("Invalid argument: " + args[0])
+			return
+		}
+		h.Buf.DetectFileType()
+	}
+
+	InfoBar.Message(fmt.Sprintf("filetype %s: %s", h.Buf.FileType(), h.Buf.FileTypeReason()))
+}
+
+// ColorschemeCmd shows or sets the colorscheme. `colorscheme preview` opens
+// a prompt which applies each colorscheme while the user cycles through
+// them with the up and down arrows, and keeps the one chosen with enter.
+func (h *BufPane) ColorschemeCmd(args []string) {
+	if len(args) == 0 {
+		InfoBar.Message(config.GetGlobalOption("colorscheme"))
+		return
+	}
+	if args[0] != "preview" {
+		if err := SetGlobalOption("colorscheme", args[0]); err != nil {
+			InfoBar.Error(err)
+		}
+		return
+	}
+
+	orig := config.GetGlobalOption("colorscheme").(string)
+	var names []string
+	for _, f := range config.ListRuntimeFiles(config.RTColorscheme) {
+		names = append(names, f.Name())
+	}
+	sort.Strings(names)
+
+	InfoBar.Prompt("Colorscheme: ", orig, "Colorscheme", func(resp string) {
+		if resp != config.GetGlobalOption("colorscheme") && config.ColorschemeExists(resp) {
+			config.GlobalSettings["colorscheme"] = resp
+			reloadColorscheme()
+		}
+	}, func(resp string, canceled bool) {
+		delete(InfoBar.History, "Colorscheme")
+
+		previewed := config.GetGlobalOption("colorscheme")
+		config.GlobalSettings["colorscheme"] = orig
+		if !canceled && config.ColorschemeExists(resp) {
+			if err := SetGlobalOptionNative("colorscheme", resp); err != nil {
+				InfoBar.Error(err)
+			}
+			if resp != orig {
+				return
+			}
+		} else if !canceled {
+			InfoBar.Error(resp, " is not a valid colorscheme")
+		}
+		if previewed != orig {
+			reloadColorscheme()
+		}
+	})
+
+	// the prompt's history holds the colorschemes, so that the up and down
+	// arrows cycle through them starting from the current one
+	InfoBar.History["Colorscheme"] = append(names, "")
+	InfoBar.HistoryNum = len(names)
+	for i, name := range names {
+		if name == orig {
+			InfoBar.HistoryNum = i
+		}
+	}
+}
+
+// ExportCmd exports the buffer, or the current selection, with its syntax
+// highlighting as HTML or as text with ANSI escape sequences. The result is
+// written to the given file, or copied to the clipboard if no file is given.
+// The flags -n and -d add line numbers and diffgutter markers.
+func (h *BufPane) ExportCmd(args []string) {
+	var opts buffer.ExportOptions
+	var rest []string
+	for _, arg := range args {
+		switch arg {
+		case "-n":
+			opts.LineNumbers = true
+		case "-d":
+			opts.DiffGutter = true
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	if len(rest) < 1 || len(rest) > 2 {
+		InfoBar.Error("usage: export [-n] [-d] html|ansi [file]")
+		return
+	}
+
+	start, end := h.Buf.Start(), h.Buf.End()
+	if h.Cursor.HasSelection() {
+		start, end = h.Cursor.CurSelection[0], h.Cursor.CurSelection[1]
+	}
+
+	var out string
+	switch rest[0] {
+	case "html":
+		out = h.Buf.ExportHTML(start, end, opts)
+	case "ansi":
+		out = h.Buf.ExportANSI(start, end, opts)
+	default:
+		InfoBar.Error("Invalid export format: " + rest[0])
+		return
+	}
+
+	if len(rest) == 1 {
+		if err := clipboard.Write(out, clipboard.ClipboardReg); err != nil {
+			InfoBar.Error(err)
+			return
+		}
+		InfoBar.Message("Exported ", rest[0], " to the clipboard")
+		return
+	}
+
+	filename, err := util.ReplaceHome(rest[1])
+	if err != nil {
+		InfoBar.Error(err)
+		return
+	}
+	if err := os.WriteFile(filename, []byte(out), util.FileMode); err != nil {
+		InfoBar.Error(err)
+		return
+	}
+	InfoBar.Message("Exported ", rest[0], " to ", filename)
+}
+
 // RawCmd opens a new raw view which displays the escape sequences micro
 // is receiving in real-time
 func (h *BufPane) RawCmd(args []string) {
@@ -344,6 +609,86 @@ func ReloadConfig() {
 	reloadRuntime(false)
 }
 
+// ReloadConfigFile applies the changes of a settings.json or bindings.json
+// file which has been modified on disk
+func ReloadConfigFile(path string) {
+	var err error
+	if filepath.Base(path) == "bindings.json" {
+		err = ReloadBindings()
+	} else {
+		err = ReloadSettings()
+	}
+	if err != nil {
+		ConfigError(err)
+		return
+	}
+	InfoBar.Message("Reloaded ", path)
+
+	// editing the project's files revokes the trust in the project
+	if filepath.Dir(path) == config.ProjectDir {
+		PromptProjectTrust()
+	}
+}
+
+// ReloadSettings reads the settings.json files again and sets the options
+// whose value has changed, so that only their callbacks run. If a file
+// cannot be parsed, the current settings are kept.
+func ReloadSettings() error {
+	err := config.ReadSettings()
+	if _, ok := err.(*config.JSONError); ok {
+		return err
+	}
+
+	parsed := config.ParsedSettings()
+	for k, def := range config.DefaultAllSettings() {
+		if _, ok := config.VolatileSettings[k]; ok {
+			continue
+		}
+		v, ok := parsed[k]
+		if !ok {
+			v = def
+		}
+		if e := doSetGlobalOptionNative(k, v); e != nil {
+			err = e
+		}
+	}
+	for _, b := range buffer.OpenBuffers {
+		b.ReloadSettings(true)
+	}
+	return err
+}
+
+// ConfigError shows an error about a configuration file in the infobar. If
+// the error has a position, clicking the message opens the file there.
+func ConfigError(err error) {
+	if e, ok := err.(*config.JSONError); ok {
+		InfoBar.ErrorAt(e.Path, buffer.Loc{X: e.Col - 1, Y: e.Line - 1}, err)
+	} else {
+		InfoBar.Error(err)
+	}
+}
+
+// OpenMessageLink opens the file which the current error of the infobar
+// refers to in a new tab, with the cursor at the location of the error
+func OpenMessageLink() bool {
+	link := InfoBar.Link
+	if !InfoBar.HasError || link == nil {
+		return false
+	}
+	h := MainTab().CurPane()
+	if h == nil {
+		return false
+	}
+	InfoBar.Message("")
+	h.NewTabCmd([]string{link.Path})
+
+	h = MainTab().CurPane()
+	loc := link.Loc.Clamp(h.Buf.Start(), h.Buf.End())
+	loc.X = util.Clamp(loc.X, 0, util.CharacterCount(h.Buf.LineBytes(loc.Y)))
+	h.GotoLoc(loc)
+	return true
+}
+
 func reloadRuntime(reloadPlugins bool) {
 	if reloadPlugins {
 		err := config.RunPluginFn("deinit")
@@ -435,7 +780,12 @@ func (h *BufPane) openHelp(page string, hsplit bool, forceSplit bool) error {
 	if data, err := config.FindRuntimeFile(config.RTHelp, page).Data(); err != nil {
 		return errors.New(fmt.Sprintf("Unable to load help text for %s: %v", page, err))
 	} else {
-		helpBuffer := buffer.NewBufferFromString(string(data), page+".md", buffer.BTHelp)
+		text := string(data)
+		if page == "options" {
+			// fill in the options registered by micro and the plugins
+			text = config.GenerateOptionsHelp(text, false)
+		}
+		helpBuffer := buffer.NewBufferFromString(text, page+".md", buffer.BTHelp)
 		helpBuffer.SetName("Help " + page)
 		helpBuffer.SetOptionNative("hltaberrors", false)
 		helpBuffer.SetOptionNative("hltrailingws", false)
@@ -564,9 +914,7 @@ func (h *BufPane) NewTabCmd(args []string) {
 				InfoBar.Error(err)
 				return
 			}
-			tp := NewTabFromBuffer(0, 0, width, height-1-iOffset, b)
-			Tabs.AddTab(tp)
-			Tabs.SetActive(len(Tabs.List) - 1)
+			h.addTab(b)
 		}
 	} else {
 		b := buffer.NewBufferFromString("", "", buffer.BTDefault)
@@ -576,6 +924,24 @@ func (h *BufPane) NewTabCmd(args []string) {
 	}
 }
 
+// addTab opens the buffer in a new tab
+func (h *BufPane) addTab(b *buffer.Buffer) {
+	width, height := screen.Screen.Size()
+	iOffset := config.GetInfoBarOffset()
+	tp := NewTabFromBuffer(0, 0, width, height-1-iOffset, b)
+	Tabs.AddTab(tp)
+	Tabs.SetActive(len(Tabs.List) - 1)
+}
+
+// reloadColorscheme loads the colorscheme given by the colorscheme option
+// and rehighlights all buffers with it
+func reloadColorscheme() {
+	config.InitColorscheme()
+	for _, b := range buffer.OpenBuffers {
+		b.UpdateRules()
+	}
+}
+
 func doSetGlobalOptionNative(option string, nativeValue interface{}) error {
 	if reflect.DeepEqual(config.GlobalSettings[option], nativeValue) {
 		return nil
@@ -587,10 +953,7 @@ func doSetGlobalOptionNative(option string, nativeValue interface{}) error {
 
 	if option == "colorscheme" {
 		// LoadSyntaxFiles()
-		config.InitColorscheme()
-		for _, b := range buffer.OpenBuffers {
-			b.UpdateRules()
-		}
+		reloadColorscheme()
 	} else if option == "infobar" || option == "keymenu" {
 		Tabs.Resize()
 	} else if option == "mouse" {
@@ -616,6 +979,9 @@ func doSetGlobalOptionNative(option string, nativeValue interface{}) error {
 	} else {
 		for _, pl := range config.Plugins {
 			if option == pl.Name {
+				if nativeValue.(bool) {
+					pl.Disabled = false
+				}
 				if nativeValue.(bool) && !pl.Loaded {
 					pl.Load()
 					_, err := pl.Call("init")
@@ -623,8 +989,7 @@ func doSetGlobalOptionNative(option string, nativeValue interface{}) error {
 						screen.TermMessage(err)
 					}
 				} else if !nativeValue.(bool) && pl.Loaded {
-					_, err := pl.Call("deinit")
-					if err != nil && err != config.ErrNoSuchFunction {
+					if err := pl.Unload(); err != nil {
 						screen.TermMessage(err)
 					}
 				}
@@ -702,6 +1067,10 @@ func (h *BufPane) ResetCmd(args []string) {
 
 // SetCmd sets an option
 func (h *BufPane) SetCmd(args []string) {
+	if len(args) >= 1 && args[0] == "-explain" {
+		h.explainSettings(args[1:])
+		return
+	}
 	if len(args) < 2 {
 		InfoBar.Error("Not enough arguments")
 		return
@@ -721,6 +1090,36 @@ func (h *BufPane) SetCmd(args []string) {
 	}
 }
 
+// explainSettings opens a buffer listing the given options (or all the
+// options of the buffer), their values and where they come from, with the
+// sections of settings.json which set them for the buffer
+func (h *BufPane) explainSettings(options []string) {
+	if len(options) == 0 {
+		for k := range h.Buf.Settings {
+			options = append(options, k)
+		}
+		sort.Strings(options)
+	}
+
+	var text strings.Builder
+	for _, option := range options {
+		v, ok := h.Buf.Settings[option]
+		if !ok {
+			InfoBar.Error(option, " is not a valid option")
+			return
+		}
+		fmt.Fprintf(&text, "%s = %v (%s)\n", option, v, h.Buf.SettingOrigin(option))
+		for _, line := range h.Buf.ExplainSetting(option) {
+			text.WriteString("    " + line + "\n")
+		}
+	}
+
+	b := buffer.NewBufferFromString(text.String(), "", buffer.BTScratch)
+	b.SetName("Settings of " + h.Buf.GetName())
+	b.Type.Readonly = true
+	h.HSplitBuf(b)
+}
+
 // SetLocalCmd sets an option local to the buffer
 func (h *BufPane) SetLocalCmd(args []string) {
 	if len(args) < 2 {
@@ -745,10 +1144,13 @@ func (h *BufPane) ShowCmd(args []string) {
 	}
 
 	var option interface{}
+	var origin string
 	if opt, ok := h.Buf.Settings[args[0]]; ok {
 		option = opt
+		origin = h.Buf.SettingOrigin(args[0])
 	} else if opt, ok := config.GlobalSettings[args[0]]; ok {
 		option = opt
+		origin = config.SettingOrigin(args[0], opt, config.SettingsTarget{})
 	}
 
 	if option == nil {
@@ -756,7 +1158,7 @@ func (h *BufPane) ShowCmd(args []string) {
 		return
 	}
 
-	InfoBar.Message(option)
+	InfoBar.Message(fmt.Sprintf("%v (%s)", option, origin))
 }
 
 func parseKeyArg(arg string) string {
@@ -1153,6 +1555,8 @@ func (h *BufPane) HandleCommand(input string) {
 
 	if _, ok := commands[inputCmd]; !ok {
 		InfoBar.Error("Unknown command ", inputCmd)
+	} else if h.popup != nil && popupDisabledCommands[inputCmd] {
+		InfoBar.Error("The ", inputCmd, " command is not available in a popup")
 	} else {
 		WriteLog("> " + input + "\n")
 		commands[inputCmd].action(h, args[1:])
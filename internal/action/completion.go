@@ -0,0 +1,276 @@
+package action
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/micro-editor/tcell/v2"
+	"github.com/zyedidia/micro/v2/internal/buffer"
+	"github.com/zyedidia/micro/v2/internal/config"
+	"github.com/zyedidia/micro/v2/internal/util"
+)
+
+// The maximum size of the completion menu and of its documentation
+const (
+	maxCompletionItems = 10
+	maxCompletionWidth = 60
+	maxDocWidth        = 50
+	maxDocHeight       = 12
+)
+
+// A completionMenu shows the completion items for the text before the
+// cursor of a bufpane in a popup below it, with the documentation of the
+// selected item in a second popup
+type completionMenu struct {
+	h *BufPane
+	// all the items of the sources, and the ones matching the text being
+	// completed
+	all, items []*buffer.CompletionItem
+	sel        int
+	// the line and start of the text being completed, and this text
+	line, start int
+	text        string
+
+	menu, doc *Popup
+}
+
+// colorGroup returns the first of the given colorscheme groups which is
+// defined, or the last one
+func colorGroup(groups ...string) string {
+	for _, g := range groups[:len(groups)-1] {
+		if _, ok := config.Colorscheme[g]; ok {
+			return g
+		}
+	}
+	return groups[len(groups)-1]
+}
+
+// OpenCompletion shows the completion menu with the items of the completion
+// sources for the text before the cursor, or accepts the item if there is
+// only one. It returns false if there is none.
+func (h *BufPane) OpenCompletion() bool {
+	return h.openCompletion(h.Buf.CompletionItems())
+}
+
+// openCompletion shows the completion menu with the given items, which are
+// then filtered as the text being completed changes
+func (h *BufPane) openCompletion(items []*buffer.CompletionItem) bool {
+	h.CloseCompletion()
+	if len(items) == 0 {
+		return false
+	}
+	if len(items) == 1 {
+		h.Buf.AcceptCompletion(items[0])
+		h.Cursor = h.Buf.GetActiveCursor()
+		h.Relocate()
+		return true
+	}
+
+	m := &completionMenu{h: h, all: items, items: items, line: h.Cursor.Y, start: h.Cursor.X}
+	for _, it := range items {
+		m.start = util.Min(m.start, it.Start)
+	}
+	m.text = m.currentText()
+
+	menu, err := NewPopup("", PopupCursor, 1, 1)
+	if err != nil {
+		return false
+	}
+	m.menu = menu
+	menu.passive = true
+	menu.anchorLoc = buffer.Loc{X: m.start, Y: m.line}
+	// the labels are aligned with the text being completed
+	menu.offsetX = -1
+	menu.onClick = func(x, y int) {
+		if line := y - menu.GetView().Y + menu.GetView().StartLine.Line; line >= 0 && line < len(m.items) {
+			m.sel = line
+			m.accept()
+		}
+	}
+	menu.OnClose(func() {
+		if h.completion == m {
+			h.completion = nil
+		}
+		if m.doc != nil {
+			m.doc.Close()
+		}
+	})
+	menu.SetZ(100)
+	h.completion = m
+	m.render()
+	return true
+}
+
+// CloseCompletion closes the completion menu of the bufpane if it is open
+func (h *BufPane) CloseCompletion() {
+	if h.completion != nil {
+		h.completion.menu.Close()
+	}
+}
+
+// currentText returns the text being completed
+func (m *completionMenu) currentText() string {
+	c := m.h.Cursor
+	line := []rune(string(m.h.Buf.LineBytes(c.Y)))
+	if m.start > c.X || c.X > len(line) {
+		return ""
+	}
+	return string(line[m.start:c.X])
+}
+
+// render shows the matching items in the menu, with the selected one
+// highlighted, and the documentation of the selected item
+func (m *completionMenu) render() {
+	width := 0
+	for _, it := range m.items {
+		width = util.Max(width, util.CharacterCountInString(it.Label)+1+util.CharacterCountInString(it.Kind))
+	}
+	width = util.Min(width, maxCompletionWidth)
+
+	lines := make([]string, len(m.items))
+	for i, it := range m.items {
+		label := it.Label
+		kind := it.Kind
+		if it.Detail != "" && util.CharacterCountInString(label+kind+it.Detail)+2 <= width {
+			kind = it.Detail + " " + kind
+		}
+		pad := width - util.CharacterCountInString(label) - util.CharacterCountInString(kind)
+		lines[i] = label + strings.Repeat(" ", util.Max(pad, 1)) + kind
+	}
+
+	p := m.menu
+	p.SetText(strings.Join(lines, "\n"))
+	p.SetSize(width, util.Min(len(m.items), maxCompletionItems))
+
+	b := p.Buf
+	b.ClearOverlays("completion")
+	selected := colorGroup("completion.selected", "statusline", "reverse")
+	match := colorGroup("completion.match", "special")
+	kind := colorGroup("completion.kind", "comment")
+	for i, it := range m.items {
+		end := util.CharacterCountInString(lines[i])
+		if i == m.sel {
+			b.AddOverlay(buffer.NewOverlay("completion", buffer.Loc{X: 0, Y: i}, buffer.Loc{X: end, Y: i}, selected, 2))
+			continue
+		}
+		for _, x := range it.Matches {
+			b.AddOverlay(buffer.NewOverlay("completion", buffer.Loc{X: x, Y: i}, buffer.Loc{X: x + 1, Y: i}, match, 1))
+		}
+		b.AddOverlay(buffer.NewOverlay("completion", buffer.Loc{X: util.CharacterCountInString(it.Label), Y: i}, buffer.Loc{X: end, Y: i}, kind, 1))
+	}
+	p.layout(p.tab)
+	p.Cursor.GotoLoc(buffer.Loc{X: 0, Y: m.sel})
+	p.Relocate()
+
+	m.renderDoc()
+}
+
+// renderDoc shows the documentation of the selected item next to the menu
+func (m *completionMenu) renderDoc() {
+	doc := m.items[m.sel].Doc
+	if doc == "" {
+		if m.doc != nil {
+			d := m.doc
+			m.doc = nil
+			d.Close()
+		}
+		return
+	}
+
+	lines := strings.Split(doc, "\n")
+	width := 0
+	for _, l := range lines {
+		width = util.Max(width, util.StringWidth([]byte(l), util.CharacterCountInString(l), 4))
+	}
+	width = util.Min(width, maxDocWidth)
+	height := 0
+	for _, l := range lines {
+		height += util.Max(1, (util.StringWidth([]byte(l), util.CharacterCountInString(l), 4)+width-1)/width)
+	}
+	height = util.Min(height, maxDocHeight)
+
+	if m.doc == nil {
+		d, err := NewPopup(doc, PopupCursor, width, height)
+		if err != nil {
+			return
+		}
+		d.passive = true
+		d.Buf.SetOptionNative("softwrap", true)
+		d.anchorLoc = m.menu.anchorLoc
+		d.OnClose(func() {
+			// the documentation is closed with the menu
+			if m.doc == d {
+				m.doc = nil
+				m.menu.Close()
+			}
+		})
+		d.SetZ(100)
+		m.doc = d
+	} else {
+		m.doc.SetText(doc)
+		m.doc.SetSize(width, height)
+	}
+	m.doc.offsetX = m.menu.offsetX + m.menu.placed.Width
+}
+
+// update filters the items after an event which changed the text being
+// completed, and closes the menu when the cursor leaves this text or no
+// item matches it
+func (m *completionMenu) update() {
+	c := m.h.Cursor
+	if c.Y != m.line || c.X < m.start || c.HasSelection() || m.h.Buf.NumCursors() > 1 {
+		m.menu.Close()
+		return
+	}
+	text := m.currentText()
+	if text == m.text {
+		return
+	}
+	m.text = text
+
+	items := m.h.Buf.FilterCompletions(m.all)
+	if len(items) == 0 {
+		m.menu.Close()
+		return
+	}
+	// the items are kept in the order of the sources for the same score
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].Score > items[j].Score
+	})
+	m.items = items
+	m.sel = 0
+	m.render()
+}
+
+// accept replaces the text being completed with the selected item
+func (m *completionMenu) accept() {
+	it := m.items[m.sel]
+	m.menu.Close()
+	h := m.h
+	h.Buf.AcceptCompletion(it)
+	h.Cursor = h.Buf.GetActiveCursor()
+	h.Relocate()
+}
+
+// handleKey handles the keys which select and accept the items, and
+// returns whether the key was consumed
+func (m *completionMenu) handleKey(e *tcell.EventKey) bool {
+	n := len(m.items)
+	switch e.Key() {
+	case tcell.KeyDown, tcell.KeyTab, tcell.KeyCtrlN:
+		m.sel = (m.sel + 1) % n
+	case tcell.KeyUp, tcell.KeyBacktab, tcell.KeyCtrlP:
+		m.sel = (m.sel + n - 1) % n
+	case tcell.KeyPgDn:
+		m.sel = util.Min(m.sel+maxCompletionItems, n-1)
+	case tcell.KeyPgUp:
+		m.sel = util.Max(m.sel-maxCompletionItems, 0)
+	case tcell.KeyEnter:
+		m.accept()
+		return true
+	default:
+		return false
+	}
+	m.render()
+	return true
+}
@@ -6,6 +6,30 @@ var termdefaults = map[string]string{
 	"<Ctrl-w><Ctrl-w>": "NextSplit|FirstSplit",
 }
 
+var explorerdefaults = map[string]string{
+	"Up":       "CursorUp",
+	"Down":     "CursorDown",
+	"PageUp":   "CursorPageUp",
+	"PageDown": "CursorPageDown",
+	"Home":     "CursorStart",
+	"End":      "CursorEnd",
+	"Enter":    "Open",
+	"Right":    "Expand",
+	"Left":     "Collapse",
+	"v":        "OpenVSplit",
+	"s":        "OpenHSplit",
+	"t":        "OpenTab",
+	"a":        "NewFile",
+	"r":        "Rename",
+	"m":        "Move",
+	"d":        "Delete",
+	"Delete":   "Delete",
+	"Ctrl-r":   "Refresh",
+	"Ctrl-w":   "NextSplit",
+	"q":        "Quit",
+	"Ctrl-q":   "Quit",
+}
+
 // DefaultBindings returns a map containing micro's default keybindings
 func DefaultBindings(pane string) map[string]string {
 	switch pane {
@@ -15,6 +39,8 @@ func DefaultBindings(pane string) map[string]string {
 		return bufdefaults
 	case "terminal":
 		return termdefaults
+	case "explorer":
+		return explorerdefaults
 	default:
 		return map[string]string{}
 	}
@@ -0,0 +1,787 @@
+package action
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	shellquote "github.com/kballard/go-shellquote"
+	"github.com/micro-editor/tcell/v2"
+	"github.com/zyedidia/micro/v2/internal/buffer"
+	"github.com/zyedidia/micro/v2/internal/config"
+	"github.com/zyedidia/micro/v2/internal/display"
+	"github.com/zyedidia/micro/v2/internal/shell"
+	"github.com/zyedidia/micro/v2/internal/util"
+)
+
+// explorerWidth is the width of the file explorer when it is opened
+const explorerWidth = 30
+
+type ExplorerKeyAction func(*ExplorerPane)
+
+var ExplorerBindings *KeyTree
+
+func init() {
+	ExplorerBindings = NewKeyTree()
+}
+
+func ExplorerKeyActionGeneral(a ExplorerKeyAction) PaneKeyAction {
+	return func(p Pane) bool {
+		a(p.(*ExplorerPane))
+		return true
+	}
+}
+
+// ExplorerMapEvent binds an event to an action of the file explorer
+func ExplorerMapEvent(k Event, action string) {
+	config.Bindings["explorer"][k.Name()] = action
+
+	if f, ok := ExplorerKeyActions[action]; ok {
+		ExplorerBindings.RegisterKeyBinding(k, ExplorerKeyActionGeneral(f))
+	}
+}
+
+// An explorerEntry is a line of the file explorer
+type explorerEntry struct {
+	path  string
+	name  string
+	depth int
+	dir   bool
+}
+
+// The ExplorerPane is a side panel showing the tree of the files of the
+// working directory. The directories can be expanded and collapsed, and
+// the files opened, created, renamed, moved and deleted. The files are
+// decorated with their git status.
+type ExplorerPane struct {
+	*BufPane
+
+	root     string
+	entries  []explorerEntry
+	expanded map[string]bool
+
+	// git is the git status of the files by absolute path, and dirty the
+	// directories which contain changed files
+	git   map[string]string
+	dirty map[string]bool
+
+	// target is the pane in which files are opened, the buffer pane of
+	// the tab which was active last, and revealed the path of its buffer
+	// when it was last revealed
+	target   *BufPane
+	revealed string
+
+	released  bool
+	lastClick time.Time
+
+	unsubscribe func()
+}
+
+// NewExplorerPane creates a file explorer showing the working directory
+func NewExplorerPane(tab *Tab) *ExplorerPane {
+	b := buffer.NewBufferFromString("", "", buffer.BTExplorer)
+	for option, value := range map[string]interface{}{
+		"ruler":        false,
+		"diffgutter":   false,
+		"softwrap":     false,
+		"scrollbar":    false,
+		"minimap":      false,
+		"matchbrace":   false,
+		"hltrailingws": false,
+		"cursorline":   true,
+		// the status line shows the root of the tree
+		"statusformatl": "$(filename)",
+		"statusformatr": "",
+	} {
+		b.SetOptionNative(option, value)
+	}
+	w := display.NewBufWindow(0, 0, 0, 0, b)
+
+	e := new(ExplorerPane)
+	e.BufPane = NewBufPane(b, w, tab)
+	e.expanded = make(map[string]bool)
+	e.released = true
+	e.setRoot()
+
+	e.unsubscribe = config.Subscribe(config.EvBufferSaved, 0, func(config.Event) error {
+		// a file may have been created, and its status has changed
+		e.Refresh()
+		return nil
+	})
+	e.Refresh()
+	return e
+}
+
+// setRoot makes the working directory the root of the tree
+func (e *ExplorerPane) setRoot() {
+	wd, err := os.Getwd()
+	if err != nil {
+		wd = "."
+	}
+	e.root = wd
+	e.expanded[wd] = true
+
+	name := wd
+	if home, err := os.UserHomeDir(); err == nil && strings.HasPrefix(wd, home) {
+		name = "~" + wd[len(home):]
+	}
+	e.Buf.SetName(name)
+}
+
+// ToggleExplorer opens the file explorer on the left of the tab, or
+// closes it if it is open
+func (h *BufPane) ToggleExplorer() bool {
+	t := h.tab
+	for _, p := range t.Panes {
+		if e, ok := p.(*ExplorerPane); ok {
+			e.Quit()
+			return true
+		}
+	}
+
+	e := NewExplorerPane(t)
+	e.SetID(t.DockLeft())
+	e.target = h
+	t.AddPane(e, 0)
+	n := t.GetNode(e.ID())
+	n.ResizeSplit(util.Min(explorerWidth, t.W/3))
+	// keep the width of the explorer when the other panes are split
+	n.SetResize(false)
+	t.Resize()
+	t.SetActive(0)
+	return true
+}
+
+// list adds the entries of the given directory, and of its expanded
+// subdirectories, the directories first
+func (e *ExplorerPane) list(dir string, depth int) {
+	infos, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+
+	var dirs, files []explorerEntry
+	for _, info := range infos {
+		if info.Name() == ".git" {
+			continue
+		}
+		path := filepath.Join(dir, info.Name())
+		isDir := info.IsDir()
+		if info.Type()&fs.ModeSymlink != 0 {
+			if fi, err := os.Stat(path); err == nil {
+				isDir = fi.IsDir()
+			}
+		}
+		en := explorerEntry{path, info.Name(), depth, isDir}
+		if isDir {
+			dirs = append(dirs, en)
+		} else {
+			files = append(files, en)
+		}
+	}
+	byName := func(entries []explorerEntry) {
+		sort.Slice(entries, func(i, j int) bool {
+			return strings.ToLower(entries[i].name) < strings.ToLower(entries[j].name)
+		})
+	}
+	byName(dirs)
+	byName(files)
+
+	for _, d := range dirs {
+		e.entries = append(e.entries, d)
+		if e.expanded[d.path] {
+			e.list(d.path, depth+1)
+		}
+	}
+	e.entries = append(e.entries, files...)
+}
+
+// status returns the git status of an entry: its own, or "!!" if it is
+// in an ignored directory, or " M" for a directory with changed files
+func (e *ExplorerPane) status(en explorerEntry) string {
+	if st, ok := e.git[en.path]; ok {
+		return st
+	}
+	for d := filepath.Dir(en.path); len(d) > len(e.root); d = filepath.Dir(d) {
+		if e.git[d] == "!!" {
+			return "!!"
+		}
+	}
+	if en.dir && e.dirty[en.path] {
+		return " M"
+	}
+	return ""
+}
+
+// gitDecoration returns the letter shown after a file with the given git
+// status, and the group with which it is highlighted
+func gitDecoration(st string) (string, string) {
+	switch {
+	case st == "":
+		return "", ""
+	case st == "!!":
+		return "", colorGroup("explorer.ignored", "comment")
+	case st == "??":
+		return "?", colorGroup("explorer.added", "diff-added")
+	case strings.Contains(st, "U") || st == "AA" || st == "DD":
+		return "!", colorGroup("explorer.conflict", "error")
+	case st[0] == 'A':
+		return "A", colorGroup("explorer.added", "diff-added")
+	}
+	return strings.TrimSpace(st)[:1], colorGroup("explorer.modified", "diff-modified")
+}
+
+// render lists the entries of the tree and shows them, keeping the
+// selected line
+func (e *ExplorerPane) render() {
+	y := e.Cursor.Y
+	e.entries = e.entries[:0]
+	e.list(e.root, 0)
+
+	lines := make([]string, len(e.entries))
+	for i, en := range e.entries {
+		icon := "  "
+		if en.dir && e.expanded[en.path] {
+			icon = "▾ "
+		} else if en.dir {
+			icon = "▸ "
+		}
+		lines[i] = strings.Repeat("  ", en.depth) + icon + en.name
+		if en.dir {
+			lines[i] += "/"
+		}
+	}
+
+	b := e.Buf
+	b.Remove(b.Start(), b.End())
+	b.Insert(b.Start(), strings.Join(lines, "\n"))
+	b.ClearOverlays("explorer")
+	b.ClearVirtualText("explorer")
+	dir := colorGroup("explorer.dir", "identifier")
+	for i, en := range e.entries {
+		start := buffer.Loc{X: en.depth*2 + 2, Y: i}
+		end := buffer.Loc{X: util.CharacterCountInString(lines[i]), Y: i}
+		if en.dir {
+			b.AddOverlay(buffer.NewOverlay("explorer", start, end, dir, 0))
+		}
+		letter, group := gitDecoration(e.status(en))
+		if group != "" {
+			b.AddOverlay(buffer.NewOverlay("explorer", start, end, group, 1))
+		}
+		if letter != "" {
+			b.AddVirtualText(buffer.NewVirtualText("explorer", start, letter, buffer.VTEndOfLine, group))
+		}
+	}
+
+	e.Cursor.GotoLoc(buffer.Loc{X: 0, Y: util.Clamp(y, 0, b.LinesNum()-1)})
+	e.Relocate()
+}
+
+// refreshGit updates the git status of the files in the background
+func (e *ExplorerPane) refreshGit() {
+	root := e.root
+	go func() {
+		status, _ := util.GitStatus(root)
+		shell.Jobs <- shell.JobFunction{Function: func(string, []interface{}) {
+			if e.root != root {
+				return
+			}
+			e.git = status
+			e.dirty = make(map[string]bool)
+			for path, st := range status {
+				if st == "!!" {
+					continue
+				}
+				for d := filepath.Dir(path); len(d) >= len(root) && d != filepath.Dir(d); d = filepath.Dir(d) {
+					e.dirty[d] = true
+				}
+			}
+			e.render()
+		}}
+	}()
+}
+
+// selected returns the selected entry, or nil if the tree is empty
+func (e *ExplorerPane) selected() *explorerEntry {
+	if y := e.Cursor.Y; y < len(e.entries) {
+		return &e.entries[y]
+	}
+	return nil
+}
+
+// selectPath selects the entry with the given path, if it is shown
+func (e *ExplorerPane) selectPath(path string) {
+	for i, en := range e.entries {
+		if en.path == path {
+			e.GotoLoc(buffer.Loc{X: 0, Y: i})
+			return
+		}
+	}
+}
+
+// Reveal expands the directories containing the file with the given path,
+// if it is in the tree, and selects it
+func (e *ExplorerPane) Reveal(path string) {
+	rel, err := filepath.Rel(e.root, path)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return
+	}
+	for d := filepath.Dir(path); len(d) > len(e.root); d = filepath.Dir(d) {
+		e.expanded[d] = true
+	}
+	e.render()
+	e.selectPath(path)
+}
+
+// targetPane returns the pane in which files are opened, or nil if there
+// is no buffer pane in the tab
+func (e *ExplorerPane) targetPane() *BufPane {
+	var first *BufPane
+	for _, p := range e.tab.Panes {
+		if bp, ok := p.(*BufPane); ok {
+			if bp == e.target {
+				return bp
+			}
+			if first == nil {
+				first = bp
+			}
+		}
+	}
+	e.target = first
+	return first
+}
+
+// Display reveals the file of the target pane when it changes, and shows
+// the tree
+func (e *ExplorerPane) Display() {
+	if bp := e.tab.CurPane(); bp != nil {
+		e.target = bp
+	}
+	if bp := e.targetPane(); bp != nil && bp.Buf.AbsPath != e.revealed {
+		e.revealed = bp.Buf.AbsPath
+		if bp.Buf.Path != "" {
+			e.Reveal(e.revealed)
+		}
+	}
+	e.BufPane.Display()
+}
+
+// SetActive marks the explorer as active. Unlike a buffer pane it does
+// not run the callbacks of the plugins.
+func (e *ExplorerPane) SetActive(b bool) {
+	e.BWindow.SetActive(b)
+}
+
+// HandleEvent handles the keys bound in the explorer section of the
+// bindings, and the mouse: the wheel scrolls, a click selects an entry,
+// and opens it if it is a directory or if it is a double click
+func (e *ExplorerPane) HandleEvent(event tcell.Event) {
+	switch ev := event.(type) {
+	case *tcell.EventKey:
+		action, more := ExplorerBindings.NextEvent(keyEvent(ev), nil)
+		if !more {
+			ExplorerBindings.ResetEvents()
+			if action != nil {
+				action(e)
+			}
+		}
+	case *tcell.EventMouse:
+		mx, my := ev.Position()
+		switch ev.Buttons() {
+		case tcell.WheelUp:
+			e.ScrollUpAction()
+		case tcell.WheelDown:
+			e.ScrollDownAction()
+		case tcell.Button1:
+			if !e.released {
+				return
+			}
+			e.released = false
+			v := e.GetView()
+			if my >= v.Y+e.BufView().Height {
+				return
+			}
+			loc := e.LocFromVisual(buffer.Loc{X: mx, Y: my})
+			if loc.Y >= len(e.entries) {
+				return
+			}
+			double := loc.Y == e.Cursor.Y && time.Since(e.lastClick)/time.Millisecond < config.DoubleClickThreshold
+			e.lastClick = time.Now()
+			e.Cursor.GotoLoc(buffer.Loc{X: 0, Y: loc.Y})
+			if double || e.entries[loc.Y].dir {
+				e.lastClick = time.Time{}
+				e.Open()
+			}
+		case tcell.ButtonNone:
+			e.released = true
+		}
+	}
+}
+
+// Close stops following the saved files and closes the buffer of the
+// explorer
+func (e *ExplorerPane) Close() {
+	if e.unsubscribe != nil {
+		e.unsubscribe()
+		e.unsubscribe = nil
+	}
+	e.BufPane.Close()
+}
+
+// Quit closes the explorer
+func (e *ExplorerPane) Quit() {
+	if e.unsubscribe != nil {
+		e.unsubscribe()
+		e.unsubscribe = nil
+	}
+	e.ForceQuit()
+}
+
+// CursorUp selects the previous entry
+func (e *ExplorerPane) CursorUp() {
+	e.BufPane.CursorUp()
+}
+
+// CursorDown selects the next entry
+func (e *ExplorerPane) CursorDown() {
+	e.BufPane.CursorDown()
+}
+
+// CursorPageUp selects the entry a page up
+func (e *ExplorerPane) CursorPageUp() {
+	e.BufPane.CursorPageUp()
+}
+
+// CursorPageDown selects the entry a page down
+func (e *ExplorerPane) CursorPageDown() {
+	e.BufPane.CursorPageDown()
+}
+
+// CursorStart selects the first entry
+func (e *ExplorerPane) CursorStart() {
+	e.BufPane.CursorStart()
+}
+
+// CursorEnd selects the last entry
+func (e *ExplorerPane) CursorEnd() {
+	e.BufPane.CursorEnd()
+	e.Cursor.GotoLoc(buffer.Loc{X: 0, Y: e.Cursor.Y})
+}
+
+// Expand expands the selected directory, or selects its first entry if
+// it is expanded
+func (e *ExplorerPane) Expand() {
+	en := e.selected()
+	if en == nil || !en.dir {
+		return
+	}
+	if e.expanded[en.path] {
+		e.BufPane.CursorDown()
+		return
+	}
+	e.expanded[en.path] = true
+	e.render()
+}
+
+// Collapse collapses the selected directory, or selects the directory
+// containing the selected entry
+func (e *ExplorerPane) Collapse() {
+	en := e.selected()
+	if en == nil {
+		return
+	}
+	if en.dir && e.expanded[en.path] {
+		e.expanded[en.path] = false
+		e.render()
+		return
+	}
+	e.selectPath(filepath.Dir(en.path))
+}
+
+// Refresh reads the working directory and the git status again
+func (e *ExplorerPane) Refresh() {
+	if wd, err := os.Getwd(); err == nil && wd != e.root {
+		e.setRoot()
+	}
+	e.render()
+	e.refreshGit()
+}
+
+// Open expands or collapses the selected directory, or shows the selected
+// file: in the pane which shows it if any, in the target pane otherwise
+func (e *ExplorerPane) Open() {
+	en := e.selected()
+	if en == nil {
+		return
+	}
+	if en.dir {
+		e.expanded[en.path] = !e.expanded[en.path]
+		e.render()
+		return
+	}
+	if showFile(en.path) {
+		return
+	}
+	h := e.targetPane()
+	if h == nil {
+		e.openSplit(en.path, false)
+		return
+	}
+	e.tab.SetActive(e.tab.GetPane(h.ID()))
+	h.OpenCmd([]string{shellquote.Join(openName(en.path))})
+}
+
+// OpenVSplit opens the selected file in a vertical split of the target pane
+func (e *ExplorerPane) OpenVSplit() {
+	if en := e.selected(); en != nil && !en.dir {
+		e.openSplit(en.path, true)
+	}
+}
+
+// OpenHSplit opens the selected file in a horizontal split of the target
+// pane
+func (e *ExplorerPane) OpenHSplit() {
+	if en := e.selected(); en != nil && !en.dir {
+		e.openSplit(en.path, false)
+	}
+}
+
+// openSplit opens a file in a split of the target pane, or on the right of
+// the explorer if there is none
+func (e *ExplorerPane) openSplit(path string, vertical bool) {
+	b, err := buffer.NewBufferFromFile(openName(path), buffer.BTDefault)
+	if err != nil {
+		InfoBar.Error(err)
+		return
+	}
+	h := e.targetPane()
+	switch {
+	case h == nil:
+		width := e.GetView().Width
+		e.VSplitIndex(b, true)
+		e.tab.GetNode(e.ID()).ResizeSplit(width)
+		e.tab.Resize()
+	case vertical:
+		h.VSplitBuf(b)
+	default:
+		h.HSplitBuf(b)
+	}
+}
+
+// OpenTab opens the selected file in a new tab
+func (e *ExplorerPane) OpenTab() {
+	en := e.selected()
+	if en == nil || en.dir {
+		return
+	}
+	b, err := buffer.NewBufferFromFile(openName(en.path), buffer.BTDefault)
+	if err != nil {
+		InfoBar.Error(err)
+		return
+	}
+	e.addTab(b)
+}
+
+// NextSplit focuses the next split
+func (e *ExplorerPane) NextSplit() {
+	e.tab.SetActive((e.tab.active + 1) % len(e.tab.Panes))
+}
+
+// relative returns the path relative to the root of the tree
+func (e *ExplorerPane) relative(path string) string {
+	if rel, err := filepath.Rel(e.root, path); err == nil {
+		return filepath.ToSlash(rel)
+	}
+	return path
+}
+
+// absolute returns the absolute path of a path entered by the user,
+// relative to the root of the tree
+func (e *ExplorerPane) absolute(path string) string {
+	if p, err := util.ReplaceHome(path); err == nil {
+		path = p
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(e.root, path)
+	}
+	return filepath.Clean(path)
+}
+
+// confirm asks the user to confirm an operation on the files and runs it,
+// then selects the given path
+func (e *ExplorerPane) confirm(question, sel string, op func() error) {
+	InfoBar.YNPrompt(question+" (y,n,esc)", func(yes, canceled bool) {
+		if canceled || !yes {
+			return
+		}
+		if err := op(); err != nil {
+			InfoBar.Error(err)
+		}
+		e.Reveal(sel)
+		e.refreshGit()
+	})
+}
+
+// exists returns an error if a file exists at the given path
+func (e *ExplorerPane) exists(path string) error {
+	if _, err := os.Lstat(path); !errors.Is(err, fs.ErrNotExist) {
+		return errors.New(e.relative(path) + " already exists")
+	}
+	return nil
+}
+
+// renameBuffers updates the paths of the open buffers of the files which
+// were moved from old to new
+func renameBuffers(old, new string) {
+	for _, b := range buffer.OpenBuffers {
+		if b.Path == "" {
+			continue
+		}
+		if b.AbsPath == old || strings.HasPrefix(b.AbsPath, old+string(filepath.Separator)) {
+			b.AbsPath = new + b.AbsPath[len(old):]
+			b.Path = openName(b.AbsPath)
+			b.ReloadSettings(true)
+		}
+	}
+}
+
+// NewFile asks for the path of a new file, relative to the selected
+// directory, and creates it. A path ending with a slash creates a
+// directory.
+func (e *ExplorerPane) NewFile() {
+	dir := e.root
+	if en := e.selected(); en != nil && en.dir {
+		dir = en.path
+	} else if en != nil {
+		dir = filepath.Dir(en.path)
+	}
+	prefill := ""
+	if dir != e.root {
+		prefill = e.relative(dir) + "/"
+	}
+
+	InfoBar.Prompt("New file (end with / for a directory): ", prefill, "Explorer", nil, func(resp string, canceled bool) {
+		if canceled || strings.TrimSpace(resp) == "" {
+			return
+		}
+		path := e.absolute(resp)
+		if err := e.exists(path); err != nil {
+			InfoBar.Error(err)
+			return
+		}
+		e.confirm("Create "+e.relative(path)+"?", path, func() error {
+			if strings.HasSuffix(resp, "/") {
+				return os.MkdirAll(path, os.ModePerm)
+			}
+			if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+				return err
+			}
+			f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0666)
+			if err != nil {
+				return err
+			}
+			return f.Close()
+		})
+	})
+}
+
+// move asks for confirmation and moves the file or directory old to new
+func (e *ExplorerPane) move(verb, old, new string) {
+	if err := e.exists(new); err != nil {
+		InfoBar.Error(err)
+		return
+	}
+	e.confirm(verb+" "+e.relative(old)+" to "+e.relative(new)+"?", new, func() error {
+		if err := os.MkdirAll(filepath.Dir(new), os.ModePerm); err != nil {
+			return err
+		}
+		if err := os.Rename(old, new); err != nil {
+			return err
+		}
+		e.expanded[new] = e.expanded[old]
+		renameBuffers(old, new)
+		return nil
+	})
+}
+
+// Rename asks for a new name for the selected file or directory
+func (e *ExplorerPane) Rename() {
+	en := e.selected()
+	if en == nil {
+		return
+	}
+	old := en.path
+	InfoBar.Prompt("Rename to: ", en.name, "Explorer", nil, func(resp string, canceled bool) {
+		if canceled || resp == "" || resp == filepath.Base(old) {
+			return
+		}
+		e.move("Rename", old, filepath.Join(filepath.Dir(old), resp))
+	})
+}
+
+// Move asks for a new path for the selected file or directory, relative
+// to the root of the tree. A file moved to a directory is moved into it.
+func (e *ExplorerPane) Move() {
+	en := e.selected()
+	if en == nil {
+		return
+	}
+	old := en.path
+	InfoBar.Prompt("Move to: ", e.relative(old), "Explorer", nil, func(resp string, canceled bool) {
+		if canceled || resp == "" {
+			return
+		}
+		new := e.absolute(resp)
+		if fi, err := os.Stat(new); err == nil && fi.IsDir() {
+			new = filepath.Join(new, filepath.Base(old))
+		}
+		if new != old {
+			e.move("Move", old, new)
+		}
+	})
+}
+
+// Delete deletes the selected file, or the selected directory and its
+// content
+func (e *ExplorerPane) Delete() {
+	en := e.selected()
+	if en == nil {
+		return
+	}
+	path := en.path
+	question := "Delete " + e.relative(path) + "?"
+	if en.dir {
+		question = "Delete " + e.relative(path) + " and everything in it?"
+	}
+	e.confirm(question, filepath.Dir(path), func() error {
+		return os.RemoveAll(path)
+	})
+}
+
+// ExplorerKeyActions contains the list of all possible key actions the
+// file explorer could execute
+var ExplorerKeyActions = map[string]ExplorerKeyAction{
+	"CursorUp":       (*ExplorerPane).CursorUp,
+	"CursorDown":     (*ExplorerPane).CursorDown,
+	"CursorPageUp":   (*ExplorerPane).CursorPageUp,
+	"CursorPageDown": (*ExplorerPane).CursorPageDown,
+	"CursorStart":    (*ExplorerPane).CursorStart,
+	"CursorEnd":      (*ExplorerPane).CursorEnd,
+	"Open":           (*ExplorerPane).Open,
+	"Expand":         (*ExplorerPane).Expand,
+	"Collapse":       (*ExplorerPane).Collapse,
+	"OpenVSplit":     (*ExplorerPane).OpenVSplit,
+	"OpenHSplit":     (*ExplorerPane).OpenHSplit,
+	"OpenTab":        (*ExplorerPane).OpenTab,
+	"NewFile":        (*ExplorerPane).NewFile,
+	"Rename":         (*ExplorerPane).Rename,
+	"Move":           (*ExplorerPane).Move,
+	"Delete":         (*ExplorerPane).Delete,
+	"Refresh":        (*ExplorerPane).Refresh,
+	"NextSplit":      (*ExplorerPane).NextSplit,
+	"Quit":           (*ExplorerPane).Quit,
+}
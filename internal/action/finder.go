@@ -0,0 +1,455 @@
+package action
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/micro-editor/tcell/v2"
+	"github.com/zyedidia/micro/v2/internal/buffer"
+	"github.com/zyedidia/micro/v2/internal/config"
+	"github.com/zyedidia/micro/v2/internal/shell"
+	"github.com/zyedidia/micro/v2/internal/util"
+)
+
+// The modes of the finder
+const (
+	FinderFiles    = "files"
+	FinderBuffers  = "buffers"
+	FinderRecent   = "recent"
+	FinderCommands = "commands"
+	FinderSymbols  = "symbols"
+	FinderPalette  = "palette"
+)
+
+// FinderModes are the modes of the finder, in the order of the finder
+// command's completion
+var FinderModes = []string{FinderFiles, FinderBuffers, FinderRecent, FinderCommands, FinderSymbols, FinderPalette}
+
+const (
+	// maxFinderFiles is the maximum number of files indexed by the finder
+	maxFinderFiles = 100000
+	// finderBatch is the number of files sent at once by the indexer
+	finderBatch = 1000
+	// maxFinderResults is the maximum number of results shown
+	maxFinderResults = 500
+	// maxFinderLabel is the maximum width to which the labels are padded
+	maxFinderLabel = 40
+)
+
+// A FinderItem is an entry of the finder, matched against the text typed
+// in its prompt
+type FinderItem struct {
+	Label  string
+	Detail string
+	// Accept is called when the item is chosen
+	Accept func()
+
+	score   int
+	matches []int
+}
+
+// A Finder is a prompt which filters a list of items fuzzily as the user
+// types, and shows the best matches in a popup above the infobar
+type Finder struct {
+	title   string
+	items   []*FinderItem
+	matches []*FinderItem
+	sel     int
+	query   string
+
+	popup *Popup
+	// indexing is true while items are being added in the background, and
+	// closing done stops the indexer
+	indexing bool
+	done     chan struct{}
+}
+
+// finder is the open finder, if any
+var finder *Finder
+
+// NewFinder opens a finder with the given title and items. More items can
+// be added with Add while it is open.
+func NewFinder(title string, items []*FinderItem) *Finder {
+	if finder != nil {
+		InfoBar.DonePrompt(true)
+	}
+
+	f := &Finder{title: title, items: items, done: make(chan struct{})}
+	t := MainTab()
+	p, err := NewPopup("", PopupCenter, 1, 1)
+	if err != nil {
+		return nil
+	}
+	f.popup = p
+	p.passive = true
+	p.SetZ(100)
+	p.OnClose(func() {
+		if finder == f && InfoBar.HasPrompt {
+			InfoBar.DonePrompt(true)
+		}
+	})
+	w := util.Clamp(t.W-8, 1, 100)
+	h := util.Clamp(t.H-6, 1, 20)
+	p.SetSize(w, h)
+
+	finder = f
+	InfoBar.Prompt(title+": ", "", "Finder", func(resp string) {
+		f.filter(resp)
+	}, func(resp string, canceled bool) {
+		delete(InfoBar.History, "Finder")
+		InfoBar.eventHandler = nil
+		finder = nil
+		close(f.done)
+		p.Close()
+		if !canceled && f.sel < len(f.matches) && f.matches[f.sel].Accept != nil {
+			f.matches[f.sel].Accept()
+		}
+	})
+	InfoBar.eventHandler = f.handleEvent
+	f.filter("")
+	return f
+}
+
+// Add adds items to the finder, and refilters them
+func (f *Finder) Add(items []*FinderItem) {
+	f.items = append(f.items, items...)
+	f.filter(f.query)
+}
+
+// filter computes the items matching the query, best first, and shows them
+func (f *Finder) filter(query string) {
+	f.query = query
+	f.matches = f.matches[:0]
+	for _, it := range f.items {
+		if score, matches, ok := util.FuzzyMatch(query, it.Label); ok {
+			it.score, it.matches = score, matches
+			f.matches = append(f.matches, it)
+		}
+	}
+	if query != "" {
+		sort.SliceStable(f.matches, func(i, j int) bool {
+			return f.matches[i].score > f.matches[j].score
+		})
+	}
+	if len(f.matches) > maxFinderResults {
+		f.matches = f.matches[:maxFinderResults]
+	}
+	f.sel = 0
+	f.render()
+}
+
+// render shows the matches in the popup, with the selected one highlighted
+func (f *Finder) render() {
+	p := f.popup
+	// the details are aligned after the longest label, within reason
+	width := 0
+	for _, it := range f.matches {
+		width = util.Max(width, util.CharacterCountInString(it.Label))
+	}
+	width = util.Min(width, maxFinderLabel)
+	lines := make([]string, len(f.matches))
+	labels := make([]int, len(f.matches))
+	for i, it := range f.matches {
+		lines[i] = it.Label
+		labels[i] = util.CharacterCountInString(it.Label)
+		if it.Detail != "" {
+			lines[i] += strings.Repeat(" ", util.Max(width-labels[i], 0)) + "  " + it.Detail
+			labels[i] = util.Max(labels[i], width)
+		}
+	}
+	p.SetText(strings.Join(lines, "\n"))
+
+	title := f.title + " " + strconv.Itoa(len(f.matches)) + "/" + strconv.Itoa(len(f.items))
+	if f.indexing {
+		title += "…"
+	}
+	p.SetTitle(title)
+
+	b := p.Buf
+	b.ClearOverlays("finder")
+	selected := colorGroup("completion.selected", "statusline", "reverse")
+	match := colorGroup("completion.match", "special")
+	detail := colorGroup("completion.kind", "comment")
+	for i, it := range f.matches {
+		end := util.CharacterCountInString(lines[i])
+		if i == f.sel {
+			b.AddOverlay(buffer.NewOverlay("finder", buffer.Loc{X: 0, Y: i}, buffer.Loc{X: end, Y: i}, selected, 2))
+			continue
+		}
+		for _, x := range it.matches {
+			b.AddOverlay(buffer.NewOverlay("finder", buffer.Loc{X: x, Y: i}, buffer.Loc{X: x + 1, Y: i}, match, 1))
+		}
+		if it.Detail != "" {
+			b.AddOverlay(buffer.NewOverlay("finder", buffer.Loc{X: labels[i], Y: i}, buffer.Loc{X: end, Y: i}, detail, 1))
+		}
+	}
+	p.Cursor.GotoLoc(buffer.Loc{X: 0, Y: f.sel})
+	p.Relocate()
+}
+
+// handleEvent handles the events of the infobar which select and choose
+// the items, and returns whether the event was consumed
+func (f *Finder) handleEvent(event tcell.Event) bool {
+	n := len(f.matches)
+	switch e := event.(type) {
+	case *tcell.EventKey:
+		if n == 0 {
+			return false
+		}
+		page := f.popup.GetView().Height
+		switch e.Key() {
+		case tcell.KeyDown, tcell.KeyTab, tcell.KeyCtrlN:
+			f.sel = (f.sel + 1) % n
+		case tcell.KeyUp, tcell.KeyBacktab, tcell.KeyCtrlP:
+			f.sel = (f.sel + n - 1) % n
+		case tcell.KeyPgDn:
+			f.sel = util.Min(f.sel+page, n-1)
+		case tcell.KeyPgUp:
+			f.sel = util.Max(f.sel-page, 0)
+		default:
+			return false
+		}
+		f.render()
+		return true
+	case *tcell.EventMouse:
+		mx, my := e.Position()
+		v := f.popup.GetView()
+		if mx < v.X || mx >= v.X+v.Width || my < v.Y || my >= v.Y+v.Height {
+			return false
+		}
+		switch e.Buttons() {
+		case tcell.WheelUp:
+			f.sel = util.Max(f.sel-1, 0)
+		case tcell.WheelDown:
+			f.sel = util.Min(f.sel+1, n-1)
+		case tcell.Button1:
+			if line := my - v.Y + v.StartLine.Line; line < n {
+				f.sel = line
+				InfoBar.DonePrompt(false)
+			}
+			return true
+		default:
+			return true
+		}
+		f.render()
+		return true
+	}
+	return false
+}
+
+// showFile focuses the pane which shows the file with the given path, and
+// returns whether there is one
+func showFile(path string) bool {
+	for i, t := range Tabs.List {
+		for j, p := range t.Panes {
+			if bp, ok := p.(*BufPane); ok && bp.Buf.AbsPath == path && bp.Buf.Path != "" {
+				Tabs.SetActive(i)
+				t.SetActive(j)
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// openName returns the name with which the file with the given absolute
+// path is opened: the files of the working directory are named as with
+// the open command
+func openName(path string) string {
+	if wd, err := os.Getwd(); err == nil {
+		if rel, err := filepath.Rel(wd, path); err == nil && !strings.HasPrefix(rel, "..") {
+			return rel
+		}
+	}
+	return path
+}
+
+// openFinderFile shows the file with the given path in the pane which
+// shows it, or opens it according to the multiopen option
+func (h *BufPane) openFinderFile(path string) {
+	if showFile(path) {
+		return
+	}
+
+	b, err := buffer.NewBufferFromFile(openName(path), buffer.BTDefault)
+	if err != nil {
+		InfoBar.Error(err)
+		return
+	}
+	switch config.GetGlobalOption("multiopen").(string) {
+	case "tab":
+		h.addTab(b)
+	case "vsplit":
+		h.VSplitBuf(b)
+	default:
+		h.HSplitBuf(b)
+	}
+}
+
+// relativePath returns the path relative to the working directory if it is
+// below it, with the home directory abbreviated otherwise
+func relativePath(path string) string {
+	if wd, err := os.Getwd(); err == nil {
+		if rel, err := filepath.Rel(wd, path); err == nil && !strings.HasPrefix(rel, "..") {
+			return rel
+		}
+	}
+	if home, err := os.UserHomeDir(); err == nil && strings.HasPrefix(path, home+string(os.PathSeparator)) {
+		return "~" + path[len(home):]
+	}
+	return path
+}
+
+// FindFile opens a finder with the files of the working directory, which
+// are indexed in the background, skipping the files ignored by git
+func (h *BufPane) FindFile() bool {
+	wd, err := os.Getwd()
+	if err != nil {
+		InfoBar.Error(err)
+		return false
+	}
+	f := NewFinder(FinderFiles, nil)
+	if f == nil {
+		return false
+	}
+	f.indexing = true
+	go func() {
+		var batch []*FinderItem
+		count := 0
+		send := func(items []*FinderItem, last bool) bool {
+			select {
+			case shell.Jobs <- shell.JobFunction{Function: func(string, []interface{}) {
+				if finder == f {
+					f.indexing = !last
+					f.Add(items)
+				}
+			}}:
+				return true
+			case <-f.done:
+				return false
+			}
+		}
+		util.WalkProject(wd, f.done, func(rel string) bool {
+			path := filepath.Join(wd, filepath.FromSlash(rel))
+			batch = append(batch, &FinderItem{Label: rel, Accept: func() { h.openFinderFile(path) }})
+			count++
+			if len(batch) == finderBatch {
+				if !send(batch, false) {
+					return false
+				}
+				batch = nil
+			}
+			return count < maxFinderFiles
+		})
+		send(batch, true)
+	}()
+	return true
+}
+
+// FindBuffer opens a finder with the open buffers
+func (h *BufPane) FindBuffer() bool {
+	var items []*FinderItem
+	seen := make(map[*buffer.SharedBuffer]bool)
+	for _, b := range buffer.OpenBuffers {
+		if b.Type == buffer.BTInfo || b.Type == buffer.BTLog || b.Type == buffer.BTScratch || b.Type == buffer.BTExplorer || seen[b.SharedBuffer] {
+			continue
+		}
+		seen[b.SharedBuffer] = true
+		b := b
+		detail := ""
+		if b.Modified() {
+			detail = "modified"
+		}
+		items = append(items, &FinderItem{Label: b.GetName(), Detail: detail, Accept: func() {
+			for i, t := range Tabs.List {
+				for j, p := range t.Panes {
+					if bp, ok := p.(*BufPane); ok && bp.Buf.SharedBuffer == b.SharedBuffer {
+						Tabs.SetActive(i)
+						t.SetActive(j)
+						return
+					}
+				}
+			}
+			h.OpenBuffer(b)
+		}})
+	}
+	return NewFinder(FinderBuffers, items) != nil
+}
+
+// FindRecent opens a finder with the recently opened files
+func (h *BufPane) FindRecent() bool {
+	var items []*FinderItem
+	for _, path := range InfoBar.Recent {
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		path := path
+		items = append(items, &FinderItem{Label: relativePath(path), Accept: func() { h.openFinderFile(path) }})
+	}
+	return NewFinder(FinderRecent, items) != nil
+}
+
+// FindCommand opens a finder with the commands, the chosen one being
+// written in the command prompt
+func (h *BufPane) FindCommand() bool {
+	var names []string
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	items := make([]*FinderItem, len(names))
+	for i, name := range names {
+		name := name
+		items[i] = &FinderItem{
+			Label:  name,
+			Detail: commands[name].desc,
+			Accept: func() { CommandEditAction(name + " ")(h) },
+		}
+	}
+	return NewFinder(FinderCommands, items) != nil
+}
+
+// FindSymbol opens a finder with the definitions of the current buffer
+func (h *BufPane) FindSymbol() bool {
+	symbols := h.Buf.Symbols()
+	items := make([]*FinderItem, len(symbols))
+	for i, s := range symbols {
+		loc := s.Loc
+		items[i] = &FinderItem{
+			Label:  s.Name,
+			Detail: s.Kind + " " + strconv.Itoa(loc.Y+1),
+			Accept: func() {
+				h.GotoLoc(loc)
+				h.Center()
+			},
+		}
+	}
+	return NewFinder(FinderSymbols, items) != nil
+}
+
+// FinderCmd opens the finder in the given mode, files by default
+func (h *BufPane) FinderCmd(args []string) {
+	mode := FinderFiles
+	if len(args) > 0 {
+		mode = args[0]
+	}
+	switch mode {
+	case FinderFiles:
+		h.FindFile()
+	case FinderBuffers:
+		h.FindBuffer()
+	case FinderRecent:
+		h.FindRecent()
+	case FinderCommands:
+		h.FindCommand()
+	case FinderSymbols:
+		h.FindSymbol()
+	case FinderPalette:
+		h.CommandPalette()
+	default:
+		InfoBar.Error("Invalid finder mode ", mode)
+	}
+}
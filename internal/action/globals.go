@@ -1,6 +1,9 @@
 package action
 
-import "github.com/zyedidia/micro/v2/internal/buffer"
+import (
+	"github.com/zyedidia/micro/v2/internal/buffer"
+	"github.com/zyedidia/micro/v2/internal/config"
+)
 
 // InfoBar is the global info bar.
 var InfoBar *InfoPane
@@ -12,6 +15,19 @@ var LogBufPane *BufPane
 func InitGlobals() {
 	InfoBar = NewInfoBar()
 	buffer.LogBuf = buffer.NewBufferFromString("", "Log", buffer.BTLog)
+
+	config.OnPluginDisabled = func(p *config.Plugin, err error) {
+		WriteLog("Plugin " + p.Name + " has been disabled: " + err.Error() + "\n")
+		InfoBar.Error("Plugin ", p.Name, " has been disabled after failing too many times (see > log)")
+	}
+
+	// the opened files are remembered for the recent files of the finder
+	config.Subscribe(config.EvBufferOpen, 0, func(e config.Event) error {
+		if b, ok := e.Data["buf"].(*buffer.Buffer); ok && b.Type == buffer.BTDefault && b.Path != "" {
+			InfoBar.AddRecent(b.AbsPath)
+		}
+		return nil
+	})
 }
 
 // GetInfoBar returns the infobar pane
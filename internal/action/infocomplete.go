@@ -58,6 +58,44 @@ func HelpComplete(b *buffer.Buffer) ([]string, []string) {
 	return completions, suggestions
 }
 
+// FinderComplete autocompletes the modes of the finder command
+func FinderComplete(b *buffer.Buffer) ([]string, []string) {
+	c := b.GetActiveCursor()
+	input, argstart := b.GetArg()
+
+	var suggestions []string
+	for _, mode := range FinderModes {
+		if strings.HasPrefix(mode, input) {
+			suggestions = append(suggestions, mode)
+		}
+	}
+
+	completions := make([]string, len(suggestions))
+	for i := range suggestions {
+		completions[i] = util.SliceEndStr(suggestions[i], c.X-argstart)
+	}
+	return completions, suggestions
+}
+
+// ColorschemeComplete autocompletes colorschemes for the colorscheme
+// command and the colorscheme preview prompt
+func ColorschemeComplete(b *buffer.Buffer) ([]string, []string) {
+	c := b.GetActiveCursor()
+	input, argstart := b.GetArg()
+
+	_, suggestions := colorschemeComplete(input)
+	if strings.HasPrefix("preview", input) && bytes.HasPrefix(b.LineBytes(0), []byte("colorscheme ")) {
+		suggestions = append(suggestions, "preview")
+	}
+
+	sort.Strings(suggestions)
+	completions := make([]string, len(suggestions))
+	for i := range suggestions {
+		completions[i] = util.SliceEndStr(suggestions[i], c.X-argstart)
+	}
+	return completions, suggestions
+}
+
 // colorschemeComplete tab-completes names of colorschemes.
 // This is just a heper value for OptionValueComplete
 func colorschemeComplete(input string) (string, []string) {
@@ -167,46 +205,18 @@ func OptionValueComplete(b *buffer.Buffer) ([]string, []string) {
 	l = util.SliceStart(l, c.X)
 	input, argstart := b.GetArg()
 
-	completeValue := false
+	var option *config.Option
 	args := bytes.Split(l, []byte{' '})
 	if len(args) >= 2 {
-		// localSettings := config.DefaultLocalSettings()
-		for option := range config.GlobalSettings {
-			if option == string(args[len(args)-2]) {
-				completeValue = true
-				break
-			}
-		}
-		// for option := range localSettings {
-		// 	if option == string(args[len(args)-2]) {
-		// 		completeValue = true
-		// 		break
-		// 	}
-		// }
+		option = config.LookupOption(strings.TrimSpace(string(args[len(args)-2])))
 	}
-	if !completeValue {
+	if option == nil {
 		return OptionComplete(b)
 	}
 
-	inputOpt := string(args[len(args)-2])
-
-	inputOpt = strings.TrimSpace(inputOpt)
 	var suggestions []string
-	// localSettings := config.DefaultLocalSettings()
-	var optionVal interface{}
-	for k, option := range config.GlobalSettings {
-		if k == inputOpt {
-			optionVal = option
-		}
-	}
-	// for k, option := range localSettings {
-	// 	if k == inputOpt {
-	// 		optionVal = option
-	// 	}
-	// }
-
-	switch optionVal.(type) {
-	case bool:
+	switch option.Type {
+	case config.OptionBool:
 		if strings.HasPrefix("on", input) {
 			suggestions = append(suggestions, "on")
 		} else if strings.HasPrefix("true", input) {
@@ -217,21 +227,14 @@ func OptionValueComplete(b *buffer.Buffer) ([]string, []string) {
 		} else if strings.HasPrefix("false", input) {
 			suggestions = append(suggestions, "false")
 		}
-	case string:
-		switch inputOpt {
+	case config.OptionString:
+		switch option.Name {
 		case "colorscheme":
 			_, suggestions = colorschemeComplete(input)
 		case "filetype":
 			_, suggestions = filetypeComplete(input)
-		case "sucmd":
-			if strings.HasPrefix("sudo", input) {
-				suggestions = append(suggestions, "sudo")
-			}
-			if strings.HasPrefix("doas", input) {
-				suggestions = append(suggestions, "doas")
-			}
 		default:
-			if choices, ok := config.OptionChoices[inputOpt]; ok {
+			for _, choices := range [][]string{option.Choices, option.Suggestions} {
 				for _, choice := range choices {
 					if strings.HasPrefix(choice, input) {
 						suggestions = append(suggestions, choice)
@@ -59,6 +59,10 @@ func InfoKeyActionGeneral(a InfoKeyAction) PaneKeyAction {
 type InfoPane struct {
 	*BufPane
 	*info.InfoBuf
+
+	// eventHandler, if set, handles the events of the prompt before the
+	// bindings, and returns whether it consumed the event
+	eventHandler func(tcell.Event) bool
 }
 
 func NewInfoPane(ib *info.InfoBuf, w display.BWindow, tab *Tab) *InfoPane {
@@ -82,6 +86,10 @@ func (h *InfoPane) Close() {
 }
 
 func (h *InfoPane) HandleEvent(event tcell.Event) {
+	if h.eventHandler != nil && h.HasPrompt && h.eventHandler(event) {
+		return
+	}
+
 	switch e := event.(type) {
 	case *tcell.EventResize:
 		// TODO
@@ -211,6 +219,8 @@ func (h *InfoPane) CommandComplete() {
 				b.Autocomplete(action.completer)
 			}
 		}
+	} else if h.PromptType == "Colorscheme" {
+		b.Autocomplete(ColorschemeComplete)
 	} else {
 		// by default use filename autocompletion
 		b.Autocomplete(buffer.FileComplete)
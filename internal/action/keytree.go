@@ -2,6 +2,7 @@ package action
 
 import (
 	"bytes"
+	"sort"
 
 	"github.com/micro-editor/tcell/v2"
 )
@@ -39,6 +40,10 @@ type TreeAction struct {
 	any    PaneKeyAnyAction
 	mouse  PaneMouseAction
 
+	// name is the action string the binding was made from, if any. It is
+	// used to look up the bindings of an action
+	name string
+
 	modes []ModeConstraint
 }
 
@@ -139,6 +144,26 @@ func (k *KeyTree) RegisterMouseBinding(e Event, a PaneMouseAction) {
 	})
 }
 
+// RegisterNamedKeyBinding registers a PaneKeyAction with an Event, and
+// remembers the action string it was made from so that it can be found
+// with Lookup.
+func (k *KeyTree) RegisterNamedKeyBinding(e Event, name string, a PaneKeyAction) {
+	k.registerBinding(e, TreeAction{
+		action: a,
+		name:   name,
+	})
+}
+
+// RegisterNamedMouseBinding registers a PaneMouseAction with an Event, and
+// remembers the action string it was made from so that it can be found
+// with Lookup.
+func (k *KeyTree) RegisterNamedMouseBinding(e Event, name string, a PaneMouseAction) {
+	k.registerBinding(e, TreeAction{
+		mouse: a,
+		name:  name,
+	})
+}
+
 func (k *KeyTree) registerBinding(e Event, a TreeAction) {
 	switch ev := e.(type) {
 	case KeyEvent, MouseEvent, RawEvent:
@@ -238,16 +263,99 @@ func (k *KeyTree) RecordedEventsStr() string {
 	return buf.String()
 }
 
+// Lookup returns the names of the events whose currently active action was
+// registered with a name for which match returns true. The shortest names
+// come first.
+func (k *KeyTree) Lookup(match func(name string) bool) []string {
+	var events []string
+	var walk func(n *KeyTreeNode, path []Event)
+	walk = func(n *KeyTreeNode, path []Event) {
+		for e, c := range n.children {
+			p := append(path[:len(path):len(path)], e)
+			for _, a := range c.actions {
+				if !k.active(a) {
+					continue
+				}
+				if a.name != "" && match(a.name) {
+					if len(p) == 1 {
+						events = append(events, e.Name())
+					} else {
+						events = append(events, KeySequenceEvent{p}.Name())
+					}
+				}
+				break
+			}
+			walk(c, p)
+		}
+	}
+	walk(k.root, nil)
+
+	sort.Slice(events, func(i, j int) bool {
+		if len(events[i]) != len(events[j]) {
+			return len(events[i]) < len(events[j])
+		}
+		return events[i] < events[j]
+	})
+	return events
+}
+
+// active returns whether the mode constraints of the action are met
+func (k *KeyTree) active(a TreeAction) bool {
+	for _, mc := range a.modes {
+		if k.modes[mc.mode] != mc.disabled {
+			return false
+		}
+	}
+	return true
+}
+
 // DeleteBinding removes any currently active actions associated with the
 // given event.
 func (k *KeyTree) DeleteBinding(e Event) {
-
+	n := k.findNode(e)
+	if n == nil {
+		return
+	}
+	for i, a := range n.actions {
+		active := true
+		for _, mc := range a.modes {
+			if k.modes[mc.mode] != mc.disabled {
+				active = false
+			}
+		}
+		if active {
+			n.actions = append(n.actions[:i:i], n.actions[i+1:]...)
+			return
+		}
+	}
 }
 
 // DeleteAllBindings removes all actions associated with the given event,
 // regardless of whether they are active or not.
 func (k *KeyTree) DeleteAllBindings(e Event) {
+	if n := k.findNode(e); n != nil {
+		n.actions = []TreeAction{}
+	}
+}
 
+// findNode returns the node associated with the given event, or nil if
+// there is none
+func (k *KeyTree) findNode(e Event) *KeyTreeNode {
+	switch ev := e.(type) {
+	case KeyEvent, MouseEvent, RawEvent:
+		return k.root.children[e]
+	case KeySequenceEvent:
+		n := k.root
+		for _, key := range ev.keys {
+			c, ok := n.children[key]
+			if !ok {
+				return nil
+			}
+			n = c
+		}
+		return n
+	}
+	return nil
 }
 
 // SetMode enables or disabled a given mode
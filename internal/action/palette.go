@@ -0,0 +1,241 @@
+package action
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/zyedidia/micro/v2/internal/util"
+)
+
+// maxPaletteKeys is the maximum number of key bindings shown for an entry
+// of the command palette
+const maxPaletteKeys = 3
+
+func init() {
+	// registered here since the palette itself lists BufKeyActions
+	BufKeyActions["CommandPalette"] = (*BufPane).CommandPalette
+}
+
+// actionDescriptions are the one line descriptions of the actions shown in
+// the command palette
+var actionDescriptions = map[string]string{
+	"CursorUp":                  "Move the cursor up",
+	"CursorDown":                "Move the cursor down",
+	"CursorPageUp":              "Move the cursor a page up",
+	"CursorPageDown":            "Move the cursor a page down",
+	"CursorLeft":                "Move the cursor left",
+	"CursorRight":               "Move the cursor right",
+	"CursorStart":               "Move the cursor to the start of the buffer",
+	"CursorEnd":                 "Move the cursor to the end of the buffer",
+	"CursorToViewTop":           "Move the cursor to the top of the view",
+	"CursorToViewCenter":        "Move the cursor to the center of the view",
+	"CursorToViewBottom":        "Move the cursor to the bottom of the view",
+	"SelectToStart":             "Select to the start of the buffer",
+	"SelectToEnd":               "Select to the end of the buffer",
+	"SelectUp":                  "Select up one line",
+	"SelectDown":                "Select down one line",
+	"SelectLeft":                "Select the character to the left of the cursor",
+	"SelectRight":               "Select the character to the right of the cursor",
+	"WordRight":                 "Move the cursor one word to the right",
+	"WordLeft":                  "Move the cursor one word to the left",
+	"SubWordRight":              "Move the cursor one sub-word to the right",
+	"SubWordLeft":               "Move the cursor one sub-word to the left",
+	"SelectWordRight":           "Select the word to the right of the cursor",
+	"SelectWordLeft":            "Select the word to the left of the cursor",
+	"SelectSubWordRight":        "Select the sub-word to the right of the cursor",
+	"SelectSubWordLeft":         "Select the sub-word to the left of the cursor",
+	"DeleteWordRight":           "Delete the word to the right of the cursor",
+	"DeleteWordLeft":            "Delete the word to the left of the cursor",
+	"DeleteSubWordRight":        "Delete the sub-word to the right of the cursor",
+	"DeleteSubWordLeft":         "Delete the sub-word to the left of the cursor",
+	"SelectLine":                "Select the current line",
+	"SelectToStartOfLine":       "Select to the start of the line",
+	"SelectToStartOfText":       "Select to the start of the text of the line",
+	"SelectToStartOfTextToggle": "Select to the start of the text or of the line",
+	"SelectToEndOfLine":         "Select to the end of the line",
+	"ParagraphPrevious":         "Move the cursor to the previous paragraph",
+	"ParagraphNext":             "Move the cursor to the next paragraph",
+	"SelectToParagraphPrevious": "Select to the previous paragraph",
+	"SelectToParagraphNext":     "Select to the next paragraph",
+	"InsertNewline":             "Insert a newline, indented if autoindent is on",
+	"InsertEnter":               "Insert a newline, indented if autoindent is on",
+	"Backspace":                 "Delete the previous character",
+	"Delete":                    "Delete the next character",
+	"InsertTab":                 "Insert a tab or spaces",
+	"Save":                      "Save the buffer",
+	"SaveAll":                   "Save all open buffers",
+	"SaveAs":                    "Save the buffer under a new name",
+	"Find":                      "Search forward for a regular expression",
+	"FindLiteral":               "Search forward for a text",
+	"FindNext":                  "Search forward for the last search term",
+	"FindPrevious":              "Search backward for the last search term",
+	"DiffNext":                  "Move the cursor to the next block of changes",
+	"DiffPrevious":              "Move the cursor to the previous block of changes",
+	"Center":                    "Center the view on the cursor",
+	"Undo":                      "Undo the last change",
+	"Redo":                      "Redo the last undone change",
+	"Copy":                      "Copy the selection to the clipboard",
+	"CopyLine":                  "Copy the current line to the clipboard",
+	"Cut":                       "Cut the selection to the clipboard",
+	"CutLine":                   "Cut the current line to the clipboard",
+	"Duplicate":                 "Duplicate the selection",
+	"DuplicateLine":             "Duplicate the current line",
+	"DeleteLine":                "Delete the current line",
+	"MoveLinesUp":               "Move the current or selected lines up",
+	"MoveLinesDown":             "Move the current or selected lines down",
+	"IndentSelection":           "Indent the selection",
+	"OutdentSelection":          "Outdent the selection",
+	"Autocomplete":              "Complete the word before the cursor",
+	"CycleAutocompleteBack":     "Go back to the previous suggestion or tabstop",
+	"OpenCompletion":            "Open the completion menu with fuzzy matches",
+	"OutdentLine":               "Outdent the current line",
+	"IndentLine":                "Indent the current line",
+	"Paste":                     "Paste from the clipboard",
+	"PastePrimary":              "Paste from the primary clipboard",
+	"SelectAll":                 "Select the whole buffer",
+	"OpenFile":                  "Open a file in the current pane",
+	"Start":                     "Scroll to the start of the buffer",
+	"End":                       "Scroll to the end of the buffer",
+	"PageUp":                    "Scroll the view up a page",
+	"PageDown":                  "Scroll the view down a page",
+	"SelectPageUp":              "Select up one page",
+	"SelectPageDown":            "Select down one page",
+	"HalfPageUp":                "Scroll the view up half a page",
+	"HalfPageDown":              "Scroll the view down half a page",
+	"StartOfText":               "Move the cursor to the start of the text of the line",
+	"StartOfTextToggle":         "Move the cursor to the start of the text or of the line",
+	"StartOfLine":               "Move the cursor to the start of the line",
+	"EndOfLine":                 "Move the cursor to the end of the line",
+	"ToggleHelp":                "Toggle the help screen",
+	"ToggleKeyMenu":             "Toggle the key menu",
+	"ToggleDiffGutter":          "Toggle the diff gutter",
+	"ToggleRuler":               "Toggle the line numbers",
+	"ToggleHighlightSearch":     "Toggle the highlighting of the search matches",
+	"UnhighlightSearch":         "Stop highlighting the search matches",
+	"ResetSearch":               "Forget the last search term",
+	"ClearStatus":               "Clear the infobar",
+	"ClearInfo":                 "Clear the infobar",
+	"ShellMode":                 "Run a shell command",
+	"CommandMode":               "Open the command prompt",
+	"CommandPalette":            "Open the command palette",
+	"FindFile":                  "Find a file of the working directory",
+	"FindBuffer":                "Find an open buffer",
+	"FindRecent":                "Find a recently opened file",
+	"FindCommand":               "Find a command",
+	"FindSymbol":                "Find a definition of the current buffer",
+	"ToggleExplorer":            "Open or close the file explorer",
+	"ToggleOverwriteMode":       "Toggle the overwrite mode",
+	"Escape":                    "Leave the current mode",
+	"Quit":                      "Close the current pane",
+	"QuitAll":                   "Quit micro",
+	"ForceQuit":                 "Close the current pane without saving",
+	"AddTab":                    "Open a new tab",
+	"PreviousTab":               "Switch to the previous tab",
+	"NextTab":                   "Switch to the next tab",
+	"FirstTab":                  "Switch to the first tab",
+	"LastTab":                   "Switch to the last tab",
+	"NextSplit":                 "Switch to the next split",
+	"PreviousSplit":             "Switch to the previous split",
+	"FirstSplit":                "Switch to the first split",
+	"LastSplit":                 "Switch to the last split",
+	"Unsplit":                   "Close all the other splits of the tab",
+	"VSplit":                    "Open an empty vertical split",
+	"HSplit":                    "Open an empty horizontal split",
+	"ToggleMacro":               "Start or stop recording a macro",
+	"PlayMacro":                 "Play the last recorded macro",
+	"Suspend":                   "Suspend micro to the shell",
+	"ScrollUp":                  "Scroll the view up",
+	"ScrollDown":                "Scroll the view down",
+	"SpawnMultiCursor":          "Add a cursor at the next match of the selection or word",
+	"SpawnMultiCursorUp":        "Add a cursor on the line above",
+	"SpawnMultiCursorDown":      "Add a cursor on the line below",
+	"SpawnMultiCursorSelect":    "Add a cursor on each selected line",
+	"RemoveMultiCursor":         "Remove the last cursor",
+	"RemoveAllMultiCursors":     "Remove all cursors but one",
+	"SkipMultiCursor":           "Move the last cursor to the next match",
+	"SkipMultiCursorBack":       "Move the last cursor to the previous match",
+	"JumpToMatchingBrace":       "Jump to the matching brace",
+	"JumpLine":                  "Jump to a line number",
+	"Deselect":                  "Clear the selection",
+}
+
+// bindingRuns returns whether the binding, a chain of actions separated
+// by ',', '|' or '&', runs the given action, or the given command if name
+// is of the form "command:name"
+func bindingRuns(binding, name string) bool {
+	for binding != "" {
+		a := binding
+		if idx := util.IndexAnyUnquoted(binding, "&|,"); idx >= 0 {
+			a = binding[:idx]
+			binding = binding[idx+1:]
+		} else {
+			binding = ""
+		}
+
+		if strings.HasPrefix(a, "command:") || strings.HasPrefix(a, "command-edit:") {
+			args := strings.Fields(strings.SplitN(a, ":", 2)[1])
+			if len(args) > 0 && "command:"+args[0] == name {
+				return true
+			}
+		} else if a == name {
+			return true
+		}
+	}
+	return false
+}
+
+// paletteDetail returns the detail of a command palette entry: its
+// description followed by the keys it is bound to in buffers
+func paletteDetail(desc, name string) string {
+	keys := BufBindings.Lookup(func(binding string) bool {
+		return bindingRuns(binding, name)
+	})
+	if len(keys) > maxPaletteKeys {
+		keys = append(keys[:maxPaletteKeys], "…")
+	}
+	if len(keys) > 0 {
+		desc += " (" + strings.Join(keys, ", ") + ")"
+	}
+	return strings.TrimSpace(desc)
+}
+
+// CommandPalette opens a finder with every action and command, and
+// their descriptions and key bindings. A chosen action is executed, and
+// a chosen command is written in the command prompt.
+func (h *BufPane) CommandPalette() bool {
+	var items []*FinderItem
+
+	var actions []string
+	for name := range BufKeyActions {
+		if name != "None" {
+			actions = append(actions, name)
+		}
+	}
+	sort.Strings(actions)
+	for _, name := range actions {
+		name := name
+		items = append(items, &FinderItem{
+			Label:  name,
+			Detail: paletteDetail(actionDescriptions[name], name),
+			Accept: func() {
+				h.execActionOnCursors(BufKeyActions[name], name, nil)
+			},
+		})
+	}
+
+	var cmds []string
+	for name := range commands {
+		cmds = append(cmds, name)
+	}
+	sort.Strings(cmds)
+	for _, name := range cmds {
+		name := name
+		items = append(items, &FinderItem{
+			Label:  "> " + name,
+			Detail: paletteDetail(commands[name].desc, "command:"+name),
+			Accept: func() { CommandEditAction(name + " ")(h) },
+		})
+	}
+
+	return NewFinder(FinderPalette, items) != nil
+}
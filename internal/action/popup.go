@@ -0,0 +1,357 @@
+package action
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/micro-editor/tcell/v2"
+	"github.com/zyedidia/micro/v2/internal/buffer"
+	"github.com/zyedidia/micro/v2/internal/display"
+	"github.com/zyedidia/micro/v2/internal/util"
+)
+
+// The anchors which determine where a popup is placed in its tab
+const (
+	// PopupCursor places the popup below the cursor of the bufpane which
+	// was active when it was created, or above it if there is no room below
+	PopupCursor = "cursor"
+	// PopupCenter places the popup in the center of the tab
+	PopupCenter = "center"
+	// PopupLeft and PopupRight place the popup along the left or right
+	// side of the tab, with the full height of the tab
+	PopupLeft  = "left"
+	PopupRight = "right"
+)
+
+// popupDisabledActions are the actions which change the split layout or
+// the tabs and which do nothing in a popup
+var popupDisabledActions = map[string]bool{
+	"VSplit":        true,
+	"HSplit":        true,
+	"Unsplit":       true,
+	"NextSplit":     true,
+	"PreviousSplit": true,
+	"FirstSplit":    true,
+	"LastSplit":     true,
+	"AddTab":        true,
+	"PreviousTab":   true,
+	"NextTab":       true,
+	"FirstTab":      true,
+	"LastTab":       true,
+	"ToggleHelp":    true,
+}
+
+// popupDisabledCommands are the commands which open splits or change the
+// tabs and which are not available in a popup
+var popupDisabledCommands = map[string]bool{
+	"vsplit":    true,
+	"hsplit":    true,
+	"tab":       true,
+	"tabmove":   true,
+	"tabswitch": true,
+	"term":      true,
+	"help":      true,
+	"log":       true,
+}
+
+// A Popup is a floating BufPane displayed over the panes of a tab. It shows
+// its own scratch buffer, optionally surrounded by a border with a title.
+// A focused popup receives the key events, and the Escape key closes it.
+type Popup struct {
+	*BufPane
+	win *display.PopupWindow
+
+	// Z is the z-order of the popup: the popups with a higher Z are
+	// displayed above the others
+	Z int
+
+	anchor        string
+	width, height int
+	// the bufpane and location the popup is anchored to for PopupCursor
+	anchorPane *BufPane
+	anchorLoc  buffer.Loc
+	// the geometry of the window, border included, when it was last placed
+	placed display.View
+	// offsetX moves a popup anchored to the cursor to the right
+	offsetX int
+
+	// a passive popup cannot be focused, and the clicks on it are passed
+	// to onClick
+	passive bool
+	onClick func(x, y int)
+
+	focused bool
+	closed  bool
+	onClose []func()
+}
+
+// NewPopup opens a popup with a scratch buffer containing the given text in
+// the current tab. The width and height are the size of the text area, the
+// height being ignored for the side panels. The popup is not focused.
+func NewPopup(text, anchor string, width, height int) (*Popup, error) {
+	switch anchor {
+	case PopupCursor, PopupCenter, PopupLeft, PopupRight:
+	default:
+		return nil, errors.New("Invalid popup anchor " + anchor)
+	}
+
+	b := buffer.NewBufferFromString(text, "", buffer.BTScratch)
+	b.SetOptionNative("statusline", false)
+	b.SetOptionNative("ruler", false)
+	b.SetOptionNative("diffgutter", false)
+	b.SetOptionNative("minimap", false)
+
+	t := MainTab()
+	p := new(Popup)
+	p.anchor = anchor
+	p.width, p.height = width, height
+	if bp := t.CurPane(); bp != nil {
+		p.anchorPane = bp
+		p.anchorLoc = bp.Cursor.Loc
+	}
+	p.win = display.NewPopupWindow(b)
+	p.win.SetActive(false)
+	p.layout(t)
+	p.BufPane = NewBufPane(b, p.win, t)
+	p.BufPane.popup = p
+
+	t.Popups = append(t.Popups, p)
+	t.sortPopups()
+	return p, nil
+}
+
+// SetTitle sets the title displayed in the border of the popup
+func (p *Popup) SetTitle(title string) {
+	p.win.Title = title
+}
+
+// SetBorder sets whether the popup is surrounded by a border
+func (p *Popup) SetBorder(border bool) {
+	p.win.Border = border
+	p.placed = display.View{}
+}
+
+// SetSize changes the size of the text area of the popup
+func (p *Popup) SetSize(width, height int) {
+	p.width, p.height = width, height
+}
+
+// SetZ changes the z-order of the popup
+func (p *Popup) SetZ(z int) {
+	p.Z = z
+	p.tab.sortPopups()
+}
+
+// SetText replaces the text of the popup's buffer
+func (p *Popup) SetText(text string) {
+	p.Buf.Remove(p.Buf.Start(), p.Buf.End())
+	p.Buf.Insert(p.Buf.Start(), text)
+	p.Cursor.GotoLoc(p.Buf.Start())
+	p.Relocate()
+}
+
+// OnClose registers a function which is called when the popup is closed
+func (p *Popup) OnClose(f func()) {
+	p.onClose = append(p.onClose, f)
+}
+
+// IsOpen returns whether the popup has not been closed yet
+func (p *Popup) IsOpen() bool {
+	return !p.closed
+}
+
+// IsFocused returns whether the popup receives the key events
+func (p *Popup) IsFocused() bool {
+	return p.focused
+}
+
+// Focus gives the focus to the popup, which then receives the key events
+// instead of the active pane of its tab
+func (p *Popup) Focus() {
+	if p.closed || p.focused || p.passive {
+		return
+	}
+	t := p.tab
+	if f := t.FocusedPopup(); f != nil {
+		f.focused = false
+		f.SetActive(false)
+	} else {
+		t.Panes[t.active].SetActive(false)
+	}
+	p.focused = true
+	p.SetActive(true)
+}
+
+// Unfocus gives the focus back to the active pane of the popup's tab
+func (p *Popup) Unfocus() {
+	if !p.focused {
+		return
+	}
+	p.focused = false
+	p.SetActive(false)
+	p.tab.Panes[p.tab.active].SetActive(true)
+}
+
+// Close removes the popup from its tab and closes its buffer
+func (p *Popup) Close() {
+	if p.closed {
+		return
+	}
+	p.Unfocus()
+	p.closed = true
+	t := p.tab
+	for i, q := range t.Popups {
+		if q == p {
+			t.Popups = append(t.Popups[:i:i], t.Popups[i+1:]...)
+			break
+		}
+	}
+	p.Buf.Close()
+	for _, f := range p.onClose {
+		f()
+	}
+}
+
+// Display places the popup according to its anchor and draws it
+func (p *Popup) Display() {
+	p.layout(p.tab)
+	p.BufPane.Display()
+}
+
+// layout computes the geometry of the popup in the given tab and moves the
+// window if it changed
+func (p *Popup) layout(t *Tab) {
+	border := 0
+	if p.win.Border {
+		border = 2
+	}
+	w := util.Min(p.width+border, t.W)
+	h := util.Min(p.height+border, t.H)
+	x, y := t.X, t.Y
+
+	switch p.anchor {
+	case PopupCenter:
+		x += (t.W - w) / 2
+		y += (t.H - h) / 2
+	case PopupLeft:
+		h = t.H
+	case PopupRight:
+		x += t.W - w
+		h = t.H
+	case PopupCursor:
+		cx, cy := t.X, t.Y
+		if p.anchorPane != nil {
+			cx, cy = p.anchorPane.screenLoc(p.anchorLoc)
+		}
+		x = util.Clamp(cx+p.offsetX, t.X, t.X+t.W-w)
+		if cy+1+h <= t.Y+t.H || cy-h < t.Y {
+			y = util.Clamp(cy+1, t.Y, t.Y+t.H-h)
+		} else {
+			y = cy - h
+		}
+	}
+
+	v := display.View{X: x, Y: y, Width: w, Height: h}
+	if v != p.placed {
+		p.placed = v
+		p.win.Place(x, y, w, h)
+	}
+}
+
+// screenLoc returns the location on the screen of the given location of
+// the buffer
+func (h *BufPane) screenLoc(loc buffer.Loc) (int, int) {
+	bv := h.BufView()
+	vloc := h.VLocFromLoc(loc)
+	return bv.X + vloc.VisualX - bv.StartCol, bv.Y + h.Diff(bv.StartLine, vloc.SLoc)
+}
+
+// closePopups closes all the popups of the tab
+func (t *Tab) closePopups() {
+	for len(t.Popups) > 0 {
+		t.Popups[len(t.Popups)-1].Close()
+	}
+}
+
+// FocusedPopup returns the popup of the tab which has the focus, if any
+func (t *Tab) FocusedPopup() *Popup {
+	for _, p := range t.Popups {
+		if p.focused {
+			return p
+		}
+	}
+	return nil
+}
+
+// sortPopups sorts the popups of the tab by z-order
+func (t *Tab) sortPopups() {
+	sort.SliceStable(t.Popups, func(i, j int) bool {
+		return t.Popups[i].Z < t.Popups[j].Z
+	})
+}
+
+// popupAt returns the topmost popup covering the given screen location
+func (t *Tab) popupAt(x, y int) *Popup {
+	for i := len(t.Popups) - 1; i >= 0; i-- {
+		if t.Popups[i].win.Contains(x, y) {
+			return t.Popups[i]
+		}
+	}
+	return nil
+}
+
+// handlePopupEvent sends the event to the popup it is meant for and returns
+// whether it was consumed. Escape closes the focused popup; the key events
+// go to the focused popup and the mouse events to the popup under the
+// mouse, which gets the focus when it is clicked.
+func (t *Tab) handlePopupEvent(event tcell.Event) bool {
+	if len(t.Popups) == 0 {
+		return false
+	}
+	focused := t.FocusedPopup()
+
+	switch e := event.(type) {
+	case *tcell.EventKey:
+		if e.Key() == tcell.KeyEscape && focused != nil {
+			focused.Close()
+			return true
+		}
+	case *tcell.EventMouse:
+		mx, my := e.Position()
+		btn := e.Buttons()
+		if btn == tcell.ButtonNone {
+			if focused != nil && len(focused.mousePressed) > 0 {
+				focused.HandleEvent(event)
+				return true
+			}
+			return false
+		}
+		p := t.popupAt(mx, my)
+		if p == nil {
+			if btn&^(tcell.WheelUp|tcell.WheelDown|tcell.WheelLeft|tcell.WheelRight) != tcell.ButtonNone && focused != nil {
+				focused.Unfocus()
+			}
+			return false
+		}
+		if p.passive {
+			if btn == tcell.Button1 && p.onClick != nil {
+				p.onClick(mx, my)
+			}
+			return true
+		}
+		if btn&^(tcell.WheelUp|tcell.WheelDown|tcell.WheelLeft|tcell.WheelRight) != tcell.ButtonNone {
+			p.Focus()
+		}
+		v := p.GetView()
+		if mx >= v.X && mx < v.X+v.Width && my >= v.Y && my < v.Y+v.Height {
+			p.HandleEvent(event)
+		}
+		return true
+	}
+
+	if focused == nil {
+		return false
+	}
+	focused.HandleEvent(event)
+	return true
+}
@@ -0,0 +1,45 @@
+package action
+
+import (
+	"github.com/zyedidia/micro/v2/internal/config"
+)
+
+// PromptProjectTrust asks the user whether the init.lua and the
+// bindings.json of the project's .micro directory may run commands, if
+// they exist and the project has not been trusted yet in the current
+// content of its files. The answer is remembered in ConfigDir/trusted.json
+// until one of the files changes.
+func PromptProjectTrust() {
+	if !config.ProjectNeedsTrust() || config.ProjectTrusted() {
+		return
+	}
+
+	InfoBar.YNPrompt("Trust the init.lua and bindings.json in "+config.ProjectDir+"? (y,n)", func(yes, canceled bool) {
+		if !yes || canceled {
+			return
+		}
+		if err := config.TrustProject(); err != nil {
+			InfoBar.Error(err)
+			return
+		}
+		if err := ReloadBindings(); err != nil {
+			InfoBar.Error(err)
+			return
+		}
+
+		p := config.FindPlugin("projectlua")
+		if p == nil || p.Loaded {
+			return
+		}
+		if err := p.Load(); err != nil {
+			InfoBar.Error(err)
+			return
+		}
+		for _, fn := range []string{"preinit", "init", "postinit"} {
+			if _, err := p.Call(fn); err != nil && err != config.ErrNoSuchFunction {
+				InfoBar.Error("Plugin ", p.Name, ": ", err)
+				return
+			}
+		}
+	})
+}
@@ -62,6 +62,7 @@ func (t *TabList) RemoveTab(id uint64) {
 			continue
 		}
 		if p.Panes[0].ID() == id {
+			p.closePopups()
 			copy(t.List[i:], t.List[i+1:])
 			t.List[len(t.List)-1] = nil
 			t.List = t.List[:len(t.List)-1]
@@ -107,6 +108,9 @@ func (t *TabList) HandleEvent(event tcell.Event) {
 		mx, my := e.Position()
 		switch e.Buttons() {
 		case tcell.Button1:
+			if my == InfoBar.GetView().Y && OpenMessageLink() {
+				return
+			}
 			if my == t.Y && len(t.List) > 1 {
 				if mx == 0 {
 					t.Scroll(-4)
@@ -161,6 +165,12 @@ func (t *TabList) SetActive(a int) {
 				if err != nil {
 					screen.TermMessage(err)
 				}
+				if err := config.Publish(config.EvPaneActivated, map[string]interface{}{"bufpane": p.CurPane()}); err != nil {
+					screen.TermMessage(err)
+				}
+				if err := config.Publish(config.EvTabSwitched, map[string]interface{}{"tab": a, "bufpane": p.CurPane()}); err != nil {
+					screen.TermMessage(err)
+				}
 			}
 		} else {
 			p.isActive = false
@@ -237,6 +247,10 @@ type Tab struct {
 	Panes  []Pane
 	active int
 
+	// Popups are the floating popups displayed over the panes, sorted by
+	// z-order
+	Popups []*Popup
+
 	resizing *views.Node // node currently being resized
 	// captures whether the mouse is released
 	release bool
@@ -274,6 +288,10 @@ func NewTabFromPane(x, y, width, height int, pane Pane) *Tab {
 // If the event is a mouse press event in a pane, that pane will become active
 // and get the event
 func (t *Tab) HandleEvent(event tcell.Event) {
+	if t.handlePopupEvent(event) {
+		return
+	}
+
 	switch e := event.(type) {
 	case *tcell.EventMouse:
 		mx, my := e.Position()
@@ -391,6 +409,14 @@ func (t *Tab) Resize() {
 	}
 }
 
+// Display draws the dividers between the splits and the popups of the tab
+func (t *Tab) Display() {
+	t.UIWindow.Display()
+	for _, p := range t.Popups {
+		p.Display()
+	}
+}
+
 // CurPane returns the currently active pane
 func (t *Tab) CurPane() *BufPane {
 	p, ok := t.Panes[t.active].(*BufPane)
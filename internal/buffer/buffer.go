@@ -11,6 +11,7 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -60,6 +61,8 @@ var (
 	BTRaw = BufType{4, false, true, false}
 	// BTInfo is a buffer for inputting information
 	BTInfo = BufType{5, false, true, false}
+	// BTExplorer is a buffer that shows the tree of the file explorer
+	BTExplorer = BufType{6, false, true, false}
 	// BTStdout is a buffer that only writes to stdout
 	// when closed
 	BTStdout = BufType{6, false, true, true}
@@ -95,6 +98,16 @@ type SharedBuffer struct {
 	CurSuggestion int
 
 	Messages []*Message
+	// Overlays are highlighted spans drawn over the syntax highlighting
+	Overlays []*Overlay
+	// overlayLines indexes the overlays by the lines that they intersect,
+	// it is nil when it must be built again
+	overlayLines map[int][]*Overlay
+	// VirtualText are the non-editable strings drawn in the buffer, sorted
+	// by location
+	VirtualText []*VirtualText
+	// snippet is the snippet being expanded, if any
+	snippet *snippetSession
 
 	updateDiffTimer   *time.Timer
 	diffBase          []byte
@@ -119,8 +132,19 @@ type SharedBuffer struct {
 	// SyntaxDef represents the syntax highlighting definition being used
 	// This stores the highlighting rules and filetype detection info
 	SyntaxDef *highlight.Def
+	// ftReason describes why the current filetype was detected
+	ftReason string
+	// settingOrigins describes where the local settings of the buffer
+	// which were not set by the user come from
+	settingOrigins map[string]string
 
 	ModifiedThisFrame bool
+	// version is incremented each time the text is modified
+	version int
+	// wordList are the words of the text at wordListVersion, used by the
+	// completion
+	wordList        []string
+	wordListVersion int
 
 	// Hash of the original buffer -- empty if fastdirty is on
 	origHash [md5.Size]byte
@@ -128,6 +152,7 @@ type SharedBuffer struct {
 
 func (b *SharedBuffer) insert(pos Loc, value []byte) {
 	b.isModified = true
+	b.version++
 	b.HasSuggestions = false
 	b.LineArray.insert(pos, value)
 
@@ -136,6 +161,7 @@ func (b *SharedBuffer) insert(pos Loc, value []byte) {
 }
 func (b *SharedBuffer) remove(start, end Loc) []byte {
 	b.isModified = true
+	b.version++
 	b.HasSuggestions = false
 	defer b.MarkModified(start.Y, end.Y)
 	return b.LineArray.remove(start, end)
@@ -211,6 +237,13 @@ type Buffer struct {
 	LastSearchRegex bool
 	// HighlightSearch enables highlighting all instances of the last successful search
 	HighlightSearch bool
+	// searchLines are the lines containing a match of the last search, and
+	// searchLinesKey the search and the version of the text they were found
+	// for, searchLinesBusy being true while they are found in the background
+	searchLines     []int
+	searchLinesKey  searchLinesKey
+	searchLinesBusy bool
+	searchLinesLock sync.Mutex
 
 	// OverwriteMode indicates that we are in overwrite mode (toggled by
 	// Insert key by default) i.e. that typing a character shall replace the
@@ -323,6 +356,7 @@ func NewBuffer(r io.Reader, size int64, path string, startcursor Loc, btype BufT
 	}
 
 	hasBackup := false
+	var editorConfig map[string]config.EditorConfigSetting
 	if !found {
 		b.SharedBuffer = new(SharedBuffer)
 		b.Type = btype
@@ -338,7 +372,8 @@ func NewBuffer(r io.Reader, size int64, path string, startcursor Loc, btype BufT
 				b.Settings[k] = v
 			}
 		}
-		config.UpdatePathGlobLocals(b.Settings, absPath)
+		config.UpdatePathGlobLocals(b.Settings, b.settingsTarget(size))
+		editorConfig = b.applyEditorConfig(nil)
 
 		b.encoding, err = htmlindex.Get(b.Settings["encoding"].(string))
 		if err != nil {
@@ -369,6 +404,7 @@ func NewBuffer(r io.Reader, size int64, path string, startcursor Loc, btype BufT
 			} else {
 				// in case of autodetection treat as locally set
 				b.LocalSettings["fileformat"] = true
+				b.setOrigin("fileformat", "detected from the file")
 			}
 
 			b.LineArray = NewLineArray(uint64(size), ff, reader)
@@ -392,7 +428,13 @@ func NewBuffer(r io.Reader, size int64, path string, startcursor Loc, btype BufT
 
 	b.UpdateRules()
 	// we know the filetype now, so update per-filetype settings
-	config.UpdateFileTypeLocals(b.Settings, b.Settings["filetype"].(string))
+	config.UpdateFileTypeLocals(b.Settings, b.settingsTarget(size))
+	// .editorconfig files and modelines take precedence over the settings
+	// from settings.json
+	if !found {
+		b.applyEditorConfig(editorConfig)
+	}
+	b.applyModeline()
 
 	if _, err := os.Stat(filepath.Join(config.ConfigDir, "buffers")); errors.Is(err, fs.ErrNotExist) {
 		os.Mkdir(filepath.Join(config.ConfigDir, "buffers"), os.ModePerm)
@@ -425,6 +467,9 @@ func NewBuffer(r io.Reader, size int64, path string, startcursor Loc, btype BufT
 	if err != nil {
 		screen.TermMessage(err)
 	}
+	if err := config.Publish(config.EvBufferOpen, map[string]interface{}{"buf": b}); err != nil {
+		screen.TermMessage(err)
+	}
 
 	OpenBuffers = append(OpenBuffers, b)
 
@@ -434,6 +479,9 @@ func NewBuffer(r io.Reader, size int64, path string, startcursor Loc, btype BufT
 // CloseOpenBuffers removes all open buffers
 func CloseOpenBuffers() {
 	for i, buf := range OpenBuffers {
+		if err := config.Publish(config.EvBufferClose, map[string]interface{}{"buf": buf}); err != nil {
+			screen.TermMessage(err)
+		}
 		buf.Fini()
 		OpenBuffers[i] = nil
 	}
@@ -444,6 +492,9 @@ func CloseOpenBuffers() {
 func (b *Buffer) Close() {
 	for i, buf := range OpenBuffers {
 		if b == buf {
+			if err := config.Publish(config.EvBufferClose, map[string]interface{}{"buf": b}); err != nil {
+				screen.TermMessage(err)
+			}
 			b.Fini()
 			copy(OpenBuffers[i:], OpenBuffers[i+1:])
 			OpenBuffers[len(OpenBuffers)-1] = nil
@@ -563,6 +614,9 @@ func (b *Buffer) ReOpen() error {
 	}
 	b.isModified = false
 	b.RelocateCursors()
+	if err := config.Publish(config.EvBufferReloaded, map[string]interface{}{"buf": b}); err != nil {
+		screen.TermMessage(err)
+	}
 	return err
 }
 
@@ -785,12 +839,23 @@ func (b *Buffer) UpdateRules() {
 
 	b.SyntaxDef = nil
 
+	if ft == "unknown" || ft == "" {
+		b.ftReason = "no detection rule matched"
+		if dft, reason := b.detectFileType(); dft != "" {
+			ft = dft
+			b.Settings["filetype"] = ft
+			b.ftReason = reason
+		}
+	}
+
 	// syntaxFileInfo is an internal helper structure
 	// to store properties of one single syntax file
 	type syntaxFileInfo struct {
 		header    *highlight.Header
 		fileName  string
 		syntaxDef *highlight.Def
+		// user is true for the user's custom syntax files
+		user bool
 	}
 
 	fnameMatches := []syntaxFileInfo{}
@@ -852,9 +917,9 @@ func (b *Buffer) UpdateRules() {
 			}
 
 			if matchedFileName {
-				fnameMatches = append(fnameMatches, syntaxFileInfo{header, f.Name(), syndef})
+				fnameMatches = append(fnameMatches, syntaxFileInfo{header, f.Name(), syndef, true})
 			} else if matchedFileHeader {
-				headerMatches = append(headerMatches, syntaxFileInfo{header, f.Name(), syndef})
+				headerMatches = append(headerMatches, syntaxFileInfo{header, f.Name(), syndef, true})
 			}
 		}
 	}
@@ -876,10 +941,10 @@ func (b *Buffer) UpdateRules() {
 
 			if ft == "unknown" || ft == "" {
 				if header.MatchFileName(b.Path) {
-					fnameMatches = append(fnameMatches, syntaxFileInfo{header, f.Name(), nil})
+					fnameMatches = append(fnameMatches, syntaxFileInfo{header, f.Name(), nil, false})
 				}
 				if len(fnameMatches) == 0 && header.MatchFileHeader(b.lines[0].data) {
-					headerMatches = append(headerMatches, syntaxFileInfo{header, f.Name(), nil})
+					headerMatches = append(headerMatches, syntaxFileInfo{header, f.Name(), nil, false})
 				}
 			} else if header.FileType == ft {
 				syntaxFile = f.Name()
@@ -895,6 +960,49 @@ func (b *Buffer) UpdateRules() {
 		}
 
 		length := len(matches)
+		// the ties are ranked by the rules they match: matching the first
+		// line too, being a user syntax file, the length of the filename
+		// match, and finally the name of the syntax file
+		firstLine := b.lines[0].data
+		nameLen := func(m syntaxFileInfo) int {
+			if m.header.FileNameRegex == nil {
+				return 0
+			}
+			return len(m.header.FileNameRegex.FindString(b.Path))
+		}
+		criteria := []struct {
+			reason string
+			less   func(m1, m2 syntaxFileInfo) bool
+		}{
+			{"it also matches the first line", func(m1, m2 syntaxFileInfo) bool {
+				return m1.header.MatchFileHeader(firstLine) && !m2.header.MatchFileHeader(firstLine)
+			}},
+			{"it is a user syntax file", func(m1, m2 syntaxFileInfo) bool {
+				return m1.user && !m2.user
+			}},
+			{"its filename rule matches a longer part of the path", func(m1, m2 syntaxFileInfo) bool {
+				return nameLen(m1) > nameLen(m2)
+			}},
+			{"its name comes first", func(m1, m2 syntaxFileInfo) bool {
+				return m1.fileName < m2.fileName
+			}},
+		}
+		// rank returns the reason why m1 ranks before m2, "" if it doesn't
+		rank := func(m1, m2 syntaxFileInfo) string {
+			for _, c := range criteria {
+				if c.less(m1, m2) {
+					return c.reason
+				}
+				if c.less(m2, m1) {
+					return ""
+				}
+			}
+			return ""
+		}
+		sort.SliceStable(matches, func(i, j int) bool {
+			return rank(matches[i], matches[j]) != ""
+		})
+
 		if length > 0 {
 			signatureMatch := false
 			if length > 1 {
@@ -912,6 +1020,7 @@ func (b *Buffer) UpdateRules() {
 					if m.header.HasFileSignature() {
 						for i := 0; i < limit; i++ {
 							if m.header.MatchFileSignature(b.lines[i].data) {
+								b.ftReason = fmt.Sprintf("%d syntax files matched, line %d matches the signature of %s", length, i+1, m.fileName)
 								syntaxFile = m.fileName
 								if m.syntaxDef != nil {
 									b.SyntaxDef = m.syntaxDef
@@ -926,6 +1035,19 @@ func (b *Buffer) UpdateRules() {
 				}
 			}
 			if length == 1 || !signatureMatch {
+				kind := "filename"
+				if len(fnameMatches) == 0 {
+					kind = "first line"
+				}
+				if length == 1 {
+					b.ftReason = fmt.Sprintf("%s matches the %s rule of %s", kind, kind, matches[0].fileName)
+				} else {
+					names := make([]string, length)
+					for i, m := range matches {
+						names[i] = m.fileName
+					}
+					b.ftReason = fmt.Sprintf("%s matches the rules of %s, picked %s since %s", kind, strings.Join(names, ", "), matches[0].fileName, rank(matches[0], matches[1]))
+				}
 				syntaxFile = matches[0].fileName
 				if matches[0].syntaxDef != nil {
 					b.SyntaxDef = matches[0].syntaxDef
@@ -1422,6 +1544,12 @@ func (b *Buffer) SearchMatch(pos Loc) bool {
 	return b.LineArray.SearchMatch(b, pos)
 }
 
+// LineSearchMatch returns true if the given line contains a match of the
+// last search
+func (b *Buffer) LineSearchMatch(lineN int) bool {
+	return b.LineArray.LineSearchMatch(b, lineN)
+}
+
 // WriteLog writes a string to the log buffer
 func WriteLog(s string) {
 	LogBuf.EventHandler.Insert(LogBuf.End(), s)
@@ -321,3 +321,20 @@ func BenchmarkEdit1000000Lines100Cursors(b *testing.B) {
 func BenchmarkEdit1000000Lines1000Cursors(b *testing.B) {
 	benchEdit(b, 1000000, 1000)
 }
+
+func TestCloseOpenBuffers(t *testing.T) {
+	a := NewBufferFromString("a", "", BTDefault)
+	b := NewBufferFromString("b", "", BTDefault)
+
+	var closed []*Buffer
+	unsubscribe := config.Subscribe(config.EvBufferClose, 0, func(e config.Event) error {
+		closed = append(closed, e.Data["buf"].(*Buffer))
+		return nil
+	})
+	defer unsubscribe()
+
+	CloseOpenBuffers()
+	assert.Contains(t, closed, a)
+	assert.Contains(t, closed, b)
+	assert.Empty(t, OpenBuffers)
+}
@@ -0,0 +1,349 @@
+package buffer
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/zyedidia/micro/v2/internal/config"
+	"github.com/zyedidia/micro/v2/internal/util"
+)
+
+// The kinds of the completion items of the built-in sources. The sources
+// registered by plugins may use other kinds, such as "function".
+const (
+	CKWord    = "word"
+	CKFile    = "file"
+	CKSnippet = "snippet"
+	CKKeyword = "keyword"
+)
+
+// A CompletionItem is a suggestion of a completion source, which replaces
+// the text between its Start column and the cursor when it is accepted
+type CompletionItem struct {
+	// Label is shown in the completion menu and matched against the text
+	// being completed
+	Label string
+	// Insert is the text which is inserted, Label if it is empty. It is a
+	// snippet body when Snippet is true.
+	Insert  string
+	Snippet bool
+	// Kind is the kind of the item, such as CKWord
+	Kind string
+	// Detail is a short description shown beside the label and Doc is the
+	// documentation shown next to the completion menu
+	Detail string
+	Doc    string
+	// Start is the column where the text being completed starts, on the
+	// line of the cursor
+	Start int
+	// Source is the name of the source of the item
+	Source string
+
+	// Score is the score of the fuzzy match of the text being completed
+	// against the label, and Matches the positions of the matched runes
+	Score   int
+	Matches []int
+}
+
+// Items returns the suggestions of a completer as completion items of the
+// given kind
+func (c Completer) Items(b *Buffer, kind string) []*CompletionItem {
+	completions, suggestions := c(b)
+	if len(completions) != len(suggestions) {
+		return nil
+	}
+	cur := b.GetActiveCursor()
+	items := make([]*CompletionItem, len(suggestions))
+	for i, s := range suggestions {
+		start := cur.X - (util.CharacterCountInString(s) - util.CharacterCountInString(completions[i]))
+		items[i] = &CompletionItem{Label: s, Kind: kind, Start: util.Max(start, 0)}
+	}
+	return items
+}
+
+// Source returns a completion source with the suggestions of a completer,
+// as items of the given kind
+func (c Completer) Source(name string, priority int, kind string) *CompletionSource {
+	return &CompletionSource{
+		Name:     name,
+		Priority: priority,
+		Complete: func(b *Buffer, word string) []*CompletionItem {
+			return c.Items(b, kind)
+		},
+	}
+}
+
+// A CompletionSource provides completion items for the word before the
+// cursor of a buffer. The items with a negative Start column complete this
+// word.
+type CompletionSource struct {
+	Name string
+	// the items of the sources with a lower priority come first when
+	// their score is the same
+	Priority int
+	Complete func(b *Buffer, word string) []*CompletionItem
+}
+
+// completionSources are the registered completion sources, sorted by
+// priority
+var completionSources []*CompletionSource
+
+// RegisterCompletionSource registers a completion source, replacing the
+// source with the same name if there is one
+func RegisterCompletionSource(s *CompletionSource) {
+	UnregisterCompletionSource(s.Name)
+	completionSources = append(completionSources, s)
+	sort.SliceStable(completionSources, func(i, j int) bool {
+		return completionSources[i].Priority < completionSources[j].Priority
+	})
+}
+
+// UnregisterCompletionSource removes the completion source with the given
+// name
+func UnregisterCompletionSource(name string) {
+	for i, s := range completionSources {
+		if s.Name == name {
+			completionSources = append(completionSources[:i:i], completionSources[i+1:]...)
+			return
+		}
+	}
+}
+
+func init() {
+	RegisterCompletionSource(&CompletionSource{Name: "snippets", Priority: 10, Complete: snippetItems})
+	RegisterCompletionSource(&CompletionSource{Name: "keywords", Priority: 20, Complete: keywordItems})
+	RegisterCompletionSource(&CompletionSource{Name: "words", Priority: 30, Complete: wordItems})
+	RegisterCompletionSource(&CompletionSource{Name: "files", Priority: 40, Complete: fileItems})
+}
+
+// CompletionItems returns the completion items of all the sources for the text
+// before the active cursor, ranked by the score of their fuzzy match
+func (b *Buffer) CompletionItems() []*CompletionItem {
+	word, start := b.GetWord()
+	if start < 0 {
+		word, start = nil, b.GetActiveCursor().X
+	}
+
+	var items []*CompletionItem
+	// the same label is only suggested once, except for the snippets
+	seen := make(map[string]bool)
+	priority := make(map[*CompletionItem]int)
+	for _, s := range completionSources {
+		for _, it := range s.Complete(b, string(word)) {
+			key := it.Label
+			if it.Snippet {
+				key = "\x00" + key
+			}
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			if it.Start < 0 || it.Start > b.GetActiveCursor().X {
+				it.Start = start
+			}
+			it.Source = s.Name
+			priority[it] = s.Priority
+			items = append(items, it)
+		}
+	}
+	items = b.FilterCompletions(items)
+	sort.SliceStable(items, func(i, j int) bool {
+		return priority[items[i]] < priority[items[j]]
+	})
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].Score > items[j].Score
+	})
+	return items
+}
+
+// FilterCompletions returns the items whose label matches the text between
+// their start and the active cursor, with their score, in the same order
+func (b *Buffer) FilterCompletions(items []*CompletionItem) []*CompletionItem {
+	c := b.GetActiveCursor()
+	line := []rune(string(b.LineBytes(c.Y)))
+	var matched []*CompletionItem
+	for _, it := range items {
+		if it.Start > c.X || c.X > len(line) {
+			continue
+		}
+		text := string(line[it.Start:c.X])
+		if it.Label == text {
+			continue
+		}
+		if score, matches, ok := util.FuzzyMatch(text, it.Label); ok {
+			it.Score, it.Matches = score, matches
+			matched = append(matched, it)
+		}
+	}
+	return matched
+}
+
+// PrefixCompletions returns the items whose label starts with the text
+// between their start and the active cursor, in the same order
+func (b *Buffer) PrefixCompletions(items []*CompletionItem) []*CompletionItem {
+	c := b.GetActiveCursor()
+	line := []rune(string(b.LineBytes(c.Y)))
+	var matched []*CompletionItem
+	for _, it := range items {
+		if it.Start > c.X || c.X > len(line) {
+			continue
+		}
+		if strings.HasPrefix(it.Label, string(line[it.Start:c.X])) {
+			matched = append(matched, it)
+		}
+	}
+	return matched
+}
+
+// AcceptCompletion replaces the text being completed with the given item
+func (b *Buffer) AcceptCompletion(it *CompletionItem) {
+	c := b.GetActiveCursor()
+	start := Loc{it.Start, c.Y}
+	insert := it.Insert
+	if insert == "" {
+		insert = it.Label
+	}
+	if it.Snippet {
+		b.ExpandSnippet(start, c.Loc, insert)
+		return
+	}
+	b.Replace(start, c.Loc, insert)
+}
+
+// words returns the words of the buffer, each once in the order of their
+// first appearance. They are found again only when the text has changed.
+func (b *SharedBuffer) words() []string {
+	if b.wordList != nil && b.wordListVersion == b.version {
+		return b.wordList
+	}
+	b.wordList = []string{}
+	b.wordListVersion = b.version
+	seen := make(map[string]bool)
+	for i := 0; i < b.LinesNum(); i++ {
+		for _, w := range bytes.FieldsFunc(b.LineBytes(i), util.IsNonWordChar) {
+			if s := string(w); !seen[s] {
+				seen[s] = true
+				b.wordList = append(b.wordList, s)
+			}
+		}
+	}
+	return b.wordList
+}
+
+// wordItems are the words of the open buffers, starting with the current
+// one. The word being completed is not suggested since it is the text being
+// completed.
+func wordItems(b *Buffer, word string) []*CompletionItem {
+	if word == "" {
+		return nil
+	}
+	var items []*CompletionItem
+	seen := make(map[string]bool)
+	first := []rune(word)[0]
+	add := func(buf *Buffer, detail string) {
+		for _, s := range buf.words() {
+			// the words which don't contain the first character of the
+			// word being completed cannot match it
+			if !strings.ContainsRune(strings.ToLower(s), first) && !strings.ContainsRune(s, first) {
+				continue
+			}
+			if !seen[s] && s != word {
+				seen[s] = true
+				items = append(items, &CompletionItem{Label: s, Kind: CKWord, Detail: detail, Start: -1})
+			}
+		}
+	}
+	add(b, "")
+	for _, buf := range OpenBuffers {
+		if buf.SharedBuffer != b.SharedBuffer && buf.Type != BTInfo {
+			add(buf, buf.GetName())
+		}
+	}
+	return items
+}
+
+// keywordItems are the keywords of the syntax definition of the buffer
+func keywordItems(b *Buffer, word string) []*CompletionItem {
+	if word == "" || b.SyntaxDef == nil {
+		return nil
+	}
+	var items []*CompletionItem
+	for k, group := range b.SyntaxDef.Keywords() {
+		items = append(items, &CompletionItem{Label: k, Kind: CKKeyword, Detail: group, Start: -1})
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Label < items[j].Label
+	})
+	return items
+}
+
+// snippetItems are the snippets of the filetype of the buffer
+func snippetItems(b *Buffer, word string) []*CompletionItem {
+	if word == "" {
+		return nil
+	}
+	snippets, err := config.ListSnippets(b.FileType())
+	if err != nil {
+		return nil
+	}
+	var items []*CompletionItem
+	for _, s := range snippets {
+		doc := s.Body
+		if s.Description != "" {
+			doc = s.Description + "\n\n" + doc
+		}
+		for _, p := range s.Prefixes {
+			items = append(items, &CompletionItem{
+				Label:   p,
+				Insert:  s.Body,
+				Snippet: true,
+				Kind:    CKSnippet,
+				Detail:  s.Name,
+				Doc:     doc,
+				Start:   -1,
+			})
+		}
+	}
+	return items
+}
+
+// fileItems are the files of the directory of the path before the cursor,
+// which contains a path separator
+func fileItems(b *Buffer, word string) []*CompletionItem {
+	c := b.GetActiveCursor()
+	line := []rune(string(b.LineBytes(c.Y)))
+	start := c.X
+	for start > 0 && !util.IsWhitespace(line[start-1]) && !strings.ContainsRune("\"'`()[]{}<>=,;", line[start-1]) {
+		start--
+	}
+	path := string(line[start:c.X])
+	sep := strings.LastIndexAny(path, "/"+string(os.PathSeparator))
+	if sep < 0 {
+		return nil
+	}
+
+	dir, _ := util.ReplaceHome(path[:sep+1])
+	if !filepath.IsAbs(dir) && b.AbsPath != "" {
+		dir = filepath.Join(filepath.Dir(b.AbsPath), dir)
+	}
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var items []*CompletionItem
+	for _, f := range files {
+		name := f.Name()
+		if f.IsDir() {
+			name += "/"
+		}
+		items = append(items, &CompletionItem{
+			Label: name,
+			Kind:  CKFile,
+			Start: start + util.CharacterCountInString(path[:sep+1]),
+		})
+	}
+	return items
+}
@@ -0,0 +1,138 @@
+package buffer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zyedidia/micro/v2/internal/config"
+	"github.com/zyedidia/micro/v2/pkg/highlight"
+)
+
+func TestCompletionItems(t *testing.T) {
+	b := NewBufferFromString("fooBar foobaz fb\nfoo", "", BTDefault)
+	defer b.Close()
+
+	c := b.GetActiveCursor()
+	c.Loc = Loc{7, 0}
+	assert.Nil(t, b.CompletionItems())
+
+	c.Loc = Loc{16, 0}
+	items := b.CompletionItems()
+	assert.Equal(t, 2, len(items))
+	// the camel case boundary makes fooBar the best match
+	assert.Equal(t, "fooBar", items[0].Label)
+	assert.Equal(t, []int{0, 3}, items[0].Matches)
+	assert.Equal(t, CKWord, items[0].Kind)
+	assert.Equal(t, 14, items[0].Start)
+
+	b.AcceptCompletion(items[1])
+	assert.Equal(t, "fooBar foobaz foobaz\nfoo", string(b.Bytes()))
+	assert.Equal(t, Loc{20, 0}, c.Loc)
+
+	// an exact match is not suggested
+	c.Loc = Loc{3, 1}
+	items = b.CompletionItems()
+	assert.Equal(t, []string{"fooBar", "foobaz"}, []string{items[0].Label, items[1].Label})
+	b.Insert(c.Loc, "ba")
+	assert.Equal(t, 2, len(b.FilterCompletions(items)))
+	b.Insert(c.Loc, "z")
+	assert.Equal(t, 0, len(b.FilterCompletions(items)))
+}
+
+func TestCompletionSource(t *testing.T) {
+	RegisterCompletionSource(&CompletionSource{
+		Name:     "test",
+		Priority: 0,
+		Complete: func(b *Buffer, word string) []*CompletionItem {
+			return []*CompletionItem{
+				{Label: "print", Kind: "function", Start: -1},
+				{Label: "fmt.Println", Insert: "fmt.Println($1)", Snippet: true, Start: 0},
+			}
+		},
+	})
+	defer UnregisterCompletionSource("test")
+
+	b := NewBufferFromString("pr", "", BTDefault)
+	defer b.Close()
+	b.GetActiveCursor().Loc = Loc{2, 0}
+	items := b.CompletionItems()
+	assert.Equal(t, 2, len(items))
+	assert.Equal(t, "print", items[0].Label)
+	assert.Equal(t, "test", items[0].Source)
+
+	b.AcceptCompletion(items[1])
+	assert.Equal(t, "fmt.Println()", string(b.Bytes()))
+	assert.Equal(t, Loc{12, 0}, b.GetActiveCursor().Loc)
+}
+
+func TestKeywords(t *testing.T) {
+	data, err := config.FindRuntimeFile(config.RTSyntax, "go").Data()
+	assert.NoError(t, err)
+	f, err := highlight.ParseFile(data)
+	assert.NoError(t, err)
+	def, err := highlight.ParseDef(f, &highlight.Header{FileType: "go"})
+	assert.NoError(t, err)
+
+	keywords := def.Keywords()
+	assert.Equal(t, "preproc", keywords["func"])
+	assert.Equal(t, "special", keywords["return"])
+	assert.NotContains(t, keywords, "b")
+}
+
+func TestCompleterSource(t *testing.T) {
+	RegisterCompletionSource(Completer(BufferComplete).Source("test", 0, "test"))
+	defer UnregisterCompletionSource("test")
+
+	b := NewBufferFromString("fooBar foobaz xfoo fo", "", BTDefault)
+	defer b.Close()
+	b.GetActiveCursor().Loc = Loc{21, 0}
+	items := b.CompletionItems()
+	assert.Equal(t, 3, len(items))
+	assert.Equal(t, "test", items[0].Source)
+	assert.Equal(t, "test", items[0].Kind)
+	assert.Equal(t, 19, items[0].Start)
+
+	// the fuzzy match xfoo is not a prefix match
+	assert.Equal(t, []string{"fooBar", "foobaz"}, labels(b.PrefixCompletions(items)))
+}
+
+func TestAutocomplete(t *testing.T) {
+	b := NewBufferFromString("fooBar foobaz fb fo", "", BTDefault)
+	defer b.Close()
+	c := b.GetActiveCursor()
+
+	c.Loc = Loc{16, 0}
+	assert.False(t, b.Autocomplete(BufferComplete))
+
+	c.Loc = Loc{19, 0}
+	assert.True(t, b.Autocomplete(BufferComplete))
+	assert.Equal(t, []string{"fooBar", "foobaz", "fo"}, b.Suggestions)
+	assert.Equal(t, []string{"oBar", "obaz", ""}, b.Completions)
+	assert.Equal(t, "fooBar foobaz fb fooBar", string(b.Bytes()))
+	b.CycleAutocomplete(true)
+	assert.Equal(t, "fooBar foobaz fb foobaz", string(b.Bytes()))
+
+	// the completions of a completer are inserted as they are, even if
+	// they don't complete the suggestions
+	assert.True(t, b.Autocomplete(func(*Buffer) ([]string, []string) {
+		return []string{"!"}, []string{"bang"}
+	}))
+	assert.Equal(t, "fooBar foobaz fb foobaz!", string(b.Bytes()))
+}
+
+func TestWordItemsChanges(t *testing.T) {
+	b := NewBufferFromString("foo", "", BTDefault)
+	defer b.Close()
+	assert.Equal(t, []string{"foo"}, b.words())
+	b.Insert(b.End(), " fooBar")
+	assert.Equal(t, []string{"foo", "fooBar"}, b.words())
+	assert.Equal(t, []string{"fooBar"}, labels(wordItems(b, "foo")))
+}
+
+func labels(items []*CompletionItem) []string {
+	var l []string
+	for _, it := range items {
+		l = append(l, it.Label)
+	}
+	return l
+}
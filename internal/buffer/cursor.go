@@ -104,6 +104,13 @@ func (c *Cursor) GetCharPosInLine(b []byte, visualPos int) int {
 	return util.GetCharPosInLine(b, visualPos, tabsize)
 }
 
+// LineCharPos is like GetCharPosInLine for the given line of the buffer,
+// whose inline virtual text takes visual spaces too
+func (c *Cursor) LineCharPos(lineN, visualPos int) int {
+	tabsize := int(c.buf.Settings["tabsize"].(float64))
+	return CharPosInLine(c.buf.LineBytes(lineN), visualPos, tabsize, c.buf.InlineWidths(lineN))
+}
+
 // Start moves the cursor to the start of the line it is on
 func (c *Cursor) Start() {
 	c.X = 0
@@ -253,7 +260,7 @@ func (c *Cursor) UpN(amount int) {
 	}
 
 	bytes := c.buf.LineBytes(proposedY)
-	c.X = c.GetCharPosInLine(bytes, c.LastVisualX)
+	c.X = c.LineCharPos(proposedY, c.LastVisualX)
 
 	if c.X > util.CharacterCount(bytes) || (amount < 0 && proposedY == c.Y) {
 		c.X = util.CharacterCount(bytes)
@@ -621,7 +628,10 @@ func (c *Cursor) RuneUnder(x int) rune {
 	return '\n'
 }
 
+// StoreVisualX stores the visual x of the cursor, where it goes back when
+// moving up and down. Like LineCharPos, it includes the width of the inline
+// virtual text before the cursor.
 func (c *Cursor) StoreVisualX() {
-	c.LastVisualX = c.GetVisualX(false)
+	c.LastVisualX = c.GetVisualX(false) + InlineWidthBefore(c.buf.InlineWidths(c.Y), c.X)
 	c.LastWrappedVisualX = c.GetVisualX(true)
 }
@@ -0,0 +1,349 @@
+package buffer
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/zyedidia/glob"
+	"github.com/zyedidia/micro/v2/internal/config"
+	"github.com/zyedidia/micro/v2/internal/util"
+)
+
+// modelineLines is the number of lines at the start and at the end of
+// a file which are searched for modelines
+const modelineLines = 5
+
+var (
+	vimModelineRegex   = regexp.MustCompile(`(?:^|\s)(?:vi|vim|ex)(?:[<=>]?\d+)?:\s*(.*)$`)
+	emacsModelineRegex = regexp.MustCompile(`-\*-\s*(.*?)\s*-\*-`)
+)
+
+// modelineFileTypes maps the filetype names used by Vim and Emacs to the
+// names used by micro's syntax files
+var modelineFileTypes = map[string]string{
+	"bash":         "shell",
+	"sh":           "shell",
+	"shell-script": "shell",
+	"cpp":          "c++",
+	"cs":           "csharp",
+	"make":         "makefile",
+	"objc":         "objective-c",
+	"plaintex":     "tex",
+	"latex":        "tex",
+	"dosini":       "ini",
+	"gitcommit":    "git-commit",
+	"gitconfig":    "git-config",
+	"gitrebase":    "git-rebase-todo",
+	"python3":      "python",
+	"ps1":          "powershell",
+	"js":           "javascript",
+	"js2":          "javascript",
+	"emacs-lisp":   "lisp",
+	"elisp":        "lisp",
+	"ts":           "typescript",
+}
+
+// shebangFileTypes maps interpreters found in a shebang line to filetypes
+var shebangFileTypes = map[string]string{
+	"ash":        "shell",
+	"bash":       "shell",
+	"dash":       "shell",
+	"ksh":        "shell",
+	"mksh":       "shell",
+	"sh":         "shell",
+	"zsh":        "zsh",
+	"fish":       "fish",
+	"nu":         "nu",
+	"python":     "python",
+	"python2":    "python2",
+	"pypy":       "python",
+	"ruby":       "ruby",
+	"perl":       "perl",
+	"raku":       "raku",
+	"lua":        "lua",
+	"luajit":     "lua",
+	"node":       "javascript",
+	"nodejs":     "javascript",
+	"deno":       "typescript",
+	"ts-node":    "typescript",
+	"php":        "php",
+	"tclsh":      "tcl",
+	"wish":       "tcl",
+	"awk":        "awk",
+	"gawk":       "awk",
+	"mawk":       "awk",
+	"nawk":       "awk",
+	"sed":        "sed",
+	"make":       "makefile",
+	"Rscript":    "r",
+	"julia":      "julia",
+	"crystal":    "crystal",
+	"elixir":     "elixir",
+	"escript":    "erlang",
+	"groovy":     "groovy",
+	"octave":     "octave",
+	"gnuplot":    "gnuplot",
+	"runghc":     "haskell",
+	"runhaskell": "haskell",
+	"scala":      "scala",
+	"swift":      "swift",
+	"dart":       "dart",
+	"pwsh":       "powershell",
+}
+
+// A modeline holds the settings found in a Vim or Emacs modeline
+type modeline struct {
+	filetype string
+	settings map[string]interface{}
+}
+
+// parseVimModeline parses the options of a Vim modeline such as
+// `vim: set ft=python ts=4 et :` or `vim: ft=python:ts=4`
+func parseVimModeline(opts string, m *modeline) {
+	var fields []string
+	if strings.HasPrefix(opts, "set ") || strings.HasPrefix(opts, "se ") {
+		opts = opts[strings.Index(opts, " ")+1:]
+		if i := strings.Index(opts, ":"); i >= 0 {
+			opts = opts[:i]
+		}
+		fields = strings.Fields(opts)
+	} else {
+		fields = strings.FieldsFunc(opts, func(r rune) bool {
+			return r == ':' || r == ' ' || r == '\t'
+		})
+	}
+
+	tabstop, shiftwidth := 0, 0
+	expandtab := false
+	for _, f := range fields {
+		key, value, _ := strings.Cut(f, "=")
+		switch key {
+		case "ft", "filetype", "syn", "syntax":
+			m.filetype = value
+		case "ts", "tabstop":
+			tabstop, _ = strconv.Atoi(value)
+		case "sw", "shiftwidth":
+			shiftwidth, _ = strconv.Atoi(value)
+		case "et", "expandtab":
+			expandtab = true
+			m.settings["tabstospaces"] = true
+		case "noet", "noexpandtab":
+			m.settings["tabstospaces"] = false
+		case "ff", "fileformat":
+			m.settings["fileformat"] = value
+		}
+	}
+
+	// with expandtab the indentation width is given by shiftwidth
+	if expandtab && shiftwidth > 0 {
+		m.settings["tabsize"] = float64(shiftwidth)
+	} else if tabstop > 0 {
+		m.settings["tabsize"] = float64(tabstop)
+	}
+}
+
+// parseEmacsModeline parses the variables of an Emacs modeline such as
+// `-*- mode: python; tab-width: 4; indent-tabs-mode: nil -*-` or `-*- c -*-`
+func parseEmacsModeline(vars string, m *modeline) {
+	if !strings.Contains(vars, ":") {
+		m.filetype = strings.ToLower(vars)
+		return
+	}
+
+	for _, v := range strings.Split(vars, ";") {
+		key, value, ok := strings.Cut(v, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		switch key {
+		case "mode":
+			m.filetype = strings.ToLower(value)
+		case "tab-width":
+			if n, err := strconv.Atoi(value); err == nil && n > 0 {
+				m.settings["tabsize"] = float64(n)
+			}
+		case "indent-tabs-mode":
+			m.settings["tabstospaces"] = value == "nil"
+		}
+	}
+}
+
+// findModeline searches the start and the end of the buffer for a Vim or
+// Emacs modeline and returns the settings found in it, or nil if there is
+// no modeline or modelines are disabled
+func (b *Buffer) findModeline() *modeline {
+	if !b.Settings["modeline"].(bool) {
+		return nil
+	}
+
+	m := &modeline{settings: make(map[string]interface{})}
+	found := false
+	check := func(lineN int) {
+		line := string(b.LineBytes(lineN))
+		if match := emacsModelineRegex.FindStringSubmatch(line); match != nil {
+			parseEmacsModeline(match[1], m)
+			found = true
+		} else if match := vimModelineRegex.FindStringSubmatch(line); match != nil {
+			parseVimModeline(match[1], m)
+			found = true
+		}
+	}
+
+	n := b.LinesNum()
+	for i := 0; i < n && i < modelineLines; i++ {
+		check(i)
+	}
+	for i := util.Max(modelineLines, n-modelineLines); i < n; i++ {
+		check(i)
+	}
+
+	if !found {
+		return nil
+	}
+	if ft, ok := modelineFileTypes[m.filetype]; ok {
+		m.filetype = ft
+	}
+	for k, v := range m.settings {
+		if config.OptionIsValid(k, v) != nil {
+			delete(m.settings, k)
+		}
+	}
+	return m
+}
+
+// applyModeline sets the options found in the buffer's modeline (other
+// than the filetype) as local options of the buffer
+func (b *Buffer) applyModeline() {
+	m := b.findModeline()
+	if m == nil {
+		return
+	}
+	for k, v := range m.settings {
+		b.DoSetOptionNative(k, v)
+		b.LocalSettings[k] = true
+		b.setOrigin(k, "modeline")
+	}
+}
+
+// shebangInterpreter returns the name of the interpreter given in a
+// shebang line like `#!/bin/sh` or `#!/usr/bin/env python3`
+func shebangInterpreter(line []byte) string {
+	if len(line) < 2 || line[0] != '#' || line[1] != '!' {
+		return ""
+	}
+	fields := strings.Fields(string(line[2:]))
+	if len(fields) == 0 {
+		return ""
+	}
+
+	interp := path.Base(fields[0])
+	if interp == "env" {
+		interp = ""
+		for _, f := range fields[1:] {
+			// skip env's flags and variable assignments
+			if strings.HasPrefix(f, "-") || strings.Contains(f, "=") {
+				continue
+			}
+			interp = path.Base(f)
+			break
+		}
+	}
+	return interp
+}
+
+// shebangFileType returns the filetype for a shebang interpreter, looking
+// first in the user's "filetypes" map and then in the built-in one. Version
+// numbers are ignored if the exact name is not known (python3.11 -> python).
+func shebangFileType(interp string, user map[string]string) string {
+	for _, name := range []string{interp, strings.TrimRight(interp, "0123456789.")} {
+		if ft, ok := user["#!"+name]; ok {
+			return ft
+		}
+		if ft, ok := shebangFileTypes[name]; ok {
+			return ft
+		}
+	}
+	return ""
+}
+
+// userFileType returns the filetype assigned to the buffer's path by the
+// "filetypes" map in settings.json, along with the matching pattern.
+// Patterns are matched against the file name and the absolute path, and
+// the longest matching pattern wins.
+func (b *Buffer) userFileType(user map[string]string) (string, string) {
+	var patterns []string
+	for p := range user {
+		if !strings.HasPrefix(p, "#!") {
+			patterns = append(patterns, p)
+		}
+	}
+	sort.Slice(patterns, func(i, j int) bool {
+		if len(patterns[i]) != len(patterns[j]) {
+			return len(patterns[i]) > len(patterns[j])
+		}
+		return patterns[i] < patterns[j]
+	})
+
+	base := filepath.Base(b.Path)
+	for _, p := range patterns {
+		g, err := glob.Compile(p)
+		if err != nil {
+			continue
+		}
+		if g.MatchString(base) || g.MatchString(b.AbsPath) {
+			return user[p], p
+		}
+	}
+	return "", ""
+}
+
+// detectFileType tries to determine the buffer's filetype from explicit
+// hints, before the filename and header rules of the syntax files are
+// tried. In order of priority these are: a modeline, the "filetypes" map
+// in settings.json and the interpreter named by a shebang line. It returns
+// the filetype and a description of why it was chosen, or empty strings.
+func (b *Buffer) detectFileType() (string, string) {
+	if m := b.findModeline(); m != nil && m.filetype != "" {
+		return m.filetype, "set by a modeline"
+	}
+
+	user := config.FileTypeMap()
+	if b.Path != "" {
+		if ft, p := b.userFileType(user); ft != "" {
+			return ft, fmt.Sprintf("filename matches \"%s\" in the filetypes setting", p)
+		}
+	}
+
+	if b.LinesNum() > 0 {
+		if interp := shebangInterpreter(b.LineBytes(0)); interp != "" {
+			if ft := shebangFileType(interp, user); ft != "" {
+				return ft, fmt.Sprintf("shebang runs %s", interp)
+			}
+		}
+	}
+
+	return "", ""
+}
+
+// FileTypeReason returns a description of why the current filetype was
+// chosen for this buffer
+func (b *Buffer) FileTypeReason() string {
+	if b.ftReason == "" {
+		return "set by the filetype option"
+	}
+	return b.ftReason
+}
+
+// DetectFileType discards the current filetype and detects it again
+func (b *Buffer) DetectFileType() {
+	delete(b.LocalSettings, "filetype")
+	b.ftReason = ""
+	b.ReloadSettings(true)
+}
@@ -0,0 +1,73 @@
+package buffer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zyedidia/micro/v2/internal/config"
+)
+
+func TestShebangInterpreter(t *testing.T) {
+	assert.Equal(t, "sh", shebangInterpreter([]byte("#!/bin/sh")))
+	assert.Equal(t, "python3", shebangInterpreter([]byte("#!/usr/bin/env python3")))
+	assert.Equal(t, "node", shebangInterpreter([]byte("#!/usr/bin/env -S NODE_ENV=1 node --flag")))
+	assert.Equal(t, "", shebangInterpreter([]byte("# not a shebang")))
+
+	assert.Equal(t, "python", shebangFileType("python3.11", nil))
+	assert.Equal(t, "python2", shebangFileType("python2", nil))
+	assert.Equal(t, "typescript", shebangFileType("bun", map[string]string{"#!bun": "typescript"}))
+	assert.Equal(t, "", shebangFileType("unknown-interp", nil))
+}
+
+func TestModeline(t *testing.T) {
+	b := NewBufferFromString("#!/bin/sh\n# vim: set ft=python ts=8 sw=2 et :\n", "", BTDefault)
+	defer b.Close()
+
+	assert.Equal(t, "python", b.FileType())
+	assert.Equal(t, "set by a modeline", b.FileTypeReason())
+	assert.Equal(t, float64(2), b.Settings["tabsize"])
+	assert.Equal(t, true, b.Settings["tabstospaces"])
+
+	e := NewBufferFromString("/* -*- mode: c++; tab-width: 3; indent-tabs-mode: t -*- */\n", "", BTDefault)
+	defer e.Close()
+
+	assert.Equal(t, "c++", e.FileType())
+	assert.Equal(t, float64(3), e.Settings["tabsize"])
+	assert.Equal(t, false, e.Settings["tabstospaces"])
+}
+
+func TestShebangDetection(t *testing.T) {
+	b := NewBufferFromString("#!/usr/bin/env python3\nprint(1)\n", "script", BTDefault)
+	defer b.Close()
+
+	assert.Equal(t, "python", b.FileType())
+	assert.Equal(t, "shebang runs python3", b.FileTypeReason())
+}
+
+func TestSyntaxFileRanking(t *testing.T) {
+	defer config.InitRuntimeFiles(false)
+	syntax := func(name, ft, detect string) {
+		config.PluginAddRuntimeFileFromMemory(config.RTSyntax, name+".yaml",
+			"filetype: "+ft+"\ndetect:\n"+detect+"\nrules:\n    - comment: \"#.*$\"\n")
+	}
+	syntax("text", "text", `    filename: "\\.txt$"`)
+	syntax("cmake", "cmake", `    filename: "CMakeLists\\.txt$"`)
+	syntax("notes", "notes", `    filename: "\\.txt$"`+"\n"+`    header: "^NOTES"`)
+
+	// the longest filename match ranks first, whatever the order of the
+	// syntax files
+	b := NewBufferFromString("", "CMakeLists.txt", BTDefault)
+	defer b.Close()
+	assert.Equal(t, "cmake", b.FileType())
+	assert.Equal(t, "filename matches the rules of cmake.yaml, notes.yaml, text.yaml, picked cmake.yaml since its filename rule matches a longer part of the path", b.FileTypeReason())
+
+	b = NewBufferFromString("NOTES\n", "todo.txt", BTDefault)
+	defer b.Close()
+	assert.Equal(t, "notes", b.FileType())
+	assert.Contains(t, b.FileTypeReason(), "picked notes.yaml since it also matches the first line")
+
+	b = NewBufferFromString("", "list.txt", BTDefault)
+	defer b.Close()
+	assert.Equal(t, "notes", b.FileType())
+	assert.Contains(t, b.FileTypeReason(), "picked notes.yaml since its name comes first")
+}
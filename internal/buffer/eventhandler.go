@@ -112,20 +112,56 @@ func (eh *EventHandler) DoTextEvent(t *TextEvent, useUndo bool) {
 	}
 }
 
-// ExecuteTextEvent runs a text event
+// insertEnd returns the end of the given text inserted at start
+func insertEnd(start Loc, text []byte) Loc {
+	lastnl := bytes.LastIndex(text, []byte{'\n'})
+	if lastnl < 0 {
+		return Loc{start.X + util.CharacterCount(text), start.Y}
+	}
+	return Loc{util.CharacterCount(text[lastnl+1:]), start.Y + bytes.Count(text, []byte{'\n'})}
+}
+
+// shiftReplacedMarks shifts the marks after the text old at start was
+// replaced by new. Only the characters which differ are considered
+// replaced, so that the marks on a line which is replaced as a whole by
+// replaceall are kept where they are not changed.
+func (b *SharedBuffer) shiftReplacedMarks(start Loc, old, new []byte) {
+	if !b.hasMarks() {
+		return
+	}
+	loc := start
+	for _, d := range dmp.New().DiffMain(string(old), string(new), false) {
+		end := insertEnd(loc, []byte(d.Text))
+		switch d.Type {
+		case dmp.DiffEqual:
+			loc = end
+		case dmp.DiffDelete:
+			b.shiftMarks(loc, end, false)
+		case dmp.DiffInsert:
+			b.shiftMarks(loc, end, true)
+			loc = end
+		}
+	}
+}
+
+// ExecuteTextEvent runs a text event. The marks attached to the buffer
+// are shifted after each delta, in the order the deltas are applied.
 func ExecuteTextEvent(t *TextEvent, buf *SharedBuffer) {
 	if t.EventType == TextEventInsert {
 		for _, d := range t.Deltas {
 			buf.insert(d.Start, d.Text)
+			buf.shiftMarks(d.Start, insertEnd(d.Start, d.Text), true)
 		}
 	} else if t.EventType == TextEventRemove {
 		for i, d := range t.Deltas {
 			t.Deltas[i].Text = buf.remove(d.Start, d.End)
+			buf.shiftMarks(d.Start, d.End, false)
 		}
 	} else if t.EventType == TextEventReplace {
 		for i, d := range t.Deltas {
 			t.Deltas[i].Text = buf.remove(d.Start, d.End)
 			buf.insert(d.Start, d.Text)
+			buf.shiftReplacedMarks(d.Start, t.Deltas[i].Text, d.Text)
 			t.Deltas[i].Start = d.Start
 			t.Deltas[i].End = Loc{d.Start.X + util.CharacterCount(d.Text), d.Start.Y}
 		}
@@ -0,0 +1,269 @@
+package buffer
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+
+	"github.com/micro-editor/tcell/v2"
+	"github.com/zyedidia/micro/v2/internal/config"
+	"github.com/zyedidia/micro/v2/pkg/highlight"
+)
+
+// ExportOptions controls what is added to the text of a buffer when it is
+// exported with ExportHTML or ExportANSI
+type ExportOptions struct {
+	// LineNumbers adds a line number in front of each line
+	LineNumbers bool
+	// DiffGutter adds the diff markers of the diffgutter in front of each line
+	DiffGutter bool
+}
+
+// An exportSpan is a piece of a line drawn with a single style
+type exportSpan struct {
+	text  string
+	style tcell.Style
+}
+
+// exportLine is a line of exported text with its gutter
+type exportLine struct {
+	gutter []exportSpan
+	spans  []exportSpan
+}
+
+// exportLines splits the text between start and end into spans of equal
+// style, using the buffer's syntax definition and the current colorscheme
+func (b *Buffer) exportLines(start, end Loc, opts ExportOptions) []exportLine {
+	if end.LessThan(start) {
+		start, end = end, start
+	}
+	if end.X == 0 && end.Y > start.Y {
+		// a selection of whole lines ends at the start of the next line
+		end = Loc{len([]rune(string(b.LineBytes(end.Y - 1)))), end.Y - 1}
+	}
+
+	// highlight the text with a separate highlighter so that the result does
+	// not depend on how far the buffer's own highlighting has progressed
+	var matches []highlight.LineMatch
+	if b.SyntaxDef != nil && b.Settings["syntax"].(bool) {
+		lines := make([]string, end.Y+1)
+		for i := range lines {
+			lines[i] = string(b.LineBytes(i))
+		}
+		matches = highlight.NewHighlighter(b.SyntaxDef).HighlightString(strings.Join(lines, "\n"))
+	}
+
+	numWidth := len(strconv.Itoa(end.Y + 1))
+	lineNumStyle := config.DefStyle
+	if s, ok := config.Colorscheme["line-number"]; ok {
+		lineNumStyle = s
+	}
+
+	var result []exportLine
+	curStyle := config.DefStyle
+	for y := 0; y <= end.Y; y++ {
+		runes := []rune(string(b.LineBytes(y)))
+		var match highlight.LineMatch
+		if y < len(matches) {
+			match = matches[y]
+		}
+
+		var line exportLine
+		var text []rune
+		style := curStyle
+		for x := 0; x <= len(runes); x++ {
+			if group, ok := match[x]; ok {
+				curStyle = config.GetColor(group.String())
+			}
+			if y < start.Y || x == len(runes) {
+				continue
+			}
+			if y == start.Y && x < start.X || y == end.Y && x >= end.X {
+				continue
+			}
+			if curStyle != style && len(text) > 0 {
+				line.spans = append(line.spans, exportSpan{string(text), style})
+				text = text[:0]
+			}
+			style = curStyle
+			text = append(text, runes[x])
+		}
+		if y < start.Y {
+			continue
+		}
+		if len(text) > 0 {
+			line.spans = append(line.spans, exportSpan{string(text), style})
+		}
+
+		if opts.DiffGutter {
+			line.gutter = append(line.gutter, b.exportDiffMarker(y, lineNumStyle))
+		}
+		if opts.LineNumbers {
+			num := fmt.Sprintf("%*d ", numWidth, y+1)
+			line.gutter = append(line.gutter, exportSpan{num, lineNumStyle})
+		}
+		result = append(result, line)
+	}
+	return result
+}
+
+// exportDiffMarker returns the diffgutter marker for a line
+func (b *Buffer) exportDiffMarker(lineN int, style tcell.Style) exportSpan {
+	symbol, styleName := " ", ""
+	switch b.DiffStatus(lineN) {
+	case DSAdded:
+		symbol, styleName = "▌", "diff-added"
+	case DSModified:
+		symbol, styleName = "▌", "diff-modified"
+	case DSDeletedAbove:
+		symbol, styleName = "▔", "diff-deleted"
+	}
+	if s, ok := config.Colorscheme[styleName]; ok {
+		fg, _, _ := s.Decompose()
+		style = style.Foreground(fg)
+	}
+	return exportSpan{symbol, style}
+}
+
+// cssColor returns the CSS value for a color, or an empty string for the
+// default color
+func cssColor(c tcell.Color) string {
+	if hex := c.Hex(); hex >= 0 {
+		return fmt.Sprintf("#%06x", hex)
+	}
+	return ""
+}
+
+// cssStyle returns the inline CSS for a style, leaving out the colors and
+// attributes it shares with the default style
+func cssStyle(style tcell.Style) string {
+	fg, bg, attr := style.Decompose()
+	defFg, defBg, _ := config.DefStyle.Decompose()
+	if attr&tcell.AttrReverse != 0 {
+		fg, bg = bg, fg
+		if fg == tcell.ColorDefault {
+			fg = defBg
+		}
+		if bg == tcell.ColorDefault {
+			bg = defFg
+		}
+	}
+
+	var css []string
+	if c := cssColor(fg); c != "" && fg != defFg {
+		css = append(css, "color:"+c)
+	}
+	if c := cssColor(bg); c != "" && bg != defBg {
+		css = append(css, "background-color:"+c)
+	}
+	if attr&tcell.AttrBold != 0 {
+		css = append(css, "font-weight:bold")
+	}
+	if attr&tcell.AttrItalic != 0 {
+		css = append(css, "font-style:italic")
+	}
+	if attr&tcell.AttrUnderline != 0 {
+		css = append(css, "text-decoration:underline")
+	}
+	return strings.Join(css, ";")
+}
+
+// ExportHTML returns the text between start and end as a standalone HTML
+// document, with the syntax highlighting of the current colorscheme given
+// as inline styles
+func (b *Buffer) ExportHTML(start, end Loc, opts ExportOptions) string {
+	buf := new(bytes.Buffer)
+	buf.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
+	fmt.Fprintf(buf, "<title>%s</title>\n</head>\n<body>\n", html.EscapeString(b.GetName()))
+
+	var body []string
+	fg, bg, _ := config.DefStyle.Decompose()
+	if c := cssColor(fg); c != "" {
+		body = append(body, "color:"+c)
+	}
+	if c := cssColor(bg); c != "" {
+		body = append(body, "background-color:"+c)
+	}
+	tabsize := int(b.Settings["tabsize"].(float64))
+	body = append(body, fmt.Sprintf("tab-size:%d", tabsize), "padding:0.5em")
+	fmt.Fprintf(buf, "<pre style=\"%s\">", strings.Join(body, ";"))
+
+	writeSpans := func(spans []exportSpan) {
+		for _, s := range spans {
+			text := html.EscapeString(s.text)
+			if css := cssStyle(s.style); css != "" {
+				fmt.Fprintf(buf, "<span style=\"%s\">%s</span>", css, text)
+			} else {
+				buf.WriteString(text)
+			}
+		}
+	}
+	for i, l := range b.exportLines(start, end, opts) {
+		if i > 0 {
+			buf.WriteByte('\n')
+		}
+		writeSpans(l.gutter)
+		writeSpans(l.spans)
+	}
+
+	buf.WriteString("</pre>\n</body>\n</html>\n")
+	return buf.String()
+}
+
+// ansiColor returns the SGR parameters which select a color, where base is
+// 30 for the foreground and 40 for the background
+func ansiColor(c tcell.Color, base int) string {
+	switch {
+	case c == tcell.ColorDefault:
+		return strconv.Itoa(base + 9)
+	case c.IsRGB():
+		r, g, b := c.RGB()
+		return fmt.Sprintf("%d;2;%d;%d;%d", base+8, r, g, b)
+	}
+	n := int(c - tcell.ColorValid)
+	switch {
+	case n < 8:
+		return strconv.Itoa(base + n)
+	case n < 16:
+		return strconv.Itoa(base + 60 + n - 8)
+	}
+	return fmt.Sprintf("%d;5;%d", base+8, n)
+}
+
+// ansiStyle returns the escape sequence which switches to a style
+func ansiStyle(style tcell.Style) string {
+	fg, bg, attr := style.Decompose()
+	sgr := []string{"0"}
+	if attr&tcell.AttrBold != 0 {
+		sgr = append(sgr, "1")
+	}
+	if attr&tcell.AttrItalic != 0 {
+		sgr = append(sgr, "3")
+	}
+	if attr&tcell.AttrUnderline != 0 {
+		sgr = append(sgr, "4")
+	}
+	if attr&tcell.AttrReverse != 0 {
+		sgr = append(sgr, "7")
+	}
+	sgr = append(sgr, ansiColor(fg, 30), ansiColor(bg, 40))
+	return "\x1b[" + strings.Join(sgr, ";") + "m"
+}
+
+// ExportANSI returns the text between start and end with the syntax
+// highlighting of the current colorscheme as ANSI escape sequences
+func (b *Buffer) ExportANSI(start, end Loc, opts ExportOptions) string {
+	buf := new(bytes.Buffer)
+	for _, l := range b.exportLines(start, end, opts) {
+		for _, s := range append(l.gutter, l.spans...) {
+			buf.WriteString(ansiStyle(s.style))
+			buf.WriteString(s.text)
+		}
+		// reset the style before the newline so that the background
+		// color does not extend to the end of the terminal line
+		buf.WriteString("\x1b[0m\n")
+	}
+	return buf.String()
+}
@@ -0,0 +1,28 @@
+package buffer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportHTML(t *testing.T) {
+	b := NewBufferFromString("a < b\nfoo\nbar\n", "", BTDefault)
+	defer b.Close()
+
+	out := b.ExportHTML(b.Start(), b.End(), ExportOptions{})
+	assert.True(t, strings.HasPrefix(out, "<!DOCTYPE html>"))
+	assert.Contains(t, out, ">a &lt; b\nfoo\nbar</pre>")
+
+	out = b.ExportHTML(Loc{1, 1}, Loc{0, 3}, ExportOptions{LineNumbers: true})
+	assert.Contains(t, out, ">2 oo\n3 bar</pre>")
+}
+
+func TestExportANSI(t *testing.T) {
+	b := NewBufferFromString("foo\nbar", "", BTDefault)
+	defer b.Close()
+
+	out := b.ExportANSI(Loc{1, 0}, Loc{2, 1}, ExportOptions{})
+	assert.Equal(t, "\x1b[0;39;49moo\x1b[0m\n\x1b[0;39;49mba\x1b[0m\n", out)
+}
@@ -0,0 +1,124 @@
+package buffer
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/zyedidia/micro/v2/internal/util"
+)
+
+// HasIndentRules returns whether the syntax definition of the buffer
+// declares indent or outdent regexes
+func (b *Buffer) HasIndentRules() bool {
+	return b.SyntaxDef != nil && (b.SyntaxDef.IndentRegex != nil || b.SyntaxDef.OutdentRegex != nil)
+}
+
+// indentWidth returns the number of columns taken by leading whitespace
+func indentWidth(ws []byte, tabsize int) int {
+	w := 0
+	for _, c := range ws {
+		if c == '\t' {
+			w += tabsize - w%tabsize
+		} else {
+			w++
+		}
+	}
+	return w
+}
+
+// indentForWidth returns the leading whitespace which indents a line by
+// the given number of columns, using tabs or spaces depending on the
+// tabstospaces option
+func (b *Buffer) indentForWidth(w int) string {
+	if b.Settings["tabstospaces"].(bool) {
+		return util.Spaces(w)
+	}
+	tabsize := util.IntOpt(b.Settings["tabsize"])
+	return strings.Repeat("\t", w/tabsize) + util.Spaces(w%tabsize)
+}
+
+// ExpectedIndent returns the leading whitespace that the given line should
+// have according to the indent and outdent regexes of the syntax
+// definition: the indentation of the previous non-blank line, one level
+// more if that line matches the indent regex and one level less if the
+// line itself matches the outdent regex
+func (b *Buffer) ExpectedIndent(lineN int) string {
+	tabsize := util.IntOpt(b.Settings["tabsize"])
+
+	w := 0
+	for y := lineN - 1; y >= 0; y-- {
+		prev := b.LineBytes(y)
+		if util.IsSpacesOrTabs(prev) {
+			continue
+		}
+		w = indentWidth(util.GetLeadingWhitespace(prev), tabsize)
+		if b.SyntaxDef.IndentRegex != nil && b.SyntaxDef.IndentRegex.Match(prev) {
+			w += tabsize
+		}
+		break
+	}
+
+	if b.SyntaxDef.OutdentRegex != nil && b.SyntaxDef.OutdentRegex.Match(b.LineBytes(lineN)) {
+		w = util.Max(w-tabsize, 0)
+	}
+
+	return b.indentForWidth(w)
+}
+
+// Reindent replaces the leading whitespace of a line by the indentation
+// given by ExpectedIndent. It returns false if the syntax definition has
+// no indentation rules.
+func (b *Buffer) Reindent(lineN int) bool {
+	if !b.HasIndentRules() {
+		return false
+	}
+
+	ws := util.GetLeadingWhitespace(b.LineBytes(lineN))
+	indent := b.ExpectedIndent(lineN)
+	if string(ws) != indent {
+		b.Replace(Loc{0, lineN}, Loc{util.CharacterCount(ws), lineN}, indent)
+	}
+	return true
+}
+
+// SignificantIndent returns whether the indentation is part of the syntax
+// of the buffer's language, as in Python
+func (b *Buffer) SignificantIndent() bool {
+	return b.SyntaxDef != nil && b.SyntaxDef.SignificantIndent
+}
+
+// ReindentLines reindents the non-blank lines between start and end
+// (inclusive). It returns false if the syntax definition has no
+// indentation rules, or if the indentation is significant since changing
+// it would change the meaning of the code.
+func (b *Buffer) ReindentLines(start, end int) bool {
+	if !b.HasIndentRules() || b.SignificantIndent() {
+		return false
+	}
+
+	for y := start; y <= end; y++ {
+		if !util.IsSpacesOrTabs(b.LineBytes(y)) {
+			b.Reindent(y)
+		}
+	}
+	return true
+}
+
+// ShouldReindent returns whether typing has just completed a token which
+// changes the indentation of the cursor's line, such as a closing brace
+// at the start of a line. A word is only complete once it is followed by
+// another character, or by the end of the line if eol is true (when a
+// newline is inserted), so that typing an identifier which starts with a
+// keyword such as `end` does not reindent the line. If eol is true, only a
+// word ending at the cursor is completed, the other tokens having already
+// been completed while typing them.
+func (b *Buffer) ShouldReindent(c *Cursor, eol bool) bool {
+	if b.SyntaxDef == nil || b.SyntaxDef.OutdentRegex == nil {
+		return false
+	}
+	before := util.SliceStart(b.LineBytes(c.Y), c.X)
+	if r, _ := utf8.DecodeLastRune(before); util.IsWordChar(r) != eol {
+		return false
+	}
+	return b.SyntaxDef.OutdentRegex.Match(before)
+}
@@ -0,0 +1,83 @@
+package buffer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zyedidia/micro/v2/pkg/highlight"
+)
+
+func setIndentRules(t *testing.T, b *Buffer, extra ...string) {
+	syntax := []byte(`filetype: test
+indent: "[{(]\\s*$"
+outdent: "^\\s*([})]|end\\b)"
+` + strings.Join(extra, "\n") + `
+rules: []
+`)
+	f, err := highlight.ParseFile(syntax)
+	assert.Nil(t, err)
+	b.SyntaxDef, err = highlight.ParseDef(f, &highlight.Header{FileType: "test"})
+	assert.Nil(t, err)
+}
+
+func TestReindent(t *testing.T) {
+	b := NewBufferFromString("func() {\nfoo(\n)\n\n      baz\n  }\n", "", BTDefault)
+	defer b.Close()
+
+	assert.False(t, b.Reindent(1))
+	setIndentRules(t, b)
+
+	b.ReindentLines(0, b.LinesNum()-1)
+	assert.Equal(t, "func() {\n\tfoo(\n\t)\n\n\tbaz\n}\n", string(b.Bytes()))
+
+	b.SetOptionNative("tabstospaces", true)
+	b.SetOptionNative("tabsize", float64(2))
+	b.ReindentLines(0, b.LinesNum()-1)
+	assert.Equal(t, "func() {\n  foo(\n  )\n\n  baz\n}\n", string(b.Bytes()))
+	assert.Equal(t, "  ", b.ExpectedIndent(2))
+}
+
+func TestShouldReindent(t *testing.T) {
+	b := NewBufferFromString("if {\n\t}", "", BTDefault)
+	defer b.Close()
+	setIndentRules(t, b)
+
+	c := b.GetActiveCursor()
+	c.GotoLoc(Loc{2, 1})
+	assert.True(t, b.ShouldReindent(c, false))
+	b.Reindent(1)
+	assert.Equal(t, "if {\n}", string(b.Bytes()))
+}
+
+func TestShouldReindentKeyword(t *testing.T) {
+	b := NewBufferFromString("if {\n\tend\n\tendpoint", "", BTDefault)
+	defer b.Close()
+	setIndentRules(t, b)
+
+	c := b.GetActiveCursor()
+	c.GotoLoc(Loc{4, 1})
+	assert.False(t, b.ShouldReindent(c, false))
+	assert.True(t, b.ShouldReindent(c, true))
+
+	c.GotoLoc(Loc{4, 2})
+	assert.False(t, b.ShouldReindent(c, false))
+	c.GotoLoc(Loc{9, 2})
+	assert.False(t, b.ShouldReindent(c, false))
+	assert.False(t, b.ShouldReindent(c, true))
+
+	b.Insert(Loc{4, 1}, " ")
+	c.GotoLoc(Loc{5, 1})
+	assert.True(t, b.ShouldReindent(c, false))
+	assert.False(t, b.ShouldReindent(c, true))
+}
+
+func TestReindentSignificant(t *testing.T) {
+	b := NewBufferFromString("if x:\n    a\nb\n", "", BTDefault)
+	defer b.Close()
+	setIndentRules(t, b, "significant-indent: true")
+
+	assert.True(t, b.SignificantIndent())
+	assert.False(t, b.ReindentLines(0, b.LinesNum()-1))
+	assert.Equal(t, "if x:\n    a\nb\n", string(b.Bytes()))
+}
@@ -385,7 +385,27 @@ func (la *LineArray) SearchMatch(b *Buffer, pos Loc) bool {
 		return false
 	}
 
-	lineN := pos.Y
+	for _, m := range la.searchMatches(b, pos.Y) {
+		if pos.X >= m[0] && pos.X < m[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// LineSearchMatch returns true if the line `lineN` contains a match of the
+// last search for the buffer `b`, using the same cache as SearchMatch.
+func (la *LineArray) LineSearchMatch(b *Buffer, lineN int) bool {
+	if b.LastSearch == "" {
+		return false
+	}
+	return len(la.searchMatches(b, lineN)) > 0
+}
+
+// searchMatches returns the start and end columns of the matches of the
+// last search for the buffer `b` in the given line, searching for them if
+// they are outdated
+func (la *LineArray) searchMatches(b *Buffer, lineN int) [][2]int {
 	if la.lines[lineN].search == nil {
 		la.lines[lineN].search = make(map[*Buffer]*searchState)
 	}
@@ -426,12 +446,7 @@ func (la *LineArray) SearchMatch(b *Buffer, pos Loc) bool {
 		s.done = true
 	}
 
-	for _, m := range s.match {
-		if pos.X >= m[0] && pos.X < m[1] {
-			return true
-		}
-	}
-	return false
+	return s.match
 }
 
 // invalidateSearchMatches marks search matches for the given line as outdated.
@@ -3,6 +3,7 @@ package buffer
 import (
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -59,3 +60,45 @@ func TestRemove(t *testing.T) {
 	bytes := la.Bytes()
 	assert.Equal(t, unicode_txt, string(bytes))
 }
+
+func TestLineSearchMatch(t *testing.T) {
+	b := NewBufferFromString("foo\nbar\nfoobar", "", BTDefault)
+	assert.False(t, b.LineSearchMatch(0))
+
+	b.LastSearch = "foo"
+	assert.True(t, b.LineSearchMatch(0))
+	assert.False(t, b.LineSearchMatch(1))
+	assert.True(t, b.LineSearchMatch(2))
+	assert.True(t, b.SearchMatch(Loc{2, 2}))
+	assert.False(t, b.SearchMatch(Loc{3, 2}))
+
+	b.LastSearch = "bar$"
+	b.LastSearchRegex = true
+	assert.False(t, b.LineSearchMatch(0))
+	assert.True(t, b.LineSearchMatch(1))
+	assert.True(t, b.LineSearchMatch(2))
+}
+
+func TestSearchMatchLines(t *testing.T) {
+	b := NewBufferFromString("foo\nbar\nfoobar", "", BTDefault)
+	assert.Nil(t, b.SearchMatchLines())
+
+	b.LastSearch = "foo"
+	assert.Equal(t, []int{0, 2}, b.SearchMatchLines())
+	b.Insert(Loc{0, 1}, "foo")
+	assert.Equal(t, []int{0, 1, 2}, b.SearchMatchLines())
+
+	// the lines of a large buffer are found in the background
+	b = NewBufferFromString(strings.Repeat("foo\r\nbar\r\n", 1000)+"bar", "", BTDefault)
+	b.LastSearch = "bar$"
+	b.LastSearchRegex = true
+	deadline := time.Now().Add(5 * time.Second)
+	var lines []int
+	for len(lines) == 0 && time.Now().Before(deadline) {
+		lines = b.SearchMatchLines()
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, 1001, len(lines))
+	assert.Equal(t, 1, lines[0])
+	assert.Equal(t, 2000, lines[1000])
+}
@@ -0,0 +1,161 @@
+package buffer
+
+import (
+	"sort"
+
+	"github.com/zyedidia/micro/v2/pkg/highlight"
+)
+
+// An Overlay is a highlighted span attached to a buffer by an external
+// source (a plugin, a language server, a parser...). Overlays are drawn
+// over the regular syntax highlighting and move along with the text
+// when it is edited.
+type Overlay struct {
+	// Start and End locations of the span (End is exclusive)
+	Start, End Loc
+	// The Group used to look up the style in the colorscheme
+	Group highlight.Group
+	// Overlays with a higher Priority are drawn over lower ones
+	Priority int
+	// The Owner of the overlay
+	Owner string
+}
+
+// NewOverlay creates a new overlay span highlighted with the given group
+func NewOverlay(owner string, start, end Loc, group string, priority int) *Overlay {
+	return &Overlay{
+		Start:    start,
+		End:      end,
+		Group:    highlight.GetGroup(group),
+		Priority: priority,
+		Owner:    owner,
+	}
+}
+
+// AddOverlay attaches an overlay to the buffer
+func (b *Buffer) AddOverlay(o *Overlay) {
+	if !o.Start.LessThan(o.End) {
+		return
+	}
+	b.Overlays = append(b.Overlays, o)
+	b.overlayLines = nil
+	// keep the overlays sorted so that the highest priority comes first
+	sort.SliceStable(b.Overlays, func(i, j int) bool {
+		return b.Overlays[i].Priority > b.Overlays[j].Priority
+	})
+}
+
+// ClearOverlays removes all overlays added by the given owner
+func (b *Buffer) ClearOverlays(owner string) {
+	overlays := b.Overlays[:0]
+	for _, o := range b.Overlays {
+		if o.Owner != owner {
+			overlays = append(overlays, o)
+		}
+	}
+	for i := len(overlays); i < len(b.Overlays); i++ {
+		b.Overlays[i] = nil
+	}
+	b.Overlays = overlays
+	b.overlayLines = nil
+}
+
+// ClearAllOverlays removes all overlays from the buffer
+func (b *Buffer) ClearAllOverlays() {
+	b.Overlays = make([]*Overlay, 0)
+	b.overlayLines = nil
+}
+
+// LineOverlays returns the overlays which intersect the given line,
+// ordered by decreasing priority
+func (b *Buffer) LineOverlays(lineN int) []*Overlay {
+	if len(b.Overlays) == 0 {
+		return nil
+	}
+	if b.overlayLines == nil {
+		// the overlays are added to the lines in the order of their
+		// priority
+		b.overlayLines = make(map[int][]*Overlay)
+		for _, o := range b.Overlays {
+			for y := o.Start.Y; y <= o.End.Y; y++ {
+				b.overlayLines[y] = append(b.overlayLines[y], o)
+			}
+		}
+	}
+	return b.overlayLines[lineN]
+}
+
+// OverlayAt returns the highest priority overlay covering the given location
+func (b *Buffer) OverlayAt(loc Loc) *Overlay {
+	for _, o := range b.LineOverlays(loc.Y) {
+		if loc.GreaterEqual(o.Start) && loc.LessThan(o.End) {
+			return o
+		}
+	}
+	return nil
+}
+
+// shiftLoc returns the new position of loc after the text between start
+// and end has been inserted or removed
+func shiftLoc(loc, start, end Loc, insert bool) Loc {
+	if insert {
+		if loc.LessThan(start) {
+			return loc
+		}
+		if loc.Y == start.Y {
+			loc.X += end.X - start.X
+		}
+		loc.Y += end.Y - start.Y
+		return loc
+	}
+
+	if loc.LessEqual(start) {
+		return loc
+	}
+	if loc.LessThan(end) {
+		return start
+	}
+	if loc.Y == end.Y {
+		loc.X += start.X - end.X
+	}
+	loc.Y -= end.Y - start.Y
+	return loc
+}
+
+// hasMarks returns true if the buffer has overlays, virtual text, messages
+// or snippet tabstops which are moved by the text events
+func (b *SharedBuffer) hasMarks() bool {
+	return len(b.Overlays) > 0 || len(b.VirtualText) > 0 || len(b.Messages) > 0 || b.snippet != nil
+}
+
+// shiftMarks moves the overlays, virtual text, messages and snippet tabstops attached to the buffer
+// after an insertion or a removal between start and end. Overlays which
+// were entirely removed are dropped.
+func (b *SharedBuffer) shiftMarks(start, end Loc, insert bool) {
+	overlays := b.Overlays[:0]
+	for _, o := range b.Overlays {
+		o.Start = shiftLoc(o.Start, start, end, insert)
+		o.End = shiftLoc(o.End, start, end, insert)
+		if o.Start.LessThan(o.End) {
+			overlays = append(overlays, o)
+		}
+	}
+	for i := len(overlays); i < len(b.Overlays); i++ {
+		b.Overlays[i] = nil
+	}
+	b.Overlays = overlays
+	b.overlayLines = nil
+
+	for _, vt := range b.VirtualText {
+		vt.Loc = shiftLoc(vt.Loc, start, end, insert)
+	}
+
+	for _, m := range b.Messages {
+		m.Start = shiftLoc(m.Start, start, end, insert)
+		m.End = shiftLoc(m.End, start, end, insert)
+	}
+
+	if b.snippet != nil {
+		b.snippet.shift(start, end, insert)
+	}
+}
@@ -0,0 +1,97 @@
+package buffer
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOverlayShift(t *testing.T) {
+	b := NewBufferFromString("foo bar\nbaz qux", "", BTDefault)
+	defer b.Close()
+
+	o := NewOverlay("test", Loc{4, 0}, Loc{7, 0}, "identifier", 0)
+	b.AddOverlay(o)
+
+	b.Insert(Loc{0, 0}, "x\n")
+	assert.Equal(t, Loc{4, 1}, o.Start)
+	assert.Equal(t, Loc{7, 1}, o.End)
+
+	b.Insert(Loc{2, 1}, "ab")
+	assert.Equal(t, Loc{6, 1}, o.Start)
+	assert.Equal(t, Loc{9, 1}, o.End)
+
+	b.Remove(Loc{1, 0}, Loc{0, 1})
+	assert.Equal(t, Loc{7, 0}, o.Start)
+	assert.Equal(t, Loc{10, 0}, o.End)
+	assert.Equal(t, o, b.OverlayAt(Loc{8, 0}))
+	assert.Nil(t, b.OverlayAt(Loc{10, 0}))
+
+	b.Remove(Loc{6, 0}, Loc{10, 0})
+	assert.Len(t, b.Overlays, 0)
+}
+
+func TestOverlayPriority(t *testing.T) {
+	b := NewBufferFromString("foo bar", "", BTDefault)
+	defer b.Close()
+
+	low := NewOverlay("a", Loc{0, 0}, Loc{7, 0}, "identifier", 0)
+	high := NewOverlay("b", Loc{2, 0}, Loc{5, 0}, "constant", 10)
+	b.AddOverlay(low)
+	b.AddOverlay(high)
+
+	assert.Equal(t, low, b.OverlayAt(Loc{0, 0}))
+	assert.Equal(t, high, b.OverlayAt(Loc{3, 0}))
+
+	b.ClearOverlays("b")
+	assert.Equal(t, low, b.OverlayAt(Loc{3, 0}))
+	assert.Len(t, b.LineOverlays(0), 1)
+}
+
+func TestOverlayShiftMultipleReplace(t *testing.T) {
+	b := NewBufferFromString("aa x aa\naa y", "", BTDefault)
+	defer b.Close()
+
+	x := NewOverlay("x", Loc{3, 0}, Loc{4, 0}, "identifier", 0)
+	y := NewOverlay("y", Loc{3, 1}, Loc{4, 1}, "identifier", 0)
+	b.AddOverlay(x)
+	b.AddOverlay(y)
+	m := NewMessage("test", "msg", Loc{3, 1}, Loc{4, 1}, MTError)
+	b.AddMessage(m)
+
+	b.ReplaceRegex(b.Start(), b.End(), regexp.MustCompile("aa"), []byte("b"), false)
+	assert.Equal(t, "b x b\nb y", string(b.Bytes()))
+	assert.Equal(t, Loc{2, 0}, x.Start)
+	assert.Equal(t, Loc{3, 0}, x.End)
+	assert.Equal(t, Loc{2, 1}, y.Start)
+	assert.Equal(t, Loc{3, 1}, y.End)
+	assert.Equal(t, Loc{2, 1}, m.Start)
+
+	b.Undo()
+	assert.Equal(t, "aa x aa\naa y", string(b.Bytes()))
+	assert.Equal(t, Loc{3, 0}, x.Start)
+	assert.Equal(t, Loc{3, 1}, y.Start)
+}
+
+func TestLineOverlays(t *testing.T) {
+	b := NewBufferFromString("a\nb\nc\nd", "", BTDefault)
+	defer b.Close()
+
+	long := NewOverlay("a", Loc{0, 0}, Loc{1, 2}, "identifier", 0)
+	short := NewOverlay("b", Loc{0, 1}, Loc{1, 1}, "constant", 10)
+	b.AddOverlay(long)
+	b.AddOverlay(short)
+	assert.Equal(t, []*Overlay{long}, b.LineOverlays(0))
+	assert.Equal(t, []*Overlay{short, long}, b.LineOverlays(1))
+	assert.Empty(t, b.LineOverlays(3))
+
+	// the index follows the overlays when the text changes
+	b.Insert(Loc{0, 0}, "\n")
+	assert.Empty(t, b.LineOverlays(0))
+	assert.Equal(t, []*Overlay{long}, b.LineOverlays(3))
+	assert.Equal(t, short, b.OverlayAt(Loc{0, 2}))
+
+	b.ClearOverlays("a")
+	assert.Empty(t, b.LineOverlays(3))
+}
@@ -227,8 +227,19 @@ func (b *Buffer) SaveAsWithSudo(filename string) error {
 	return b.saveToFile(filename, true, false)
 }
 
-func (b *Buffer) saveToFile(filename string, withSudo bool, autoSave bool) error {
-	var err error
+func (b *Buffer) saveToFile(filename string, withSudo bool, autoSave bool) (err error) {
+	defer func() {
+		data := map[string]interface{}{"buf": b, "path": filename, "autosave": autoSave}
+		event := config.EvBufferSaved
+		if err != nil {
+			event = config.EvBufferSaveFailed
+			data["error"] = err.Error()
+		}
+		if perr := config.Publish(event, data); perr != nil {
+			screen.TermMessage(perr)
+		}
+	}()
+
 	if b.Type.Readonly {
 		return errors.New("Cannot save readonly buffer")
 	}
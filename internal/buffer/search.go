@@ -1,9 +1,11 @@
 package buffer
 
 import (
+	"bytes"
 	"regexp"
 	"unicode/utf8"
 
+	"github.com/zyedidia/micro/v2/internal/screen"
 	"github.com/zyedidia/micro/v2/internal/util"
 )
 
@@ -138,6 +140,18 @@ func (b *Buffer) findAll(r *regexp.Regexp, start, end Loc) [][2]Loc {
 	return matches
 }
 
+// searchRegexp compiles the regexp of a search, which is ignoring case if
+// the ignorecase option is on
+func (b *Buffer) searchRegexp(s string, useRegex bool) (*regexp.Regexp, error) {
+	if !useRegex {
+		s = regexp.QuoteMeta(s)
+	}
+	if b.Settings["ignorecase"].(bool) {
+		return regexp.Compile("(?i)" + s)
+	}
+	return regexp.Compile(s)
+}
+
 // FindNext finds the next occurrence of a given string in the buffer
 // It returns the start and end location of the match (if found) and
 // a boolean indicating if it was found
@@ -147,19 +161,7 @@ func (b *Buffer) FindNext(s string, start, end, from Loc, down bool, useRegex bo
 		return [2]Loc{}, false, nil
 	}
 
-	var r *regexp.Regexp
-	var err error
-
-	if !useRegex {
-		s = regexp.QuoteMeta(s)
-	}
-
-	if b.Settings["ignorecase"].(bool) {
-		r, err = regexp.Compile("(?i)" + s)
-	} else {
-		r, err = regexp.Compile(s)
-	}
-
+	r, err := b.searchRegexp(s, useRegex)
 	if err != nil {
 		return [2]Loc{}, false, err
 	}
@@ -236,3 +238,74 @@ func (b *Buffer) ReplaceRegex(start, end Loc, search *regexp.Regexp, replace []b
 
 	return found, util.CharacterCount(b.LineBytes(end.Y)) - charsEnd
 }
+
+// searchLinesKey identifies the search and the version of the text that
+// the lines containing a match were found for
+type searchLinesKey struct {
+	search     string
+	useRegex   bool
+	ignorecase bool
+	version    int
+}
+
+// SearchMatchLines returns the lines containing a match of the last search,
+// in increasing order. In large buffers, they are found in the background
+// when the search or the text changes, the lines found before being
+// returned until the screen is redrawn with the new ones.
+func (b *Buffer) SearchMatchLines() []int {
+	if b.LastSearch == "" {
+		return nil
+	}
+	key := searchLinesKey{
+		search:     b.LastSearch,
+		useRegex:   b.LastSearchRegex,
+		ignorecase: b.Settings["ignorecase"].(bool),
+		version:    b.version,
+	}
+
+	b.searchLinesLock.Lock()
+	defer b.searchLinesLock.Unlock()
+	if key == b.searchLinesKey || b.searchLinesBusy {
+		return b.searchLines
+	}
+
+	n := b.LinesNum()
+	if n < 1000 {
+		var lines []int
+		for i := 0; i < n; i++ {
+			if b.LineSearchMatch(i) {
+				lines = append(lines, i)
+			}
+		}
+		b.searchLines, b.searchLinesKey = lines, key
+		return lines
+	}
+
+	r, err := b.searchRegexp(b.LastSearch, b.LastSearchRegex)
+	if err != nil {
+		b.searchLines, b.searchLinesKey = nil, key
+		return nil
+	}
+	// the search runs on a copy of the text, which may be modified meanwhile
+	data := b.Bytes()
+	dos := b.Endings == FFDos
+	b.searchLinesBusy = true
+	go func() {
+		var lines []int
+		for i, l := range bytes.Split(data, []byte{'\n'}) {
+			if dos {
+				l = bytes.TrimSuffix(l, []byte{'\r'})
+			}
+			// like LineSearchMatch, there is no match in an empty line
+			if len(l) > 0 && r.Match(l) {
+				lines = append(lines, i)
+			}
+		}
+
+		b.searchLinesLock.Lock()
+		b.searchLines, b.searchLinesKey, b.searchLinesBusy = lines, key, false
+		b.searchLinesLock.Unlock()
+		screen.Redraw()
+	}()
+	return b.searchLines
+}
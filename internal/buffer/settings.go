@@ -2,6 +2,7 @@ package buffer
 
 import (
 	"crypto/md5"
+	"os"
 	"reflect"
 
 	"github.com/zyedidia/micro/v2/internal/config"
@@ -14,7 +15,7 @@ import (
 
 func (b *Buffer) ReloadSettings(reloadFiletype bool) {
 	settings := config.ParsedSettings()
-	config.UpdatePathGlobLocals(settings, b.AbsPath)
+	config.UpdatePathGlobLocals(settings, b.settingsTarget(int64(b.Size())))
 
 	oldFiletype := b.Settings["filetype"].(string)
 
@@ -22,6 +23,7 @@ func (b *Buffer) ReloadSettings(reloadFiletype bool) {
 	_, volatile := config.VolatileSettings["filetype"]
 	if reloadFiletype && !local && !volatile {
 		// need to update filetype before updating other settings based on it
+		b.ftReason = ""
 		b.Settings["filetype"] = "unknown"
 		if v, ok := settings["filetype"]; ok {
 			b.Settings["filetype"] = v
@@ -36,7 +38,7 @@ func (b *Buffer) ReloadSettings(reloadFiletype bool) {
 		b.doCallbacks("filetype", oldFiletype, curFiletype)
 	}
 
-	config.UpdateFileTypeLocals(settings, curFiletype)
+	config.UpdateFileTypeLocals(settings, b.settingsTarget(int64(b.Size())))
 
 	for k, v := range config.DefaultCommonSettings() {
 		if k == "filetype" {
@@ -83,6 +85,7 @@ func (b *Buffer) DoSetOptionNative(option string, nativeValue interface{}) {
 	} else if option == "statusline" {
 		screen.Redraw()
 	} else if option == "filetype" {
+		b.ftReason = ""
 		b.ReloadSettings(false)
 	} else if option == "fileformat" {
 		switch b.Settings["fileformat"].(string) {
@@ -118,6 +121,7 @@ func (b *Buffer) DoSetOptionNative(option string, nativeValue interface{}) {
 		for _, pl := range config.Plugins {
 			if option == pl.Name {
 				if nativeValue.(bool) {
+					pl.Disabled = false
 					if !pl.Loaded {
 						pl.Load()
 					}
@@ -145,10 +149,103 @@ func (b *Buffer) SetOptionNative(option string, nativeValue interface{}) error {
 
 	b.DoSetOptionNative(option, nativeValue)
 	b.LocalSettings[option] = true
+	delete(b.settingOrigins, option)
 
 	return nil
 }
 
+// setOrigin records where a local setting of the buffer comes from
+func (b *Buffer) setOrigin(option, origin string) {
+	if b.settingOrigins == nil {
+		b.settingOrigins = make(map[string]string)
+	}
+	b.settingOrigins[option] = origin
+}
+
+// SettingOrigin returns a description of where the buffer's value of an
+// option comes from, such as a section of settings.json, an .editorconfig
+// file or a modeline
+func (b *Buffer) SettingOrigin(option string) string {
+	if option == "filetype" {
+		return b.FileTypeReason()
+	}
+	if b.LocalSettings[option] {
+		if origin, ok := b.settingOrigins[option]; ok {
+			return origin
+		}
+		return "set for this buffer"
+	}
+	return config.SettingOrigin(option, b.Settings[option], b.settingsTarget(int64(b.Size())))
+}
+
+// ExplainSetting returns a description of every section of settings.json
+// which sets the given option for the buffer, the last one being the one
+// which is used
+func (b *Buffer) ExplainSetting(option string) []string {
+	return config.ExplainSetting(option, b.settingsTarget(int64(b.Size())))
+}
+
+// Scheme returns the name of the buffer type, which the "scheme:" selector
+// of settings.json matches
+func (t BufType) Scheme() string {
+	switch t.Kind {
+	case BTHelp.Kind:
+		return "help"
+	case BTLog.Kind:
+		return "log"
+	case BTScratch.Kind:
+		return "scratch"
+	case BTRaw.Kind:
+		return "raw"
+	case BTInfo.Kind:
+		return "info"
+	case BTStdout.Kind:
+		return "stdout"
+	}
+	return "file"
+}
+
+// settingsTarget returns the description of the buffer that the sections
+// of settings.json are matched against, given its size
+func (b *Buffer) settingsTarget(size int64) config.SettingsTarget {
+	readonly := b.Type.Readonly
+	if info, err := os.Stat(b.AbsPath); err == nil && b.Path != "" && info.Mode().Perm()&0222 == 0 {
+		readonly = true
+	}
+	return config.SettingsTarget{
+		Path:     b.AbsPath,
+		FileType: b.Settings["filetype"].(string),
+		Size:     size,
+		Readonly: readonly,
+		Scheme:   b.Type.Scheme(),
+	}
+}
+
+// applyEditorConfig sets the options given by the .editorconfig files for
+// the buffer's file as local settings. When called with nil, while the
+// buffer is being created, it looks up the .editorconfig files and only
+// stores the values in the settings map, so that the encoding and the file
+// format are known before the file is read. It returns the settings found.
+func (b *Buffer) applyEditorConfig(settings map[string]config.EditorConfigSetting) map[string]config.EditorConfigSetting {
+	if settings == nil {
+		if !b.Settings["editorconfig"].(bool) || b.Path == "" {
+			return nil
+		}
+		settings = config.EditorConfig(b.AbsPath)
+		for k, s := range settings {
+			b.Settings[k] = s.Value
+		}
+		return settings
+	}
+
+	for k, s := range settings {
+		b.DoSetOptionNative(k, s.Value)
+		b.LocalSettings[k] = true
+		b.setOrigin(k, s.Origin)
+	}
+	return settings
+}
+
 // SetOption sets a given option to a value just for this buffer
 func (b *Buffer) SetOption(option, value string) error {
 	if _, ok := b.Settings[option]; !ok {
@@ -173,4 +270,9 @@ func (b *Buffer) doCallbacks(option string, oldValue interface{}, newValue inter
 		luar.New(ulua.L, oldValue), luar.New(ulua.L, newValue)); err != nil {
 		screen.TermMessage(err)
 	}
+	if err := config.Publish(config.EvBufferOptionChanged, map[string]interface{}{
+		"buf": b, "option": option, "old": oldValue, "new": newValue,
+	}); err != nil {
+		screen.TermMessage(err)
+	}
 }
@@ -0,0 +1,553 @@
+package buffer
+
+import (
+	"crypto/rand"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/zyedidia/micro/v2/internal/clipboard"
+	"github.com/zyedidia/micro/v2/internal/config"
+	"github.com/zyedidia/micro/v2/internal/util"
+)
+
+// A snippetNode is a part of a parsed snippet body: some text, a tabstop
+// (with a placeholder or choices) or a variable (with a default value)
+type snippetNode struct {
+	text string
+	// tabstop is the number of the tabstop, or -1 for text and variables
+	tabstop  int
+	variable string
+	choices  []string
+	// children are the placeholder of a tabstop or the default value of
+	// a variable
+	children []*snippetNode
+}
+
+type snippetParser struct {
+	src []rune
+	pos int
+}
+
+// parseSnippet parses a snippet body in the syntax of the language server
+// protocol. The constructs which are not valid are kept as text, and the
+// transforms of the variables are ignored.
+func parseSnippet(body string) []*snippetNode {
+	p := &snippetParser{src: []rune(body)}
+	return p.parse(false)
+}
+
+func (p *snippetParser) peek() rune {
+	if p.pos < len(p.src) {
+		return p.src[p.pos]
+	}
+	return 0
+}
+
+// parse parses the nodes until the end of the body or, in a placeholder,
+// until the unescaped '}' which ends it
+func (p *snippetParser) parse(inner bool) []*snippetNode {
+	var nodes []*snippetNode
+	var text []rune
+	flush := func() {
+		if len(text) > 0 {
+			nodes = append(nodes, &snippetNode{text: string(text), tabstop: -1})
+			text = nil
+		}
+	}
+
+	for p.pos < len(p.src) {
+		r := p.src[p.pos]
+		switch {
+		case r == '\\' && p.pos+1 < len(p.src) && strings.ContainsRune(`$}\`, p.src[p.pos+1]):
+			text = append(text, p.src[p.pos+1])
+			p.pos += 2
+		case r == '}' && inner:
+			flush()
+			return nodes
+		case r == '$':
+			start := p.pos
+			if n := p.dollar(); n != nil {
+				flush()
+				nodes = append(nodes, n)
+			} else {
+				p.pos = start + 1
+				text = append(text, r)
+			}
+		default:
+			text = append(text, r)
+			p.pos++
+		}
+	}
+	flush()
+	return nodes
+}
+
+// dollar parses a tabstop or a variable, and returns nil if it is invalid
+func (p *snippetParser) dollar() *snippetNode {
+	p.pos++
+	braces := p.peek() == '{'
+	if braces {
+		p.pos++
+	}
+
+	n := &snippetNode{tabstop: -1}
+	if num, ok := p.int(); ok {
+		n.tabstop = num
+	} else if n.variable = p.name(); n.variable == "" {
+		return nil
+	}
+	if !braces {
+		return n
+	}
+
+	switch p.peek() {
+	case '}':
+	case ':':
+		p.pos++
+		n.children = p.parse(true)
+	case '|':
+		if n.tabstop < 0 {
+			return nil
+		}
+		p.pos++
+		if n.choices = p.choices(); n.choices == nil {
+			return nil
+		}
+	case '/':
+		if n.variable == "" || !p.skipTransform() {
+			return nil
+		}
+	default:
+		return nil
+	}
+
+	if p.peek() != '}' {
+		return nil
+	}
+	p.pos++
+	return n
+}
+
+func (p *snippetParser) int() (int, bool) {
+	start := p.pos
+	for p.pos < len(p.src) && p.src[p.pos] >= '0' && p.src[p.pos] <= '9' {
+		p.pos++
+	}
+	n, err := strconv.Atoi(string(p.src[start:p.pos]))
+	return n, err == nil
+}
+
+func (p *snippetParser) name() string {
+	start := p.pos
+	for p.pos < len(p.src) {
+		r := p.src[p.pos]
+		if r != '_' && !unicode.IsLetter(r) && (p.pos == start || !unicode.IsDigit(r)) {
+			break
+		}
+		p.pos++
+	}
+	return string(p.src[start:p.pos])
+}
+
+// choices parses the comma separated choices of a tabstop up to the '|'
+// which ends them
+func (p *snippetParser) choices() []string {
+	var choices []string
+	var choice []rune
+	for p.pos < len(p.src) {
+		r := p.src[p.pos]
+		p.pos++
+		switch {
+		case r == '\\' && p.pos < len(p.src) && strings.ContainsRune(`$}\,|`, p.src[p.pos]):
+			choice = append(choice, p.src[p.pos])
+			p.pos++
+		case r == ',':
+			choices = append(choices, string(choice))
+			choice = nil
+		case r == '|':
+			return append(choices, string(choice))
+		default:
+			choice = append(choice, r)
+		}
+	}
+	return nil
+}
+
+// skipTransform skips the /regex/format/options transform of a variable
+func (p *snippetParser) skipTransform() bool {
+	for slashes := 0; p.pos < len(p.src); p.pos++ {
+		switch p.src[p.pos] {
+		case '\\':
+			p.pos++
+		case '/':
+			slashes++
+		case '}':
+			return slashes == 3
+		}
+	}
+	return false
+}
+
+// A snippetRange is the text of a tabstop in the buffer
+type snippetRange struct {
+	start, end Loc
+}
+
+// A snippetTabstop is a tabstop of an expanded snippet, which has several
+// ranges when it is mirrored
+type snippetTabstop struct {
+	num     int
+	ranges  []*snippetRange
+	choices []string
+}
+
+// A snippetSession is a snippet being expanded, whose tabstops are visited
+// in order, the final tabstop $0 being the last one
+type snippetSession struct {
+	tabstops []*snippetTabstop
+	cur      int
+}
+
+// snippetRenderer computes the text of a snippet and the locations of its
+// tabstops once it is inserted at a given location
+type snippetRenderer struct {
+	text strings.Builder
+	loc  Loc
+	// indent is inserted after the newlines, and tab in place of the tabs
+	indent, tab string
+	variable    func(name string) (string, bool)
+
+	// placeholders are the placeholders of the tabstops which are mirrored
+	placeholders map[int]*snippetNode
+	mirroring    map[int]bool
+	tabstops     map[int]*snippetTabstop
+}
+
+func (r *snippetRenderer) write(s string) {
+	for _, c := range s {
+		switch c {
+		case '\n':
+			r.text.WriteRune(c)
+			r.text.WriteString(r.indent)
+			r.loc = Loc{util.CharacterCountInString(r.indent), r.loc.Y + 1}
+		case '\t':
+			r.text.WriteString(r.tab)
+			r.loc.X += util.CharacterCountInString(r.tab)
+		default:
+			r.text.WriteRune(c)
+			r.loc.X++
+		}
+	}
+}
+
+// findPlaceholders finds the first placeholder of each tabstop, which is
+// used for its mirrors
+func (r *snippetRenderer) findPlaceholders(nodes []*snippetNode) {
+	for _, n := range nodes {
+		if n.tabstop >= 0 && (len(n.children) > 0 || len(n.choices) > 0) {
+			if _, ok := r.placeholders[n.tabstop]; !ok {
+				r.placeholders[n.tabstop] = n
+			}
+		}
+		r.findPlaceholders(n.children)
+	}
+}
+
+// render writes the nodes, and records their tabstops if record is true
+func (r *snippetRenderer) render(nodes []*snippetNode, record bool) {
+	for _, n := range nodes {
+		switch {
+		case n.tabstop >= 0:
+			start := r.loc
+			if len(n.choices) > 0 {
+				r.write(n.choices[0])
+			} else if len(n.children) > 0 {
+				r.render(n.children, record)
+			} else if p, ok := r.placeholders[n.tabstop]; ok && !r.mirroring[n.tabstop] {
+				r.mirroring[n.tabstop] = true
+				if len(p.choices) > 0 {
+					r.write(p.choices[0])
+				} else {
+					r.render(p.children, false)
+				}
+				r.mirroring[n.tabstop] = false
+			}
+			if record {
+				t, ok := r.tabstops[n.tabstop]
+				if !ok {
+					t = &snippetTabstop{num: n.tabstop}
+					r.tabstops[n.tabstop] = t
+				}
+				t.ranges = append(t.ranges, &snippetRange{start, r.loc})
+				if len(n.choices) > 0 {
+					t.choices = n.choices
+				}
+			}
+		case n.variable != "":
+			if v, ok := r.variable(n.variable); ok && v != "" {
+				r.write(v)
+			} else if len(n.children) > 0 {
+				r.render(n.children, record)
+			} else if !ok {
+				r.write(n.variable)
+			}
+		default:
+			r.write(n.text)
+		}
+	}
+}
+
+// session returns the snippet session visiting the recorded tabstops. If
+// the snippet has no final tabstop, it is at the end of the snippet.
+func (r *snippetRenderer) session() *snippetSession {
+	s := new(snippetSession)
+	for _, t := range r.tabstops {
+		if t.num != 0 {
+			s.tabstops = append(s.tabstops, t)
+		}
+	}
+	for i := 1; i < len(s.tabstops); i++ {
+		for j := i; j > 0 && s.tabstops[j].num < s.tabstops[j-1].num; j-- {
+			s.tabstops[j], s.tabstops[j-1] = s.tabstops[j-1], s.tabstops[j]
+		}
+	}
+	final, ok := r.tabstops[0]
+	if !ok {
+		final = &snippetTabstop{ranges: []*snippetRange{{r.loc, r.loc}}}
+	}
+	s.tabstops = append(s.tabstops, final)
+	return s
+}
+
+// shift moves the ranges of the tabstops after an insertion or a removal
+// between start and end. The text inserted at the start of a range of the
+// current tabstop is typed in the tabstop, so it is added to the range.
+func (s *snippetSession) shift(start, end Loc, insert bool) {
+	for i, t := range s.tabstops {
+		for _, rg := range t.ranges {
+			if !insert || i != s.cur || rg.start != start {
+				rg.start = shiftLoc(rg.start, start, end, insert)
+			}
+			rg.end = shiftLoc(rg.end, start, end, insert)
+		}
+	}
+}
+
+// bounds returns the start and the end of the text of the snippet
+func (s *snippetSession) bounds() (Loc, Loc) {
+	start, end := s.tabstops[0].ranges[0].start, s.tabstops[0].ranges[0].end
+	for _, t := range s.tabstops {
+		for _, rg := range t.ranges {
+			if rg.start.LessThan(start) {
+				start = rg.start
+			}
+			if rg.end.GreaterThan(end) {
+				end = rg.end
+			}
+		}
+	}
+	return start, end
+}
+
+// snippetVariable returns the value of a snippet variable, and false if it
+// is not a known variable
+func (b *Buffer) snippetVariable(name string) (string, bool) {
+	c := b.GetActiveCursor()
+	now := time.Now()
+	switch name {
+	case "TM_SELECTED_TEXT":
+		return string(c.GetSelection()), true
+	case "TM_CURRENT_LINE":
+		return string(b.LineBytes(c.Y)), true
+	case "TM_LINE_INDEX":
+		return strconv.Itoa(c.Y), true
+	case "TM_LINE_NUMBER":
+		return strconv.Itoa(c.Y + 1), true
+	case "TM_FILENAME":
+		return filepath.Base(b.GetName()), true
+	case "TM_FILENAME_BASE":
+		base := filepath.Base(b.GetName())
+		return strings.TrimSuffix(base, filepath.Ext(base)), true
+	case "TM_DIRECTORY":
+		if b.AbsPath == "" {
+			return "", true
+		}
+		return filepath.Dir(b.AbsPath), true
+	case "TM_FILEPATH":
+		return b.AbsPath, true
+	case "CLIPBOARD":
+		clip, err := clipboard.Read(clipboard.ClipboardReg)
+		return clip, err == nil
+	case "CURRENT_YEAR":
+		return now.Format("2006"), true
+	case "CURRENT_YEAR_SHORT":
+		return now.Format("06"), true
+	case "CURRENT_MONTH":
+		return now.Format("01"), true
+	case "CURRENT_MONTH_NAME":
+		return now.Format("January"), true
+	case "CURRENT_MONTH_NAME_SHORT":
+		return now.Format("Jan"), true
+	case "CURRENT_DATE":
+		return now.Format("02"), true
+	case "CURRENT_DAY_NAME":
+		return now.Format("Monday"), true
+	case "CURRENT_DAY_NAME_SHORT":
+		return now.Format("Mon"), true
+	case "CURRENT_HOUR":
+		return now.Format("15"), true
+	case "CURRENT_MINUTE":
+		return now.Format("04"), true
+	case "CURRENT_SECOND":
+		return now.Format("05"), true
+	case "CURRENT_SECONDS_UNIX":
+		return strconv.FormatInt(now.Unix(), 10), true
+	case "RANDOM", "RANDOM_HEX", "UUID":
+		var r [16]byte
+		rand.Read(r[:])
+		switch name {
+		case "RANDOM":
+			return fmt.Sprintf("%06d", (int(r[0])<<16|int(r[1])<<8|int(r[2]))%1000000), true
+		case "RANDOM_HEX":
+			return fmt.Sprintf("%x", r[:3]), true
+		}
+		r[6] = r[6]&0x0f | 0x40
+		r[8] = r[8]&0x3f | 0x80
+		return fmt.Sprintf("%x-%x-%x-%x-%x", r[:4], r[4:6], r[6:8], r[8:10], r[10:]), true
+	case "LINE_COMMENT":
+		if ct, ok := b.Settings["commenttype"].(string); ok {
+			return strings.TrimSpace(strings.SplitN(ct, "%s", 2)[0]), true
+		}
+	}
+	return "", false
+}
+
+// ExpandSnippet replaces the text between start and end by the expansion
+// of a snippet body, and moves the cursor to its first tabstop. The
+// expansion is undone in a single step.
+func (b *Buffer) ExpandSnippet(start, end Loc, body string) {
+	nodes := parseSnippet(body)
+	r := &snippetRenderer{
+		loc:          start,
+		indent:       string(util.GetLeadingWhitespace(b.LineBytes(start.Y))),
+		tab:          b.IndentString(util.IntOpt(b.Settings["tabsize"])),
+		variable:     b.snippetVariable,
+		placeholders: make(map[int]*snippetNode),
+		mirroring:    make(map[int]bool),
+		tabstops:     make(map[int]*snippetTabstop),
+	}
+	r.findPlaceholders(nodes)
+	r.render(nodes, true)
+
+	b.snippet = nil
+	b.ClearCursors()
+
+	// both events are given the same time so that they are undone together
+	var removed *TextEvent
+	if start != end {
+		b.Remove(start, end)
+		removed = b.UndoStack.Peek()
+	}
+	b.Insert(start, r.text.String())
+	if t := b.UndoStack.Peek(); removed != nil && t != removed {
+		t.Time = removed.Time
+	}
+
+	b.snippet = r.session()
+	b.gotoTabstop(0)
+}
+
+// ExpandSnippetPrefix expands the snippet of the buffer's filetype whose
+// prefix is the word before the cursor, and returns false if there is none
+func (b *Buffer) ExpandSnippetPrefix() (bool, error) {
+	if b.NumCursors() > 1 || b.GetActiveCursor().HasSelection() {
+		return false, nil
+	}
+	word, start := b.GetWord()
+	if len(word) == 0 {
+		return false, nil
+	}
+	s, err := config.FindSnippet(b.FileType(), string(word))
+	if s == nil || err != nil {
+		return false, err
+	}
+	c := b.GetActiveCursor()
+	b.ExpandSnippet(Loc{start, c.Y}, c.Loc, s.Body)
+	return true, nil
+}
+
+// gotoTabstop places the cursors on the ranges of the given tabstop of the
+// snippet being expanded, and ends the expansion at the final tabstop. The
+// text of the tabstop is selected, and its choices are offered as
+// suggestions.
+func (b *Buffer) gotoTabstop(i int) {
+	s := b.snippet
+	s.cur = i
+	t := s.tabstops[i]
+
+	b.ClearCursors()
+	for j, rg := range t.ranges {
+		c := b.GetActiveCursor()
+		if j > 0 {
+			c = NewCursor(b, rg.end)
+			b.AddCursor(c)
+		}
+		c.Loc = rg.end
+		if rg.start != rg.end && len(t.choices) == 0 {
+			c.SetSelectionStart(rg.start)
+			c.SetSelectionEnd(rg.end)
+			c.OrigSelection = c.CurSelection
+		}
+		c.StoreVisualX()
+	}
+
+	if len(t.choices) > 1 && len(t.ranges) == 1 {
+		b.Completions = t.choices
+		b.Suggestions = t.choices
+		b.CurSuggestion = 0
+		b.HasSuggestions = true
+	}
+	if i == len(s.tabstops)-1 {
+		b.snippet = nil
+	}
+}
+
+// InSnippet returns whether a snippet is being expanded with the cursor in
+// its text. Moving the cursor out of the snippet ends its expansion.
+func (b *Buffer) InSnippet() bool {
+	if b.snippet == nil {
+		return false
+	}
+	start, end := b.snippet.bounds()
+	if c := b.GetActiveCursor(); c.Loc.LessThan(start) || c.Loc.GreaterThan(end) {
+		b.snippet = nil
+		return false
+	}
+	return true
+}
+
+// NextTabstop moves the cursors to the next tabstop of the snippet being
+// expanded, and returns false if no snippet is being expanded
+func (b *Buffer) NextTabstop() bool {
+	if !b.InSnippet() {
+		return false
+	}
+	b.gotoTabstop(b.snippet.cur + 1)
+	return true
+}
+
+// PreviousTabstop moves the cursors to the previous tabstop of the snippet
+// being expanded, and returns false if no snippet is being expanded
+func (b *Buffer) PreviousTabstop() bool {
+	if !b.InSnippet() {
+		return false
+	}
+	if b.snippet.cur > 0 {
+		b.gotoTabstop(b.snippet.cur - 1)
+	}
+	return true
+}
@@ -0,0 +1,69 @@
+package buffer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSnippet(t *testing.T) {
+	nodes := parseSnippet(`a ${1:b ${2:c}} \$x $TM_NONE ${3|d,e\,f|} ${1 $`)
+	assert.Equal(t, "a ", nodes[0].text)
+	assert.Equal(t, 1, nodes[1].tabstop)
+	assert.Equal(t, 2, nodes[1].children[1].tabstop)
+	assert.Equal(t, " $x ", nodes[2].text)
+	assert.Equal(t, "TM_NONE", nodes[3].variable)
+	assert.Equal(t, []string{"d", "e,f"}, nodes[5].choices)
+	assert.Equal(t, " ${1 $", nodes[6].text)
+}
+
+func TestExpandSnippet(t *testing.T) {
+	b := NewBufferFromString("  fn", "", BTDefault)
+	defer b.Close()
+	b.Settings["tabstospaces"] = true
+	b.Settings["tabsize"] = float64(2)
+
+	c := b.GetActiveCursor()
+	c.Loc = Loc{4, 0}
+	b.ExpandSnippet(Loc{2, 0}, c.Loc, "func ${1:name}($2) {\n\t$1 ${3|a,b|}\n}")
+	assert.Equal(t, "  func name() {\n    name a\n  }", string(b.Bytes()))
+
+	// the placeholder is selected with a cursor on each mirror
+	assert.Equal(t, 2, b.NumCursors())
+	assert.Equal(t, [2]Loc{{7, 0}, {11, 0}}, b.GetCursor(0).CurSelection)
+	assert.Equal(t, Loc{8, 1}, b.GetCursor(1).Loc)
+
+	for _, c := range b.GetCursors() {
+		c.DeleteSelection()
+		c.ResetSelection()
+	}
+	for _, c := range b.GetCursors() {
+		b.Insert(c.Loc, "x")
+	}
+	assert.Equal(t, "  func x() {\n    x a\n  }", string(b.Bytes()))
+
+	assert.True(t, b.NextTabstop())
+	assert.Equal(t, 1, b.NumCursors())
+	assert.Equal(t, Loc{9, 0}, b.GetActiveCursor().Loc)
+	b.Insert(b.GetActiveCursor().Loc, "y")
+
+	assert.True(t, b.NextTabstop())
+	assert.True(t, b.HasSuggestions)
+	b.CycleAutocomplete(true)
+	assert.Equal(t, "  func x(y) {\n    x b\n  }", string(b.Bytes()))
+
+	assert.True(t, b.PreviousTabstop())
+	assert.Equal(t, [2]Loc{{9, 0}, {10, 0}}, b.GetActiveCursor().CurSelection)
+
+	assert.True(t, b.NextTabstop())
+	assert.True(t, b.NextTabstop())
+	assert.Equal(t, Loc{3, 2}, b.GetActiveCursor().Loc)
+	assert.False(t, b.NextTabstop())
+
+	// the expansion is undone in one step
+	b.UndoStack = new(TEStack)
+	b.ExpandSnippet(Loc{0, 0}, Loc{2, 0}, "$TM_LINE_NUMBER")
+	assert.False(t, b.InSnippet())
+	b.Undo()
+	assert.Equal(t, "  func x(y) {\n    x b\n  }", string(b.Bytes()))
+}
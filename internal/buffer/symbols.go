@@ -0,0 +1,51 @@
+package buffer
+
+import (
+	"regexp"
+	"strings"
+)
+
+// A Symbol is a definition in a buffer, such as a function or a type
+type Symbol struct {
+	Name string
+	// Kind is the keyword of the definition, such as func or class, or
+	// heading in markdown
+	Kind string
+	Loc  Loc
+}
+
+// symbolRegex matches the definitions of most languages: a defining
+// keyword, possibly preceded by modifiers and followed by a Go receiver,
+// then the name of the symbol
+var symbolRegex = regexp.MustCompile(`^\s*(?:(?:export|default|pub(?:\([^)]*\))?|public|private|protected|internal|static|async|local|abstract|final|override|inline|unsafe|extern)\s+)*` +
+	`(func|function|def|class|struct|type|interface|enum|trait|impl|fn|module|sub|proc|macro|object|record)\s+(?:\([^)]*\)\s*)?([A-Za-z_$][\w$.:]*)`)
+
+var headingRegex = regexp.MustCompile(`^(#{1,6})\s+(.+?)\s*#*\s*$`)
+
+// Symbols returns the definitions of the buffer, found by matching its
+// lines against common definition keywords, or the headings of a markdown
+// buffer
+func (b *Buffer) Symbols() []Symbol {
+	markdown := b.FileType() == "markdown"
+	var symbols []Symbol
+	for i := 0; i < b.LinesNum(); i++ {
+		l := b.LineBytes(i)
+		if markdown {
+			if m := headingRegex.FindSubmatch(l); m != nil {
+				symbols = append(symbols, Symbol{string(m[2]), "heading", Loc{0, i}})
+			}
+			continue
+		}
+		m := symbolRegex.FindSubmatchIndex(l)
+		if m == nil {
+			continue
+		}
+		name := strings.TrimRight(string(l[m[4]:m[5]]), ".:")
+		if name == "" {
+			continue
+		}
+		x := len([]rune(string(l[:m[4]])))
+		symbols = append(symbols, Symbol{name, string(l[m[2]:m[3]]), Loc{x, i}})
+	}
+	return symbols
+}
@@ -0,0 +1,20 @@
+package buffer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSymbols(t *testing.T) {
+	b := NewBufferFromString("package main\n\nfunc (b *Buffer) Symbols() {}\ntype Symbol struct {\n\tpub fn inner() {}\n// func comment\nexport default class App {}", "", BTDefault)
+	defer b.Close()
+
+	symbols := b.Symbols()
+	assert.Equal(t, []Symbol{
+		{"Symbols", "func", Loc{17, 2}},
+		{"Symbol", "type", Loc{5, 3}},
+		{"inner", "fn", Loc{8, 4}},
+		{"App", "class", Loc{21, 6}},
+	}, symbols)
+}
@@ -0,0 +1,153 @@
+package buffer
+
+import (
+	"sort"
+	"strings"
+
+	runewidth "github.com/mattn/go-runewidth"
+	"github.com/zyedidia/micro/v2/internal/util"
+	"github.com/zyedidia/micro/v2/pkg/highlight"
+)
+
+// The positions of virtual text relative to its location
+const (
+	// VTInline virtual text is drawn just before the character at its
+	// location, the cursor being drawn at the start of the virtual text
+	VTInline = iota
+	// VTEndOfLine virtual text is drawn after the end of the line of its
+	// location
+	VTEndOfLine
+)
+
+// VirtualText is a non-editable string attached to a location of the
+// buffer by an external source (inline diagnostics, blame, type hints,
+// ghost-text completions...). It is only drawn: it isn't part of the text,
+// so the cursor never moves into it, and it moves along with the text when
+// it is edited.
+type VirtualText struct {
+	Loc  Loc
+	Text string
+	// Pos is VTInline or VTEndOfLine
+	Pos int
+	// The Group used to look up the style in the colorscheme
+	Group highlight.Group
+	// The Owner of the virtual text
+	Owner string
+}
+
+// NewVirtualText creates virtual text drawn at the given location with the
+// given highlight group. Tabs and newlines in the text are drawn as spaces.
+func NewVirtualText(owner string, loc Loc, text string, pos int, group string) *VirtualText {
+	return &VirtualText{
+		Loc:   loc,
+		Text:  strings.NewReplacer("\t", " ", "\r", " ", "\n", " ").Replace(text),
+		Pos:   pos,
+		Group: highlight.GetGroup(group),
+		Owner: owner,
+	}
+}
+
+// AddVirtualText attaches virtual text to the buffer. Virtual text with
+// the same location and position is drawn in the order in which it was
+// added.
+func (b *Buffer) AddVirtualText(vt *VirtualText) {
+	if vt.Text == "" {
+		return
+	}
+	vt.Loc = vt.Loc.Clamp(b.Start(), b.End())
+	b.VirtualText = append(b.VirtualText, vt)
+	sort.SliceStable(b.VirtualText, func(i, j int) bool {
+		return b.VirtualText[i].Loc.LessThan(b.VirtualText[j].Loc)
+	})
+}
+
+// ClearVirtualText removes all virtual text added by the given owner
+func (b *Buffer) ClearVirtualText(owner string) {
+	vts := b.VirtualText[:0]
+	for _, vt := range b.VirtualText {
+		if vt.Owner != owner {
+			vts = append(vts, vt)
+		}
+	}
+	for i := len(vts); i < len(b.VirtualText); i++ {
+		b.VirtualText[i] = nil
+	}
+	b.VirtualText = vts
+}
+
+// ClearAllVirtualText removes all virtual text from the buffer
+func (b *Buffer) ClearAllVirtualText() {
+	b.VirtualText = make([]*VirtualText, 0)
+}
+
+// LineVirtualText returns the virtual text of the given line drawn at the
+// given position (VTInline or VTEndOfLine), sorted by location
+func (b *Buffer) LineVirtualText(lineN int, pos int) []*VirtualText {
+	// the virtual text is sorted by location, which the text events keep
+	i := sort.Search(len(b.VirtualText), func(i int) bool {
+		return b.VirtualText[i].Loc.Y >= lineN
+	})
+	var vts []*VirtualText
+	for ; i < len(b.VirtualText) && b.VirtualText[i].Loc.Y == lineN; i++ {
+		if b.VirtualText[i].Pos == pos {
+			vts = append(vts, b.VirtualText[i])
+		}
+	}
+	return vts
+}
+
+// InlineWidths returns the width of the inline virtual text drawn before
+// each character of the given line, or nil if the line has none
+func (b *Buffer) InlineWidths(lineN int) map[int]int {
+	var widths map[int]int
+	for _, vt := range b.LineVirtualText(lineN, VTInline) {
+		if widths == nil {
+			widths = make(map[int]int)
+		}
+		widths[vt.Loc.X] += runewidth.StringWidth(vt.Text)
+	}
+	return widths
+}
+
+// InlineWidthBefore returns the width of the inline virtual text drawn
+// before the first x characters of a line, given its InlineWidths
+func InlineWidthBefore(inline map[int]int, x int) int {
+	width := 0
+	for vx, vw := range inline {
+		if vx < x {
+			width += vw
+		}
+	}
+	return width
+}
+
+// CharPosInLine is like util.GetCharPosInLine for a line with inline
+// virtual text, given its InlineWidths: a visual position within the
+// virtual text drawn before a character is the position of this character
+func CharPosInLine(b []byte, visualPos, tabsize int, inline map[int]int) int {
+	if inline == nil {
+		return util.GetCharPosInLine(b, visualPos, tabsize)
+	}
+	x := 0
+	width := 0
+	textwidth := 0
+	for len(b) > 0 {
+		r, _, size := util.DecodeCharacter(b)
+		b = b[size:]
+
+		cw := 0
+		switch r {
+		case '\t':
+			cw = tabsize - (textwidth % tabsize)
+		default:
+			cw = runewidth.RuneWidth(r)
+		}
+		textwidth += cw
+		width += inline[x] + cw
+		if width > visualPos {
+			return x
+		}
+		x++
+	}
+	return x
+}
@@ -0,0 +1,54 @@
+package buffer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVirtualText(t *testing.T) {
+	b := NewBufferFromString("foo bar\nbaz", "", BTDefault)
+	defer b.Close()
+
+	hint := NewVirtualText("a", Loc{3, 0}, ": int", VTInline, "comment")
+	diag := NewVirtualText("b", Loc{1, 1}, "bad\tthing\n", VTEndOfLine, "error")
+	b.AddVirtualText(diag)
+	b.AddVirtualText(hint)
+	b.AddVirtualText(NewVirtualText("a", Loc{0, 0}, "", VTInline, "comment"))
+
+	assert.Equal(t, "bad thing ", diag.Text)
+	assert.Equal(t, []*VirtualText{hint, diag}, b.VirtualText)
+	assert.Equal(t, []*VirtualText{hint}, b.LineVirtualText(0, VTInline))
+	assert.Empty(t, b.LineVirtualText(0, VTEndOfLine))
+
+	b.Insert(Loc{0, 0}, "x")
+	assert.Equal(t, Loc{4, 0}, hint.Loc)
+	b.Insert(Loc{0, 1}, "new line\n")
+	assert.Equal(t, Loc{1, 2}, diag.Loc)
+	b.Remove(Loc{2, 0}, Loc{6, 0})
+	assert.Equal(t, Loc{2, 0}, hint.Loc)
+
+	b.ClearVirtualText("a")
+	assert.Equal(t, []*VirtualText{diag}, b.VirtualText)
+}
+
+func TestVirtualTextVerticalMotion(t *testing.T) {
+	b := NewBufferFromString("foo(x)\nfoo(long)", "", BTDefault)
+	defer b.Close()
+	b.AddVirtualText(NewVirtualText("a", Loc{4, 0}, "n: ", VTInline, "comment"))
+
+	// the cursor after x is drawn at the 8th column, above the g of long
+	c := b.GetActiveCursor()
+	c.GotoLoc(Loc{5, 0})
+	assert.Equal(t, 8, c.LastVisualX)
+	c.Down()
+	assert.Equal(t, Loc{8, 1}, c.Loc)
+	c.Up()
+	assert.Equal(t, Loc{5, 0}, c.Loc)
+
+	// a column within the virtual text is the column of the character
+	// after it
+	c.GotoLoc(Loc{5, 1})
+	c.Up()
+	assert.Equal(t, Loc{4, 0}, c.Loc)
+}
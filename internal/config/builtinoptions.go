@@ -0,0 +1,732 @@
+package config
+
+//go:generate go run ../../tools/options-doc.go ../../runtime/help/options.md
+
+// builtinOptions are micro's own options. The option list of `help options`
+// is generated from them (see GenerateOptionsHelp).
+var builtinOptions = []*Option{
+	{
+		Name:    "autoindent",
+		Type:    OptionBool,
+		Default: true,
+		Help: "when creating a new line, use the same indentation as the\n" +
+			"previous line. If the syntax file of the filetype has indentation rules\n" +
+			"(see `help colors`), the new line is indented according to them instead.",
+	},
+	{
+		Name:    "autosave",
+		Type:    OptionNumber,
+		Scope:   ScopeGlobal,
+		Default: float64(0),
+		Min:     bound(0),
+		Help: "automatically save the buffer every n seconds, where n is the\n" +
+			"value of the autosave option. Also when quitting on a modified buffer, micro\n" +
+			"will automatically save and quit. Be warned, this option saves the buffer\n" +
+			"without prompting the user, so data may be overwritten. If this option is\n" +
+			"set to `0`, no autosaving is performed.",
+	},
+	{
+		Name:    "autosu",
+		Type:    OptionBool,
+		Default: false,
+		Help: "When a file is saved that the user doesn't have permission to\n" +
+			"modify, micro will ask if the user would like to use super user\n" +
+			"privileges to save the file. If this option is enabled, micro will\n" +
+			"automatically attempt to use super user privileges to save without\n" +
+			"asking the user.",
+	},
+	{
+		Name:    "backup",
+		Type:    OptionBool,
+		Default: true,
+		Help: "micro will automatically keep backups of all open buffers. Backups\n" +
+			"are stored in `~/.config/micro/backups` and are removed when the buffer is\n" +
+			"closed cleanly. In the case of a system crash or a micro crash, the contents\n" +
+			"of the buffer can be recovered automatically by opening the file that was\n" +
+			"being edited before the crash, or manually by searching for the backup in\n" +
+			"the backup directory. Backups are made in the background for newly modified\n" +
+			"buffers every 8 seconds, or when micro detects a crash.",
+	},
+	{
+		Name:    "backupdir",
+		Type:    OptionString,
+		Default: "",
+		Help: "the directory micro should place backups in. For the default\n" +
+			"value of `\"\"` (empty string), the backup directory will be\n" +
+			"`ConfigDir/backups`, which is `~/.config/micro/backups` by default. The\n" +
+			"directory specified for backups will be created if it does not exist.",
+	},
+	{
+		Name:    "basename",
+		Type:    OptionBool,
+		Default: false,
+		Help: "in the infobar and tabbar, show only the basename of the file\n" +
+			"being edited rather than the full path.",
+	},
+	{
+		Name:    "clipboard",
+		Type:    OptionString,
+		Scope:   ScopeGlobal,
+		Default: "external",
+		Choices: []string{"internal", "external", "terminal"},
+		Help: "specifies how micro should access the system clipboard.\n" +
+			"Possible values are:\n" +
+			" * `external`: accesses clipboard via an external tool, such as xclip/xsel\n" +
+			"    or wl-clipboard on Linux, pbcopy/pbpaste on MacOS, and system calls on\n" +
+			"    Windows. On Linux, if you do not have one of the tools installed, or if\n" +
+			"    they are not working, micro will throw an error and use an internal\n" +
+			"    clipboard.\n" +
+			" * `terminal`: accesses the clipboard via your terminal emulator. Note that\n" +
+			"    there is limited support among terminal emulators for this feature\n" +
+			"    (called OSC 52). Terminals that are known to work are Kitty (enable\n" +
+			"    reading with `clipboard_control` setting), iTerm2 (only copying),\n" +
+			"    st, rxvt-unicode and xterm if enabled (see `> help copypaste` for\n" +
+			"    details). Note that Gnome-terminal does not support this feature. With\n" +
+			"    this setting, copy-paste **will** work over ssh. See `> help copypaste`\n" +
+			"    for details.\n" +
+			" * `internal`: micro will use an internal clipboard.",
+	},
+	{
+		Name:    "colorcolumn",
+		Type:    OptionNumber,
+		Default: float64(0),
+		Min:     bound(0),
+		Help: "if this is not set to 0, it will display a column at the\n" +
+			"specified column. This is useful if you want column 80 to be highlighted\n" +
+			"special for example.",
+	},
+	{
+		Name:      "colorscheme",
+		Type:      OptionString,
+		Scope:     ScopeGlobal,
+		Default:   "default",
+		Validator: validateColorscheme,
+		Help: "use the given colorscheme.\n" +
+			"The colorscheme can be either one of the colorschemes that micro comes with\n" +
+			"by default (such as `default`, `solarized` or `solarized-tc`) which are\n" +
+			"embedded in the micro binary, or a custom colorscheme stored in\n" +
+			"`~/.config/micro/colorschemes/$(option).micro` where `$(option)` is the\n" +
+			"option value. You can read more about micro's colorschemes and see the list\n" +
+			"of default colorschemes in `> help colors`.",
+	},
+	{
+		Name:    "cursorline",
+		Type:    OptionBool,
+		Default: true,
+		Help: "highlight the line that the cursor is on in a different color\n" +
+			"(the color is defined by the colorscheme you are using).",
+	},
+	{
+		Name:    "detectlimit",
+		Type:    OptionNumber,
+		Default: float64(100),
+		Min:     bound(0),
+		Help: "if this is not set to 0, it will limit the amount of first\n" +
+			"lines in a file that are matched to determine the filetype.\n" +
+			"A higher limit means better accuracy of guessing the filetype, but also\n" +
+			"taking more time.",
+	},
+	{
+		Name:    "diffgutter",
+		Type:    OptionBool,
+		Default: false,
+		Help:    "display diff indicators before lines.",
+	},
+	{
+		Name:    "divchars",
+		Type:    OptionString,
+		Scope:   ScopeGlobal,
+		Default: "|-",
+		Help: "specifies the \"divider\" characters used for the dividing line\n" +
+			"between vertical/horizontal splits. The first character is for vertical\n" +
+			"dividers, and the second is for horizontal dividers. By default, for\n" +
+			"horizontal splits the statusline serves as a divider, but if the statusline\n" +
+			"is disabled the horizontal divider character will be used.",
+	},
+	{
+		Name:    "divreverse",
+		Type:    OptionBool,
+		Scope:   ScopeGlobal,
+		Default: true,
+		Help: "colorschemes provide the color (foreground and background) for\n" +
+			"the characters displayed in split dividers. With this option enabled, the\n" +
+			"colors specified by the colorscheme will be reversed (foreground and\n" +
+			"background colors swapped).",
+	},
+	{
+		Name:    "editorconfig",
+		Type:    OptionBool,
+		Default: true,
+		Help: "apply the settings of `.editorconfig` files to the buffer.\n" +
+			"micro looks for `.editorconfig` files in the directory of the file and in\n" +
+			"its parent directories, up to a file containing `root = true`, and applies\n" +
+			"the sections matching the file (see https://editorconfig.org). The\n" +
+			"properties `indent_style`, `indent_size`, `tab_width`, `end_of_line`,\n" +
+			"`charset`, `trim_trailing_whitespace` and `insert_final_newline` set the\n" +
+			"`tabstospaces`, `tabsize`, `fileformat`, `encoding`, `rmtrailingws` and\n" +
+			"`eofnewline` options for the buffer. These take precedence over\n" +
+			"`settings.json`, but a modeline or `setlocal` overrides them. The `show`\n" +
+			"command tells which `.editorconfig` section set an option.",
+	},
+	{
+		Name:      "encoding",
+		Type:      OptionString,
+		Default:   "utf-8",
+		Validator: validateEncoding,
+		Help: "the encoding to open and save files with. Supported encodings\n" +
+			"are listed at https://www.w3.org/TR/encoding/.",
+	},
+	{
+		Name:    "eofnewline",
+		Type:    OptionBool,
+		Default: true,
+		Help: "micro will automatically add a newline to the end of the\n" +
+			"file if one does not exist.",
+	},
+	{
+		Name:    "fakecursor",
+		Type:    OptionBool,
+		Scope:   ScopeGlobal,
+		Default: false,
+		Help: "forces micro to render the cursor using terminal colors rather\n" +
+			"than the actual terminal cursor. This is useful when the terminal's cursor is\n" +
+			"slow or otherwise unavailable/undesirable to use.",
+	},
+	{
+		Name:    "fastdirty",
+		Type:    OptionBool,
+		Default: false,
+		Help: "this determines what kind of algorithm micro uses to determine\n" +
+			"if a buffer is modified or not. When `fastdirty` is on, micro just uses a\n" +
+			"boolean `modified` that is set to `true` as soon as the user makes an edit.\n" +
+			"This is fast, but can be inaccurate. If `fastdirty` is off, then micro will\n" +
+			"hash the current buffer against a hash of the original file (created when\n" +
+			"the buffer was loaded). This is more accurate but obviously more resource\n" +
+			"intensive. This option will be automatically disabled if the file size\n" +
+			"exceeds 50KB.",
+	},
+	{
+		Name:    "fileformat",
+		Type:    OptionString,
+		Default: defaultFileFormat(),
+		Choices: []string{"unix", "dos"},
+		Help: "this determines what kind of line endings micro will use for\n" +
+			"the file. Unix line endings are just `\\n` (linefeed) whereas dos line\n" +
+			"endings are `\\r\\n` (carriage return + linefeed). The two possible values for\n" +
+			"this option are `unix` and `dos`. The fileformat will be automatically\n" +
+			"detected (when you open an existing file) and displayed on the statusline,\n" +
+			"but this option is useful if you would like to change the line endings or if\n" +
+			"you are starting a new file. Changing this option while editing a file will\n" +
+			"change its line endings. Opening a file with this option set will only have\n" +
+			"an effect if the file is empty/newly created, because otherwise the fileformat\n" +
+			"will be automatically detected from the existing line endings.",
+		DefaultHelp: "`unix` on Unix systems, `dos` on Windows",
+	},
+	{
+		Name:    "filetype",
+		Type:    OptionString,
+		Scope:   ScopeLocal,
+		Default: "unknown",
+		Help: "sets the filetype for the current buffer. Set this option to\n" +
+			"`off` to completely disable filetype detection.\n" +
+			"\n" +
+			"When the filetype is not set, micro detects it from (in order of priority)\n" +
+			"a modeline (see the `modeline` option), the `filetypes` map in\n" +
+			"`settings.json` (see below), the interpreter of a shebang line such as\n" +
+			"`#!/usr/bin/env python3`, and finally the filename, header and signature\n" +
+			"rules of the syntax files. When the rules of several syntax files match,\n" +
+			"the one whose signature matches wins, and otherwise they are ranked: the\n" +
+			"ones which also match the first line, then the user's syntax files, then\n" +
+			"the ones whose filename rule matches the longest part of the path, and\n" +
+			"finally by name. The `filetype` command shows why the current filetype\n" +
+			"was chosen, and `filetype detect` runs the detection again.",
+		DefaultHelp: "`unknown`. This will be automatically overridden depending\n" +
+			"    on the file you open.",
+	},
+	{
+		Name:    "helpsplit",
+		Type:    OptionString,
+		Scope:   ScopeGlobal,
+		Default: "hsplit",
+		Choices: []string{"hsplit", "vsplit"},
+		Help: "sets the split type to be used by the `help` command.\n" +
+			"Possible values:\n" +
+			" * `vsplit`: open help in a vertical split pane\n" +
+			" * `hsplit`: open help in a horizontal split pane",
+	},
+	{
+		Name:    "hlsearch",
+		Type:    OptionBool,
+		Default: false,
+		Help: "highlight all instances of the searched text after a successful\n" +
+			"search. This highlighting can be temporarily turned off via the\n" +
+			"`UnhighlightSearch` action (triggered by the Esc key by default) or toggled\n" +
+			"on/off via the `ToggleHighlightSearch` action. Note that these actions don't\n" +
+			"change the `hlsearch` setting. As long as `hlsearch` is set to true, the next\n" +
+			"search will have the highlighting turned on again.",
+	},
+	{
+		Name:    "hltaberrors",
+		Type:    OptionBool,
+		Default: false,
+		Help: "highlight tabs when spaces are expected, and spaces when tabs\n" +
+			"are expected. More precisely: if `tabstospaces` option is on, highlight\n" +
+			"all tab characters; if `tabstospaces` is off, highlight space characters\n" +
+			"in the initial indent part of the line.",
+	},
+	{
+		Name:    "hltrailingws",
+		Type:    OptionBool,
+		Default: false,
+		Help: "highlight trailing whitespaces at ends of lines. Note that\n" +
+			"it doesn't highlight newly added trailing whitespaces that naturally occur\n" +
+			"while typing text. It highlights only nasty forgotten trailing whitespaces.",
+	},
+	{
+		Name:    "ignorecase",
+		Type:    OptionBool,
+		Default: true,
+		Help:    "perform case-insensitive searches.",
+	},
+	{
+		Name:    "incsearch",
+		Type:    OptionBool,
+		Default: true,
+		Help:    "enable incremental search in \"Find\" prompt (matching as you type).",
+	},
+	{
+		Name:    "indentchar",
+		Type:    OptionString,
+		Default: " ",
+		Help: "sets the indentation character. This will not be inserted into\n" +
+			"files; it is only a visual indicator that whitespace is present. If set to a\n" +
+			"printing character, it functions as a subset of the \"show invisibles\"\n" +
+			"setting available in many other text editors. The color of this character is\n" +
+			"determined by the `indent-char` field in the current theme rather than the\n" +
+			"default text color.",
+		DefaultHelp: "` ` (space)",
+	},
+	{
+		Name:    "infobar",
+		Type:    OptionBool,
+		Scope:   ScopeGlobal,
+		Default: true,
+		Help: "enables the line at the bottom of the editor where messages are\n" +
+			"printed.",
+	},
+	{
+		Name:    "keepautoindent",
+		Type:    OptionBool,
+		Default: false,
+		Help: "when using autoindent, whitespace is added for you. This\n" +
+			"option determines if when you move to the next line without any insertions\n" +
+			"the whitespace that was added should be deleted to remove trailing\n" +
+			"whitespace. By default, the autoindent whitespace is deleted if the line\n" +
+			"was left empty.",
+	},
+	{
+		Name:    "keymenu",
+		Type:    OptionBool,
+		Scope:   ScopeGlobal,
+		Default: false,
+		Help: "display the nano-style key menu at the bottom of the screen. Note\n" +
+			"that ToggleKeyMenu is bound to `Alt-g` by default and this is displayed in\n" +
+			"the statusline. To disable the key binding, bind `Alt-g` to `None`.",
+	},
+	{
+		Name:    "matchbrace",
+		Type:    OptionBool,
+		Default: true,
+		Help: "show matching braces for '()', '{}', '[]' when the cursor\n" +
+			"is on a brace character or (if `matchbraceleft` is enabled) next to it.",
+	},
+	{
+		Name:    "matchbraceleft",
+		Type:    OptionBool,
+		Default: true,
+		Help: "simulate I-beam cursor behavior (cursor located not on a\n" +
+			"character but \"between\" characters): when showing matching braces, if there\n" +
+			"is no brace character directly under the cursor, match the brace character\n" +
+			"to the left of the cursor instead. Also when jumping to the matching brace,\n" +
+			"move the cursor either to the matching brace character or to the character\n" +
+			"next to it, depending on whether the initial cursor position was on the\n" +
+			"brace character or next to it (i.e. \"inside\" or \"outside\" the braces).\n" +
+			"With `matchbraceleft` disabled, micro will only match the brace directly\n" +
+			"under the cursor and will only jump to precisely to the matching brace.",
+	},
+	{
+		Name:    "matchbracestyle",
+		Type:    OptionString,
+		Default: "underline",
+		Choices: []string{"underline", "highlight"},
+		Help: "whether to underline or highlight matching braces when\n" +
+			"`matchbrace` is enabled. The color of highlight is determined by the `match-brace`\n" +
+			"field in the current theme. Possible values:\n" +
+			" * `underline`: underline matching braces.\n" +
+			" * `highlight`: use `match-brace` style from the current theme.",
+	},
+	{
+		Name:    "minimap",
+		Type:    OptionBool,
+		Default: false,
+		Help: "display a minimap on the right of the buffer: a downsampled view of\n" +
+			"the lines around the view, drawn with braille characters in the colors of\n" +
+			"the syntax highlighting. Clicking on the minimap jumps to the line.",
+	},
+	{
+		Name:    "mkparents",
+		Type:    OptionBool,
+		Default: false,
+		Help: "if a file is opened on a path that does not exist, the file\n" +
+			"cannot be saved because the parent directories don't exist. This option lets\n" +
+			"micro automatically create the parent directories in such a situation.",
+	},
+	{
+		Name:    "modeline",
+		Type:    OptionBool,
+		Default: true,
+		Help: "look for a Vim or Emacs modeline in the first and last 5 lines\n" +
+			"of a file, such as `vim: set ft=python ts=4 et :` or\n" +
+			"`-*- mode: python; tab-width: 4; indent-tabs-mode: nil -*-`. A modeline can\n" +
+			"set the filetype and the `tabsize`, `tabstospaces` and `fileformat`\n" +
+			"options for the buffer; these take precedence over `settings.json`.",
+	},
+	{
+		Name:    "mouse",
+		Type:    OptionBool,
+		Scope:   ScopeGlobal,
+		Default: true,
+		Help: "mouse support. When mouse support is disabled,\n" +
+			"usually the terminal will be able to access mouse events which can be useful\n" +
+			"if you want to copy from the terminal instead of from micro (if over ssh for\n" +
+			"example, because the terminal has access to the local clipboard and micro\n" +
+			"does not).",
+	},
+	{
+		Name:    "multiopen",
+		Type:    OptionString,
+		Scope:   ScopeGlobal,
+		Default: "tab",
+		Choices: []string{"tab", "hsplit", "vsplit"},
+		Help: "specifies how to layout multiple files opened at startup.\n" +
+			"Most useful as a command-line option, like `-multiopen vsplit`. Possible\n" +
+			"values correspond to commands (see `> help commands`) that open files:\n" +
+			" * `tab`: open each file in a separate tab.\n" +
+			" * `vsplit`: open files side-by-side.\n" +
+			" * `hsplit`: open files stacked top to bottom.",
+	},
+	{
+		Name:    "pageoverlap",
+		Type:    OptionNumber,
+		Default: float64(2),
+		Min:     bound(0),
+		Help: "the number of lines from the current view to keep in view\n" +
+			"when paging up or down. If this is set to 2, for instance, and you page\n" +
+			"down, the last two lines of the previous page will be the first two lines\n" +
+			"of the next page.",
+	},
+	{
+		Name:    "parsecursor",
+		Type:    OptionBool,
+		Scope:   ScopeGlobal,
+		Default: false,
+		Help: "if enabled, this will cause micro to parse filenames such as\n" +
+			"`file.txt:10:5` as requesting to open `file.txt` with the cursor at line 10\n" +
+			"and column 5. The column number can also be dropped to open the file at a\n" +
+			"given line and column 0. Note that with this option enabled it is not possible\n" +
+			"to open a file such as `file.txt:10:5`, where `:10:5` is part of the filename.\n" +
+			"It is also possible to open a file with a certain cursor location by using the\n" +
+			"`+LINE:COL` flag syntax. See `micro -help` for the command line options.",
+	},
+	{
+		Name:    "paste",
+		Type:    OptionBool,
+		Scope:   ScopeGlobal,
+		Default: false,
+		Help: "treat characters sent from the terminal in a single chunk as a paste\n" +
+			"event rather than a series of manual key presses. If you are pasting using\n" +
+			"the terminal keybinding (not `Ctrl-v`, which is micro's default paste\n" +
+			"keybinding) then it is a good idea to enable this option during the paste\n" +
+			"and disable once the paste is over. See `> help copypaste` for details about\n" +
+			"copying and pasting in a terminal environment.",
+	},
+	{
+		Name:    "permbackup",
+		Type:    OptionBool,
+		Default: false,
+		Help: "this option causes backups (see `backup` option) to be\n" +
+			"permanently saved. With permanent backups, micro will not remove backups when\n" +
+			"files are closed and will never apply them to existing files. Use this option\n" +
+			"if you are interested in manually managing your backup files.",
+	},
+	{
+		Name:    "pluginchannels",
+		Type:    OptionStringList,
+		Scope:   ScopeGlobal,
+		Default: []string{"https://raw.githubusercontent.com/micro-editor/plugin-channel/master/channel.json"},
+		Help: "list of URLs pointing to plugin channels for downloading and\n" +
+			"installing plugins. A plugin channel consists of a json file with links to\n" +
+			"plugin repos, which store information about plugin versions and download URLs.\n" +
+			"By default, this option points to the official plugin channel hosted on GitHub\n" +
+			"at https://github.com/micro-editor/plugin-channel.",
+	},
+	{
+		Name:    "pluginmaxerrors",
+		Type:    OptionNumber,
+		Scope:   ScopeGlobal,
+		Default: float64(5),
+		Min:     bound(0),
+		Help: "number of errors in a row after which a plugin's callbacks\n" +
+			"stop being called until micro is restarted or the plugin is enabled again\n" +
+			"with its option. If this option is set to `0`, plugins are never disabled.",
+	},
+	{
+		Name:    "pluginrepos",
+		Type:    OptionStringList,
+		Scope:   ScopeGlobal,
+		Default: []string{},
+		Help:    "a list of links to plugin repositories.",
+	},
+	{
+		Name:    "plugintimeout",
+		Type:    OptionNumber,
+		Scope:   ScopeGlobal,
+		Default: float64(5),
+		Min:     bound(0),
+		Help: "maximum number of seconds that a plugin's callback can run\n" +
+			"before it is stopped with an error. The time spent waiting for the user,\n" +
+			"in an interactive shell for example, is not counted. If this option is set\n" +
+			"to `0`, callbacks can run for as long as they want.",
+	},
+	{
+		Name:    "readonly",
+		Type:    OptionBool,
+		Scope:   ScopeLocal,
+		Default: false,
+		Help: "when enabled, disallows edits to the buffer. It is recommended\n" +
+			"to only ever set this option locally using `setlocal`.",
+	},
+	{
+		Name:    "relativeruler",
+		Type:    OptionBool,
+		Default: false,
+		Help: "make line numbers display relatively. If set to true, all\n" +
+			"lines except for the line that the cursor is located will display the distance\n" +
+			"from the cursor's line.",
+	},
+	{
+		Name:    "reload",
+		Type:    OptionString,
+		Default: "prompt",
+		Choices: []string{"prompt", "auto", "disabled"},
+		Help: "controls the reload behavior of the current buffer in case the file\n" +
+			"has changed. The available options are `prompt`, `auto` & `disabled`.",
+	},
+	{
+		Name:    "rmtrailingws",
+		Type:    OptionBool,
+		Default: false,
+		Help: "micro will automatically trim trailing whitespaces at ends of\n" +
+			"lines.\n" +
+			"Note: This setting overrides `keepautoindent` and isn't used at timed `autosave`\n" +
+			"or forced `autosave` in case the buffer didn't change. A manual save will\n" +
+			"involve the action regardless if the buffer has been changed or not.",
+	},
+	{
+		Name:    "ruler",
+		Type:    OptionBool,
+		Default: true,
+		Help:    "display line numbers.",
+	},
+	{
+		Name:    "savecursor",
+		Type:    OptionBool,
+		Default: false,
+		Help: "remember where the cursor was last time the file was opened and\n" +
+			"put it there when you open the file again. Information is saved to\n" +
+			"`~/.config/micro/buffers/`",
+	},
+	{
+		Name:    "savehistory",
+		Type:    OptionBool,
+		Scope:   ScopeGlobal,
+		Default: true,
+		Help: "remember command history and the recently opened files between\n" +
+			"closing and re-opening micro. Information is saved to\n" +
+			"`~/.config/micro/buffers/history` and `~/.config/micro/buffers/recent`.",
+	},
+	{
+		Name:    "saveundo",
+		Type:    OptionBool,
+		Default: false,
+		Help: "when this option is on, undo is saved even after you close a file\n" +
+			"so if you close and reopen a file, you can keep undoing. Information is\n" +
+			"saved to `~/.config/micro/buffers/`.",
+	},
+	{
+		Name:    "scrollbar",
+		Type:    OptionBool,
+		Default: false,
+		Help: "display a scroll bar. The scroll bar also works as an overview of the\n" +
+			"buffer: it marks the lines with errors and warnings, search matches (when\n" +
+			"they are highlighted), diff changes (when `diffgutter` is enabled) and\n" +
+			"cursors (when there are several), and clicking on it jumps to the line.",
+	},
+	{
+		Name:    "scrollbarchar",
+		Type:    OptionString,
+		Scope:   ScopeGlobal,
+		Default: "|",
+		Help:    "specifies the character used for displaying the scrollbar",
+	},
+	{
+		Name:    "scrollmargin",
+		Type:    OptionNumber,
+		Default: float64(3),
+		Min:     bound(0),
+		Help: "margin at which the view starts scrolling when the cursor\n" +
+			"approaches the edge of the view.",
+	},
+	{
+		Name:    "scrollspeed",
+		Type:    OptionNumber,
+		Default: float64(2),
+		Min:     bound(0),
+		Help:    "amount of lines to scroll for one scroll event.",
+	},
+	{
+		Name:    "smartpaste",
+		Type:    OptionBool,
+		Default: true,
+		Help: "add leading whitespace when pasting multiple lines.\n" +
+			"This will attempt to preserve the current indentation level when pasting an\n" +
+			"unindented block.",
+	},
+	{
+		Name:    "softwrap",
+		Type:    OptionBool,
+		Default: false,
+		Help:    "wrap lines that are too long to fit on the screen.",
+	},
+	{
+		Name:    "splitbottom",
+		Type:    OptionBool,
+		Default: true,
+		Help: "when a horizontal split is created, create it below the\n" +
+			"current split.",
+	},
+	{
+		Name:    "splitright",
+		Type:    OptionBool,
+		Default: true,
+		Help: "when a vertical split is created, create it to the right of the\n" +
+			"current split.",
+	},
+	{
+		Name:    "statusformatl",
+		Type:    OptionString,
+		Default: "$(filename) $(modified)$(overwrite)($(line),$(col)) $(status.paste)| ft:$(opt:filetype) | $(opt:fileformat) | $(opt:encoding)",
+		Help: "format string definition for the left-justified part of the\n" +
+			"statusline. Special directives should be placed inside `$()`. Special\n" +
+			"directives include: `filename`, `modified`, `line`, `col`, `lines`,\n" +
+			"`percentage`, `opt`, `overwrite`, `bind`.\n" +
+			"The `opt` and `bind` directives take either an option or an action afterward\n" +
+			"and fill in the value of the option or the key bound to the action.",
+		DefaultHelp: "`$(filename) $(modified)$(overwrite)($(line),$(col)) $(status.paste)|\n" +
+			"                    ft:$(opt:filetype) | $(opt:fileformat) | $(opt:encoding)`",
+	},
+	{
+		Name:    "statusformatr",
+		Type:    OptionString,
+		Default: "$(bind:ToggleKeyMenu): bindings, $(bind:ToggleHelp): help",
+		Help: "format string definition for the right-justified part of the\n" +
+			"statusline.",
+	},
+	{
+		Name:    "statusline",
+		Type:    OptionBool,
+		Default: true,
+		Help:    "display the status line at the bottom of the screen.",
+	},
+	{
+		Name:        "sucmd",
+		Type:        OptionString,
+		Scope:       ScopeGlobal,
+		Default:     "sudo",
+		Suggestions: []string{"sudo", "doas"},
+		Help: "specifies the super user command. On most systems this is \"sudo\" but\n" +
+			"on BSD it can be \"doas.\" This option can be customized and is only used when\n" +
+			"saving with su.",
+	},
+	{
+		Name:    "syntax",
+		Type:    OptionBool,
+		Default: true,
+		Help:    "enables syntax highlighting.",
+	},
+	{
+		Name:    "tabhighlight",
+		Type:    OptionBool,
+		Scope:   ScopeGlobal,
+		Default: false,
+		Help: "inverts the tab characters' (filename, save indicator, etc)\n" +
+			"colors with respect to the tab bar.",
+	},
+	{
+		Name:    "tabmovement",
+		Type:    OptionBool,
+		Default: false,
+		Help: "navigate spaces at the beginning of lines as if they are tabs\n" +
+			"(e.g. move over 4 spaces at once). This option only does anything if\n" +
+			"`tabstospaces` is on.",
+	},
+	{
+		Name:    "tabreverse",
+		Type:    OptionBool,
+		Scope:   ScopeGlobal,
+		Default: true,
+		Help:    "reverses the tab bar colors when active.",
+	},
+	{
+		Name:    "tabsize",
+		Type:    OptionNumber,
+		Default: float64(4),
+		Min:     bound(1),
+		Help:    "the size in spaces that a tab character should be displayed with.",
+	},
+	{
+		Name:    "tabstospaces",
+		Type:    OptionBool,
+		Default: false,
+		Help: "use spaces instead of tabs. Note: This option will be\n" +
+			"overridden by [the `ftoptions` plugin](https://github.com/zyedidia/micro/blob/master/runtime/plugins/ftoptions/ftoptions.lua)\n" +
+			"for certain filetypes. To disable this behavior, add `\"ftoptions\": false` to\n" +
+			"your config. See [issue #2213](https://github.com/zyedidia/micro/issues/2213)\n" +
+			"for more details.",
+	},
+	{
+		Name:    "useprimary",
+		Type:    OptionBool,
+		Default: true,
+		Help: "(only useful on unix) defines whether or not micro will use the\n" +
+			"primary clipboard to copy selections in the background. This does not affect\n" +
+			"the normal clipboard using `Ctrl-c` and `Ctrl-v`.",
+	},
+	{
+		Name:    "wordwrap",
+		Type:    OptionBool,
+		Default: false,
+		Help: "wrap long lines by words, i.e. break at spaces. This option\n" +
+			"only does anything if `softwrap` is on.",
+	},
+	{
+		Name:    "xterm",
+		Type:    OptionBool,
+		Scope:   ScopeGlobal,
+		Default: false,
+		Help: "micro will assume that the terminal it is running in conforms to\n" +
+			"`xterm-256color` regardless of what the `$TERM` variable actually contains.\n" +
+			"Enabling this option may cause unwanted effects if your terminal in fact\n" +
+			"does not conform to the `xterm-256color` standard.",
+	},
+}
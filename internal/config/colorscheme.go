@@ -2,11 +2,13 @@ package config
 
 import (
 	"errors"
+	"fmt"
 	"regexp"
 	"strconv"
 	"strings"
 
 	"github.com/micro-editor/tcell/v2"
+	"github.com/zyedidia/micro/v2/internal/util"
 )
 
 // DefStyle is Micro's default style
@@ -15,6 +17,19 @@ var DefStyle tcell.Style = tcell.StyleDefault
 // Colorscheme is the current colorscheme
 var Colorscheme map[string]tcell.Style
 
+// ColorDepth is the number of colors the terminal can display. If it is
+// non-zero, colors which the terminal cannot display are replaced by the
+// closest color of its palette when a colorscheme is loaded.
+var ColorDepth int
+
+var (
+	colorParser   = regexp.MustCompile(`color-link\s+(\S*)\s+"(.*)"`)
+	includeParser = regexp.MustCompile(`include\s+"(.*)"`)
+	defineParser  = regexp.MustCompile(`^\s*define\s+([\w-]+)\s+"?([^"]*?)"?\s*$`)
+	colorToken    = regexp.MustCompile(`#?[\w-]+`)
+	colorFunction = regexp.MustCompile(`(lighten|darken)\(\s*([^,()\s]+)\s*,\s*(\d+)\s*%?\s*\)`)
+)
+
 // GetColor takes in a syntax group and returns the colorscheme's style for that group
 func GetColor(color string) tcell.Style {
 	st := DefStyle
@@ -68,6 +83,11 @@ func LoadDefaultColorscheme() (map[string]tcell.Style, error) {
 
 // LoadColorscheme loads the given colorscheme from a directory
 func LoadColorscheme(colorschemeName string, parsedColorschemes *[]string) (map[string]tcell.Style, error) {
+	return loadColorscheme(colorschemeName, parsedColorschemes, make(map[string]string))
+}
+
+// loadColorscheme loads a colorscheme, adding the colors it defines to vars
+func loadColorscheme(colorschemeName string, parsedColorschemes *[]string, vars map[string]string) (map[string]tcell.Style, error) {
 	c := make(map[string]tcell.Style)
 	file := FindRuntimeFile(RTColorscheme, colorschemeName)
 	if file == nil {
@@ -77,7 +97,7 @@ func LoadColorscheme(colorschemeName string, parsedColorschemes *[]string) (map[
 		return c, errors.New("Error loading colorscheme: " + err.Error())
 	} else {
 		var err error
-		c, err = ParseColorscheme(file.Name(), string(data), parsedColorschemes)
+		c, err = parseColorscheme(file.Name(), string(data), parsedColorschemes, vars)
 		if err != nil {
 			return c, err
 		}
@@ -89,10 +109,17 @@ func LoadColorscheme(colorschemeName string, parsedColorschemes *[]string) (map[
 // Colorschemes are made up of color-link statements linking a color group to a list of colors
 // For example, color-link keyword (blue,red) makes all keywords have a blue foreground and
 // red background
+// Colors can be given a name with define statements, for example
+// define accent "#ff8800", and derived with lighten(accent, 20%) and
+// darken(accent, 20%)
 func ParseColorscheme(name string, text string, parsedColorschemes *[]string) (map[string]tcell.Style, error) {
+	return parseColorscheme(name, text, parsedColorschemes, make(map[string]string))
+}
+
+// parseColorscheme parses a colorscheme using and extending the color
+// variables in vars, which are shared with included colorschemes
+func parseColorscheme(name string, text string, parsedColorschemes *[]string, vars map[string]string) (map[string]tcell.Style, error) {
 	var err error
-	colorParser := regexp.MustCompile(`color-link\s+(\S*)\s+"(.*)"`)
-	includeParser := regexp.MustCompile(`include\s+"(.*)"`)
 	lines := strings.Split(text, "\n")
 	c := make(map[string]tcell.Style)
 
@@ -120,7 +147,7 @@ lineLoop:
 						continue lineLoop
 					}
 				}
-				includeScheme, err := LoadColorscheme(include, parsedColorschemes)
+				includeScheme, err := loadColorscheme(include, parsedColorschemes, vars)
 				if err != nil {
 					return c, err
 				}
@@ -131,10 +158,25 @@ lineLoop:
 			continue
 		}
 
+		matches = defineParser.FindSubmatch([]byte(line))
+		if len(matches) == 3 {
+			value, verr := expandColors(string(matches[2]), vars)
+			if verr != nil {
+				err = verr
+				continue
+			}
+			vars[string(matches[1])] = value
+			continue
+		}
+
 		matches = colorParser.FindSubmatch([]byte(line))
 		if len(matches) == 3 {
 			link := string(matches[1])
-			colors := string(matches[2])
+			colors, verr := expandColors(string(matches[2]), vars)
+			if verr != nil {
+				err = verr
+				continue
+			}
 
 			style := StringToStyle(colors)
 			c[link] = style
@@ -150,6 +192,45 @@ lineLoop:
 	return c, err
 }
 
+// expandColors replaces the color variables in a color-link or define
+// value by their values and evaluates lighten and darken expressions
+func expandColors(str string, vars map[string]string) (string, error) {
+	str = colorToken.ReplaceAllStringFunc(str, func(tok string) string {
+		if v, ok := vars[tok]; ok {
+			return v
+		}
+		return tok
+	})
+
+	var err error
+	for colorFunction.MatchString(str) {
+		str = colorFunction.ReplaceAllStringFunc(str, func(expr string) string {
+			m := colorFunction.FindStringSubmatch(expr)
+			c, ok := stringToColor(m[2])
+			if !ok || c == tcell.ColorDefault {
+				err = errors.New("Invalid color in " + expr)
+				return "default"
+			}
+			amount, _ := strconv.Atoi(m[3])
+			if amount > 100 {
+				amount = 100
+			}
+
+			rgb := [3]int32{}
+			rgb[0], rgb[1], rgb[2] = c.RGB()
+			for i, v := range rgb {
+				if m[1] == "lighten" {
+					rgb[i] = v + (255-v)*int32(amount)/100
+				} else {
+					rgb[i] = v * int32(100-amount) / 100
+				}
+			}
+			return fmt.Sprintf("#%02x%02x%02x", rgb[0], rgb[1], rgb[2])
+		})
+	}
+	return str, err
+}
+
 // StringToStyle returns a style from a string
 // The strings must be in the format "extra foregroundcolor,backgroundcolor"
 // The 'extra' can be bold, reverse, italic or underline
@@ -202,7 +283,101 @@ func StringToStyle(str string) tcell.Style {
 
 // StringToColor returns a tcell color from a string representation of a color
 // We accept either bright... or light... to mean the brighter version of a color
+// If ColorDepth is set, the color is downsampled to the terminal's palette
 func StringToColor(str string) (tcell.Color, bool) {
+	c, ok := stringToColor(str)
+	if ok {
+		c = downsampleColor(c)
+	}
+	return c, ok
+}
+
+// downsampleColor returns the color number, as given to GetColor256, of
+// the color closest to c that the terminal can display, or c itself if the
+// terminal can display it. With 256 colors the 6x6x6 color cube and the
+// grayscale ramp are used, since the first 16 colors are set by the user,
+// and otherwise the standard values of the first 16 (or 8) colors.
+func downsampleColor(c tcell.Color) tcell.Color {
+	if ColorDepth <= 0 || ColorDepth > 256 || c == tcell.ColorDefault {
+		return c
+	}
+	if !c.IsRGB() && int(c-tcell.ColorValid) < ColorDepth {
+		return c
+	}
+
+	r, g, b := c.RGB()
+	var n int
+	if ColorDepth == 256 {
+		n = color256(r, g, b)
+	} else {
+		n = colorStandard(r, g, b, util.Min(ColorDepth, len(standardColors)))
+	}
+	if n == 0 {
+		// GetColor256(0) is the default color
+		return tcell.ColorBlack
+	}
+	return GetColor256(n)
+}
+
+// cubeLevels are the values of the components of the 6x6x6 color cube of
+// the 256 color palette
+var cubeLevels = [6]int32{0, 95, 135, 175, 215, 255}
+
+// standardColors are the usual values of the first 16 colors of the palette
+var standardColors = [16][3]int32{
+	{0, 0, 0}, {128, 0, 0}, {0, 128, 0}, {128, 128, 0},
+	{0, 0, 128}, {128, 0, 128}, {0, 128, 128}, {192, 192, 192},
+	{128, 128, 128}, {255, 0, 0}, {0, 255, 0}, {255, 255, 0},
+	{0, 0, 255}, {255, 0, 255}, {0, 255, 255}, {255, 255, 255},
+}
+
+func colorDistance(r1, g1, b1, r2, g2, b2 int32) int32 {
+	return (r1-r2)*(r1-r2) + (g1-g2)*(g1-g2) + (b1-b2)*(b1-b2)
+}
+
+// color256 returns the number of the color of the color cube or of the
+// grayscale ramp of the 256 color palette closest to the given color
+func color256(r, g, b int32) int {
+	level := func(v int32) int {
+		switch {
+		case v < 48:
+			return 0
+		case v < 115:
+			return 1
+		default:
+			return int(v-35) / 40
+		}
+	}
+	ri, gi, bi := level(r), level(g), level(b)
+	cube := 16 + 36*ri + 6*gi + bi
+	cubeDist := colorDistance(r, g, b, cubeLevels[ri], cubeLevels[gi], cubeLevels[bi])
+
+	gray := ((r+g+b)/3 - 8) / 10
+	if gray < 0 {
+		gray = 0
+	} else if gray > 23 {
+		gray = 23
+	}
+	v := 8 + 10*gray
+	if colorDistance(r, g, b, v, v, v) < cubeDist {
+		return 232 + int(gray)
+	}
+	return cube
+}
+
+// colorStandard returns the number of the closest of the first n standard
+// colors to the given color
+func colorStandard(r, g, b int32, n int) int {
+	best, bestDist := 0, int32(-1)
+	for i, c := range standardColors[:n] {
+		if d := colorDistance(r, g, b, c[0], c[1], c[2]); bestDist < 0 || d < bestDist {
+			best, bestDist = i, d
+		}
+	}
+	return best
+}
+
+func stringToColor(str string) (tcell.Color, bool) {
 	switch str {
 	case "black":
 		return tcell.ColorBlack, true
@@ -72,3 +72,60 @@ color-link constant.string.char "#BDE6AD,#282828"`
 	assert.Equal(t, tcell.NewRGBColor(117, 113, 94), fg)
 	assert.Equal(t, tcell.NewRGBColor(40, 40, 40), bg)
 }
+
+func TestColorschemeVariables(t *testing.T) {
+	testColorscheme := `define bg "#282828"
+define accent #ff8800
+color-link default "#F8F8F2,bg"
+color-link statement "bold accent,bg"
+color-link comment "lighten(accent, 50%),darken(bg, 50%)"`
+
+	c, err := ParseColorscheme("testColorscheme", testColorscheme, nil)
+	assert.Nil(t, err)
+
+	fg, bg, attr := c["statement"].Decompose()
+	assert.Equal(t, tcell.NewRGBColor(255, 136, 0), fg)
+	assert.Equal(t, tcell.NewRGBColor(40, 40, 40), bg)
+	assert.NotEqual(t, 0, attr&tcell.AttrBold)
+
+	fg, bg, _ = c["comment"].Decompose()
+	assert.Equal(t, tcell.NewRGBColor(255, 195, 127), fg)
+	assert.Equal(t, tcell.NewRGBColor(20, 20, 20), bg)
+
+	_, err = ParseColorscheme("testColorscheme", `color-link comment "lighten(nothing, 10%)"`, nil)
+	assert.NotNil(t, err)
+}
+
+func TestColorDownsampling(t *testing.T) {
+	ColorDepth = 256
+	defer func() { ColorDepth = 0 }()
+
+	c, ok := StringToColor("#ff0000")
+	assert.True(t, ok)
+	assert.False(t, c.IsRGB())
+	r, g, b := c.RGB()
+	assert.Equal(t, [3]int32{255, 0, 0}, [3]int32{r, g, b})
+
+	c, _ = StringToColor("#808080")
+	assert.Equal(t, GetColor256(244), c)
+	c, _ = StringToColor("#000000")
+	assert.Equal(t, GetColor256(16), c)
+	c, _ = StringToColor("#5f87af")
+	assert.Equal(t, GetColor256(67), c)
+
+	ColorDepth = 16
+	c, _ = StringToColor("#000000")
+	assert.Equal(t, tcell.ColorBlack, c)
+	c, _ = StringToColor("#c0c0c0")
+	assert.Equal(t, GetColor256(7), c)
+	c, _ = StringToColor("196")
+	assert.Equal(t, tcell.ColorRed, c)
+
+	ColorDepth = 88
+	c, _ = StringToColor("#ffff00")
+	assert.Equal(t, tcell.ColorYellow, c)
+	c, _ = StringToColor("196")
+	assert.Equal(t, tcell.ColorRed, c)
+	c, _ = StringToColor("brightblue")
+	assert.Equal(t, tcell.ColorBlue, c)
+}
@@ -0,0 +1,299 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// An EditorConfigSetting is a micro option set by an .editorconfig file
+type EditorConfigSetting struct {
+	Value interface{}
+	// Origin describes the file and the section which set the value
+	Origin string
+}
+
+// editorConfigSection is a section of an .editorconfig file
+type editorConfigSection struct {
+	glob  string
+	regex *regexp.Regexp
+	props map[string]string
+}
+
+// editorConfigFile is a parsed .editorconfig file
+type editorConfigFile struct {
+	path     string
+	root     bool
+	sections []editorConfigSection
+}
+
+// parseEditorConfig parses the text of an .editorconfig file located in
+// the directory dir
+func parseEditorConfig(path string, text string) *editorConfigFile {
+	f := &editorConfigFile{path: path}
+	dir := filepath.ToSlash(filepath.Dir(path))
+
+	var cur *editorConfigSection
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+
+		if line[0] == '[' && line[len(line)-1] == ']' {
+			glob := line[1 : len(line)-1]
+			regex, err := regexp.Compile(editorConfigGlobToRegex(dir, glob))
+			if err != nil {
+				cur = nil
+				continue
+			}
+			f.sections = append(f.sections, editorConfigSection{glob, regex, make(map[string]string)})
+			cur = &f.sections[len(f.sections)-1]
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			key, value, ok = strings.Cut(line, ":")
+		}
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		if cur == nil {
+			// preamble before the first section
+			if key == "root" {
+				f.root = strings.ToLower(value) == "true"
+			}
+			continue
+		}
+		cur.props[key] = value
+	}
+	return f
+}
+
+// editorConfigGlobToRegex converts an EditorConfig section glob for an
+// .editorconfig file in dir to a regular expression matching absolute
+// slash-separated paths. Globs without a slash match file names in any
+// subdirectory, other globs are relative to dir.
+func editorConfigGlobToRegex(dir, glob string) string {
+	var re strings.Builder
+
+	re.WriteString("^")
+	re.WriteString(regexp.QuoteMeta(strings.TrimSuffix(dir, "/")))
+	if strings.Contains(glob, "/") {
+		if !strings.HasPrefix(glob, "/") {
+			re.WriteString("/")
+		}
+	} else {
+		re.WriteString("/(?:.*/)?")
+	}
+
+	braces := 0
+	inClass := false
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case c == '\\' && i+1 < len(glob):
+			i++
+			re.WriteString(regexp.QuoteMeta(string(glob[i])))
+		case inClass:
+			if c == ']' {
+				inClass = false
+			}
+			if c == '\\' || c == '^' && glob[i-1] != '[' {
+				re.WriteByte('\\')
+			}
+			re.WriteByte(c)
+		case c == '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				re.WriteString(".*")
+				i++
+			} else {
+				re.WriteString("[^/]*")
+			}
+		case c == '?':
+			re.WriteString("[^/]")
+		case c == '[':
+			if j := strings.IndexByte(glob[i+1:], ']'); j >= 0 && !strings.Contains(glob[i+1:i+1+j], "/") {
+				inClass = true
+				re.WriteByte('[')
+				if i+1 < len(glob) && glob[i+1] == '!' {
+					re.WriteByte('^')
+					i++
+				}
+			} else {
+				re.WriteString("\\[")
+			}
+		case c == '{':
+			end := strings.IndexByte(glob[i:], '}')
+			if end < 0 {
+				re.WriteString("\\{")
+				break
+			}
+			if r := editorConfigRange(glob[i+1 : i+end]); r != "" {
+				re.WriteString(r)
+				i += end
+				break
+			}
+			if !strings.Contains(glob[i:i+end], ",") {
+				// braces without alternatives are literal
+				re.WriteString(regexp.QuoteMeta(glob[i : i+end+1]))
+				i += end
+				break
+			}
+			braces++
+			re.WriteString("(?:")
+		case c == '}' && braces > 0:
+			braces--
+			re.WriteString(")")
+		case c == ',' && braces > 0:
+			re.WriteString("|")
+		default:
+			re.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	re.WriteString("$")
+	return re.String()
+}
+
+// editorConfigRange returns a regex matching the integers of a {num1..num2}
+// glob, or an empty string if s is not a range
+func editorConfigRange(s string) string {
+	a, b, ok := strings.Cut(s, "..")
+	if !ok {
+		return ""
+	}
+	lo, err1 := strconv.Atoi(a)
+	hi, err2 := strconv.Atoi(b)
+	if err1 != nil || err2 != nil {
+		return ""
+	}
+	if lo > hi {
+		lo, hi = hi, lo
+	}
+	if hi-lo > 1000 {
+		// too large to enumerate, match any integer
+		return "[+-]?[0-9]+"
+	}
+	nums := make([]string, 0, hi-lo+1)
+	for n := lo; n <= hi; n++ {
+		nums = append(nums, regexp.QuoteMeta(strconv.Itoa(n)))
+	}
+	return "(?:" + strings.Join(nums, "|") + ")"
+}
+
+// editorConfigProperties returns the EditorConfig properties which apply
+// to the file at path, along with the file and section setting each one.
+// The .editorconfig files are searched from the directory of path upwards
+// until one has root = true.
+func editorConfigProperties(path string) (map[string]string, map[string]string) {
+	var files []*editorConfigFile
+	dir := filepath.Dir(path)
+	for {
+		name := filepath.Join(dir, ".editorconfig")
+		if data, err := os.ReadFile(name); err == nil {
+			f := parseEditorConfig(name, string(data))
+			files = append(files, f)
+			if f.root {
+				break
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+
+	props := make(map[string]string)
+	origins := make(map[string]string)
+	target := filepath.ToSlash(path)
+	// files closer to path and later sections take precedence
+	for i := len(files) - 1; i >= 0; i-- {
+		for _, s := range files[i].sections {
+			if !s.regex.MatchString(target) {
+				continue
+			}
+			for k, v := range s.props {
+				props[k] = v
+				origins[k] = fmt.Sprintf("%s [%s]", files[i].path, s.glob)
+			}
+		}
+	}
+	return props, origins
+}
+
+// EditorConfig returns the micro options set by the .editorconfig files
+// which apply to the file at the given absolute path
+func EditorConfig(path string) map[string]EditorConfigSetting {
+	props, origins := editorConfigProperties(path)
+	settings := make(map[string]EditorConfigSetting)
+
+	set := func(option string, value interface{}, prop string) {
+		if OptionIsValid(option, value) == nil {
+			settings[option] = EditorConfigSetting{value, origins[prop]}
+		}
+	}
+	get := func(prop string) string {
+		v := strings.ToLower(props[prop])
+		if v == "unset" {
+			return ""
+		}
+		return v
+	}
+
+	style := get("indent_style")
+	switch style {
+	case "space":
+		set("tabstospaces", true, "indent_style")
+	case "tab":
+		set("tabstospaces", false, "indent_style")
+	}
+
+	// micro has a single tabsize option for the width of tabs and of
+	// indentation, use the one which matters for the indent style
+	sizeProp := "indent_size"
+	if style == "tab" && get("tab_width") != "" || get("indent_size") == "tab" || get("indent_size") == "" {
+		sizeProp = "tab_width"
+	}
+	if n, err := strconv.Atoi(get(sizeProp)); err == nil {
+		set("tabsize", float64(n), sizeProp)
+	}
+
+	switch get("end_of_line") {
+	case "lf":
+		set("fileformat", "unix", "end_of_line")
+	case "crlf":
+		set("fileformat", "dos", "end_of_line")
+	}
+
+	switch charset := get("charset"); charset {
+	case "":
+	case "utf-8-bom":
+		set("encoding", "utf-8", "charset")
+	default:
+		set("encoding", charset, "charset")
+	}
+
+	for prop, option := range map[string]string{
+		"trim_trailing_whitespace": "rmtrailingws",
+		"insert_final_newline":     "eofnewline",
+	} {
+		switch get(prop) {
+		case "true":
+			set(option, true, prop)
+		case "false":
+			set(option, false, prop)
+		}
+	}
+
+	return settings
+}
@@ -0,0 +1,72 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEditorConfigGlob(t *testing.T) {
+	match := func(glob, path string) bool {
+		return regexp.MustCompile(editorConfigGlobToRegex("/p", glob)).MatchString(path)
+	}
+
+	assert.True(t, match("*", "/p/a/b.go"))
+	assert.True(t, match("*.go", "/p/a/b.go"))
+	assert.False(t, match("*.go", "/p/a/b.py"))
+	assert.True(t, match("*.{js,ts}", "/p/x.ts"))
+	assert.False(t, match("*.{js,ts}", "/p/x.rs"))
+	assert.True(t, match("lib/*.c", "/p/lib/x.c"))
+	assert.False(t, match("lib/*.c", "/p/lib/sub/x.c"))
+	assert.True(t, match("lib/**.c", "/p/lib/sub/x.c"))
+	assert.True(t, match("/Makefile", "/p/Makefile"))
+	assert.False(t, match("/Makefile", "/p/sub/Makefile"))
+	assert.True(t, match("file[0-9].txt", "/p/file3.txt"))
+	assert.False(t, match("file[!0-9].txt", "/p/file3.txt"))
+	assert.True(t, match("v{1..12}.txt", "/p/v10.txt"))
+	assert.False(t, match("v{1..12}.txt", "/p/v13.txt"))
+}
+
+func TestEditorConfig(t *testing.T) {
+	dir := t.TempDir()
+	sub := filepath.Join(dir, "sub")
+	assert.Nil(t, os.Mkdir(sub, 0755))
+
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, ".editorconfig"), []byte(`root = true
+
+[*]
+indent_style = space
+indent_size = 4
+end_of_line = lf
+insert_final_newline = true
+
+[*.go]
+indent_style = tab
+tab_width = 8
+`), 0644))
+	assert.Nil(t, os.WriteFile(filepath.Join(sub, ".editorconfig"), []byte(`[*.go]
+trim_trailing_whitespace = true
+charset = latin1
+end_of_line = crlf
+`), 0644))
+
+	s := EditorConfig(filepath.Join(sub, "main.go"))
+	assert.Equal(t, false, s["tabstospaces"].Value)
+	assert.Equal(t, float64(8), s["tabsize"].Value)
+	assert.Equal(t, "dos", s["fileformat"].Value)
+	assert.Equal(t, "latin1", s["encoding"].Value)
+	assert.Equal(t, true, s["rmtrailingws"].Value)
+	assert.Equal(t, true, s["eofnewline"].Value)
+	assert.Equal(t, filepath.Join(sub, ".editorconfig")+" [*.go]", s["fileformat"].Origin)
+	assert.Equal(t, filepath.Join(dir, ".editorconfig")+" [*]", s["eofnewline"].Origin)
+
+	s = EditorConfig(filepath.Join(dir, "README.md"))
+	assert.Equal(t, true, s["tabstospaces"].Value)
+	assert.Equal(t, float64(4), s["tabsize"].Value)
+	assert.Equal(t, "unix", s["fileformat"].Value)
+	_, ok := s["encoding"]
+	assert.False(t, ok)
+}
@@ -0,0 +1,128 @@
+package config
+
+import (
+	"errors"
+	"sort"
+
+	lua "github.com/yuin/gopher-lua"
+	ulua "github.com/zyedidia/micro/v2/internal/lua"
+	luar "layeh.com/gopher-luar"
+)
+
+// The events published on the event bus. Their payload is described in the
+// "plugins" help topic.
+const (
+	EvBufferOpen          = "BufferOpen"
+	EvBufferClose         = "BufferClose"
+	EvBufferSaved         = "BufferSaved"
+	EvBufferSaveFailed    = "BufferSaveFailed"
+	EvBufferReloaded      = "BufferReloaded"
+	EvBufferOptionChanged = "BufferOptionChanged"
+	EvBufPaneOpen         = "BufPaneOpen"
+	EvPaneActivated       = "PaneActivated"
+	EvTabSwitched         = "TabSwitched"
+	EvFocusGained         = "FocusGained"
+	EvFocusLost           = "FocusLost"
+	EvResize              = "Resize"
+	EvIdle                = "Idle"
+)
+
+// EventNames is the list of the events published on the event bus
+var EventNames = []string{
+	EvBufferOpen, EvBufferClose, EvBufferSaved, EvBufferSaveFailed,
+	EvBufferReloaded, EvBufferOptionChanged, EvBufPaneOpen, EvPaneActivated,
+	EvTabSwitched, EvFocusGained, EvFocusLost, EvResize, EvIdle,
+}
+
+// An Event is published on the event bus when something happens in the
+// editor, with a payload depending on its name
+type Event struct {
+	Name string
+	Data map[string]interface{}
+}
+
+// A subscriber is called when an event is published
+type subscriber struct {
+	id       int
+	priority int
+	fn       func(e Event) error
+}
+
+var (
+	subscribers  = make(map[string][]*subscriber)
+	subscriberID int
+)
+
+// IsEventName returns whether the given name is the name of an event
+func IsEventName(name string) bool {
+	return containsString(EventNames, name)
+}
+
+// Subscribe registers a function which is called when the given event is
+// published. The subscribers with a lower priority are called first, and
+// the ones with the same priority in the order in which they subscribed.
+// Subscribe returns a function which removes the subscription.
+func Subscribe(event string, priority int, fn func(e Event) error) func() {
+	subscriberID++
+	id := subscriberID
+	subs := append(subscribers[event], &subscriber{id, priority, fn})
+	sort.SliceStable(subs, func(i, j int) bool {
+		return subs[i].priority < subs[j].priority
+	})
+	subscribers[event] = subs
+
+	return func() {
+		subs := subscribers[event]
+		for i, s := range subs {
+			if s.id == id {
+				subscribers[event] = append(subs[:i:i], subs[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// SubscribeLua subscribes a Lua function of a plugin to an event. The
+// function is called with a table containing the event's payload, and the
+// subscription is removed when the plugin is unloaded.
+func SubscribeLua(p *Plugin, event string, priority int, fn *lua.LFunction) (func(), error) {
+	if !IsEventName(event) {
+		return nil, errors.New("Unknown event " + event)
+	}
+	unsubscribe := Subscribe(event, priority, func(e Event) error {
+		payload := ulua.L.NewTable()
+		for k, v := range e.Data {
+			payload.RawSetString(k, luar.New(ulua.L, v))
+		}
+		if p == nil {
+			return ulua.L.CallByParam(lua.P{Fn: fn, NRet: 0, Protect: true}, payload)
+		}
+		if !p.IsLoaded() {
+			return nil
+		}
+		_, err := p.CallFunction(event+" handler", fn, payload)
+		if err != nil {
+			return errors.New("Plugin " + p.Name + ": " + err.Error())
+		}
+		return nil
+	})
+	if p != nil {
+		p.OnUnload(unsubscribe)
+	}
+	return unsubscribe, nil
+}
+
+// Publish calls the subscribers of an event with its payload, and returns
+// the last error of the subscribers
+func Publish(event string, data map[string]interface{}) error {
+	var reterr error
+	e := Event{event, data}
+	// a subscriber may unsubscribe while the event is published
+	subs := append([]*subscriber{}, subscribers[event]...)
+	for _, s := range subs {
+		if err := s.fn(e); err != nil {
+			reterr = err
+		}
+	}
+	return reterr
+}
@@ -0,0 +1,39 @@
+package config
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventBus(t *testing.T) {
+	var calls []string
+	unsub1 := Subscribe(EvBufferSaved, 0, func(e Event) error {
+		calls = append(calls, "first:"+e.Data["path"].(string))
+		return nil
+	})
+	unsub2 := Subscribe(EvBufferSaved, -1, func(e Event) error {
+		calls = append(calls, "early")
+		return errors.New("failed")
+	})
+	unsub3 := Subscribe(EvBufferSaved, 0, func(e Event) error {
+		calls = append(calls, "second")
+		return nil
+	})
+
+	err := Publish(EvBufferSaved, map[string]interface{}{"path": "a.txt"})
+	assert.EqualError(t, err, "failed")
+	assert.Equal(t, []string{"early", "first:a.txt", "second"}, calls)
+
+	calls = nil
+	unsub2()
+	unsub1()
+	assert.NoError(t, Publish(EvBufferSaved, map[string]interface{}{"path": "a.txt"}))
+	assert.Equal(t, []string{"second"}, calls)
+
+	unsub3()
+	assert.Empty(t, subscribers[EvBufferSaved])
+	assert.True(t, IsEventName(EvIdle))
+	assert.False(t, IsEventName("onSave"))
+}
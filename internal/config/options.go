@@ -0,0 +1,475 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// OptionType is the type of the value of an option
+type OptionType int
+
+const (
+	OptionBool OptionType = iota
+	OptionNumber
+	OptionString
+	OptionStringList
+)
+
+func (t OptionType) String() string {
+	switch t {
+	case OptionBool:
+		return "boolean"
+	case OptionNumber:
+		return "number"
+	case OptionString:
+		return "string"
+	case OptionStringList:
+		return "list of strings"
+	}
+	return "unknown"
+}
+
+// OptionScope tells whether an option can be set globally, for a single
+// buffer or both
+type OptionScope int
+
+const (
+	// ScopeCommon options have a global value which can be overridden
+	// for each buffer
+	ScopeCommon OptionScope = iota
+	// ScopeGlobal options can only be set globally
+	ScopeGlobal
+	// ScopeLocal options are only ever set for a single buffer
+	ScopeLocal
+)
+
+// An Option describes a setting: its type, the values it accepts, where it
+// can be set, its default value and its documentation
+type Option struct {
+	Name    string
+	Type    OptionType
+	Scope   OptionScope
+	Default interface{}
+
+	// Min and Max bound the value of a number option if they are not nil
+	Min, Max *float64
+	// Choices are the only values accepted by a string option, if any
+	Choices []string
+	// Suggestions are completed for a string option but not enforced
+	Suggestions []string
+	// Validator checks the value further, for example against the list
+	// of colorschemes
+	Validator func(option string, value interface{}) error
+
+	// Help is the markdown documentation of the option shown in
+	// `help options`, without the option name
+	Help string
+	// DefaultHelp replaces the default value in the documentation when it
+	// is better explained in words
+	DefaultHelp string
+	// Plugin is the name of the plugin which registered the option, or an
+	// empty string for micro's own options
+	Plugin string
+}
+
+// bound returns a pointer to f, for the Min and Max fields of an Option
+func bound(f float64) *float64 {
+	return &f
+}
+
+// optionRegistry contains all the options known to micro
+var optionRegistry = make(map[string]*Option)
+
+func init() {
+	for _, o := range builtinOptions {
+		if err := RegisterOption(o); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// RegisterOption adds an option to the registry, replacing any option with
+// the same name, and makes its default value available in GlobalSettings
+func RegisterOption(o *Option) error {
+	if o.Name == "" {
+		return errors.New("Option has no name")
+	}
+	if err := o.validateValue(o.Default); err != nil {
+		return errors.New("Invalid default value for " + o.Name + ": " + err.Error())
+	}
+
+	delete(defaultCommonSettings, o.Name)
+	delete(DefaultGlobalOnlySettings, o.Name)
+	for i, s := range LocalSettings {
+		if s == o.Name {
+			LocalSettings = append(LocalSettings[:i], LocalSettings[i+1:]...)
+			break
+		}
+	}
+
+	switch o.Scope {
+	case ScopeGlobal:
+		DefaultGlobalOnlySettings[o.Name] = o.Default
+	case ScopeLocal:
+		LocalSettings = append(LocalSettings, o.Name)
+		defaultCommonSettings[o.Name] = o.Default
+	default:
+		defaultCommonSettings[o.Name] = o.Default
+	}
+
+	if len(o.Choices) > 0 {
+		OptionChoices[o.Name] = o.Choices
+	} else {
+		delete(OptionChoices, o.Name)
+	}
+
+	optionRegistry[o.Name] = o
+	if GlobalSettings != nil {
+		if _, ok := GlobalSettings[o.Name]; !ok {
+			GlobalSettings[o.Name] = o.Default
+		}
+	}
+	return nil
+}
+
+// UnregisterOption removes an option from the registry. Its value stays in
+// GlobalSettings if the user has set it, so that it is kept if the option
+// is registered again.
+func UnregisterOption(name string) {
+	delete(optionRegistry, name)
+	delete(defaultCommonSettings, name)
+	delete(DefaultGlobalOnlySettings, name)
+	delete(OptionChoices, name)
+	for i, s := range LocalSettings {
+		if s == name {
+			LocalSettings = append(LocalSettings[:i], LocalSettings[i+1:]...)
+			break
+		}
+	}
+	if _, ok := ParsedSettings()[name]; !ok && !ModifiedSettings[name] {
+		delete(GlobalSettings, name)
+	}
+}
+
+// LookupOption returns the registered option with the given name, or nil
+func LookupOption(name string) *Option {
+	return optionRegistry[name]
+}
+
+// Options returns all the registered options sorted by name
+func Options() []*Option {
+	opts := make([]*Option, 0, len(optionRegistry))
+	for _, o := range optionRegistry {
+		opts = append(opts, o)
+	}
+	sort.Slice(opts, func(i, j int) bool {
+		return opts[i].Name < opts[j].Name
+	})
+	return opts
+}
+
+// Validate checks that a value has the type of the option, is in its
+// range or among its choices and passes its validator
+func (o *Option) Validate(value interface{}) error {
+	if err := o.validateValue(value); err != nil {
+		return err
+	}
+	if o.Validator != nil {
+		return o.Validator(o.Name, value)
+	}
+	return nil
+}
+
+// validateValue checks the type, range and choices of a value
+func (o *Option) validateValue(value interface{}) error {
+	switch o.Type {
+	case OptionBool:
+		if _, ok := value.(bool); !ok {
+			return errors.New("Expected boolean type for " + o.Name)
+		}
+	case OptionNumber:
+		f, ok := value.(float64)
+		if !ok {
+			return errors.New("Expected numeric type for " + o.Name)
+		}
+		if o.Min != nil && f < *o.Min {
+			if *o.Min == 0 {
+				return errors.New(o.Name + " must be non-negative")
+			}
+			return fmt.Errorf("%s must be at least %v", o.Name, *o.Min)
+		}
+		if o.Max != nil && f > *o.Max {
+			return fmt.Errorf("%s must be at most %v", o.Name, *o.Max)
+		}
+	case OptionString:
+		s, ok := value.(string)
+		if !ok {
+			return errors.New("Expected string type for " + o.Name)
+		}
+		if len(o.Choices) > 0 && !containsString(o.Choices, s) {
+			return errors.New(o.Name + " must be one of: " + strings.Join(o.Choices, ", "))
+		}
+	case OptionStringList:
+		if _, ok := stringList(value); !ok {
+			return errors.New("Expected a list of strings for " + o.Name)
+		}
+	}
+	return nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// stringList converts a list of strings decoded from JSON or passed from
+// Lua to a []string
+func stringList(value interface{}) ([]string, bool) {
+	switch v := value.(type) {
+	case []string:
+		return v, true
+	case []interface{}:
+		list := make([]string, len(v))
+		for i, e := range v {
+			s, ok := e.(string)
+			if !ok {
+				return nil, false
+			}
+			list[i] = s
+		}
+		return list, true
+	case map[interface{}]interface{}:
+		// a Lua table used as an array
+		list := make([]string, len(v))
+		for k, e := range v {
+			i, ok := k.(float64)
+			s, ok2 := e.(string)
+			if !ok || !ok2 || i < 1 || int(i) > len(v) || float64(int(i)) != i {
+				return nil, false
+			}
+			list[int(i)-1] = s
+		}
+		return list, true
+	}
+	return nil, false
+}
+
+// optionTypeOf returns the type of option with the given default value
+func optionTypeOf(value interface{}) (OptionType, error) {
+	switch value.(type) {
+	case bool:
+		return OptionBool, nil
+	case float64:
+		return OptionNumber, nil
+	case string:
+		return OptionString, nil
+	}
+	if _, ok := stringList(value); ok {
+		return OptionStringList, nil
+	}
+	return 0, fmt.Errorf("Unsupported type %T for an option", value)
+}
+
+// optionFromMeta creates the option registered by a plugin. The optional
+// meta table can contain the keys help, min, max and choices, which set
+// the corresponding fields of the option.
+func optionFromMeta(plugin, name string, scope OptionScope, defaultvalue interface{}, meta map[string]interface{}) (*Option, error) {
+	t, err := optionTypeOf(defaultvalue)
+	if err != nil {
+		return nil, err
+	}
+	if t == OptionStringList {
+		defaultvalue, _ = stringList(defaultvalue)
+	}
+	o := &Option{
+		Name:    name,
+		Type:    t,
+		Scope:   scope,
+		Default: defaultvalue,
+		Plugin:  plugin,
+	}
+
+	for k, v := range meta {
+		var ok bool
+		switch k {
+		case "help":
+			o.Help, ok = v.(string)
+		case "min":
+			var f float64
+			f, ok = v.(float64)
+			o.Min = &f
+		case "max":
+			var f float64
+			f, ok = v.(float64)
+			o.Max = &f
+		case "choices":
+			o.Choices, ok = stringList(v)
+		default:
+			return nil, errors.New("Unknown option metadata " + k + " for " + name)
+		}
+		if !ok {
+			return nil, errors.New("Invalid option metadata " + k + " for " + name)
+		}
+	}
+	return o, nil
+}
+
+// formatOptionValue formats a value for the documentation
+func formatOptionValue(value interface{}) string {
+	switch v := value.(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case string:
+		return v
+	}
+	if list, ok := stringList(value); ok {
+		return strings.Join(list, ", ")
+	}
+	return fmt.Sprint(value)
+}
+
+// HelpItem returns the documentation of the option as an item of the
+// option list in `help options`
+func (o *Option) HelpItem() string {
+	var b strings.Builder
+
+	help := o.Help
+	if help == "" {
+		help = "no documentation."
+	}
+	for i, line := range strings.Split(help, "\n") {
+		if i == 0 {
+			b.WriteString("* `" + o.Name + "`: " + line)
+		} else if line == "" {
+			b.WriteString("\n")
+		} else {
+			b.WriteString("\n   " + line)
+		}
+	}
+
+	b.WriteString("\n\n    default value: ")
+	switch {
+	case o.DefaultHelp != "":
+		b.WriteString(o.DefaultHelp)
+	case o.Default == "":
+		b.WriteString("`\"\"` (empty string)")
+	default:
+		b.WriteString("`" + formatOptionValue(o.Default) + "`")
+	}
+
+	switch o.Scope {
+	case ScopeGlobal:
+		b.WriteString("\n\n    scope: global only")
+	case ScopeLocal:
+		b.WriteString("\n\n    scope: local only")
+	}
+	return b.String()
+}
+
+// OptionsHelp returns the documentation of micro's own options, or of the
+// options registered by plugins
+func OptionsHelp(plugins bool) string {
+	var items []string
+	for _, o := range Options() {
+		if (o.Plugin != "") == plugins {
+			items = append(items, o.HelpItem())
+		}
+	}
+	return strings.Join(items, "\n\n")
+}
+
+// GenerateOptionsHelp fills the sections of options.md delimited by the
+// comments <!-- BEGIN OPTIONS --> and <!-- END OPTIONS --> (and the same
+// for PLUGIN OPTIONS) with the documentation of the registered options.
+// The comments are kept if markers is true and removed otherwise.
+func GenerateOptionsHelp(doc string, markers bool) string {
+	fill := func(doc, section, content string) string {
+		begin := "<!-- BEGIN " + section + " -->\n"
+		end := "<!-- END " + section + " -->\n"
+		i := strings.Index(doc, begin)
+		j := strings.Index(doc, end)
+		if i < 0 || j < i {
+			return doc
+		}
+		if content != "" {
+			content += "\n"
+		}
+		if markers {
+			return doc[:i+len(begin)] + content + doc[j:]
+		}
+		return doc[:i] + content + doc[j+len(end):]
+	}
+
+	doc = fill(doc, "OPTIONS", OptionsHelp(false))
+	return fill(doc, "PLUGIN OPTIONS", OptionsHelp(true))
+}
+
+// schema returns the JSON Schema of the values of an option
+func (o *Option) schema() map[string]interface{} {
+	s := map[string]interface{}{
+		"default": o.Default,
+	}
+	if o.Help != "" {
+		s["description"] = o.Help
+	}
+	switch o.Type {
+	case OptionBool:
+		s["type"] = "boolean"
+	case OptionNumber:
+		s["type"] = "number"
+		if o.Min != nil {
+			s["minimum"] = *o.Min
+		}
+		if o.Max != nil {
+			s["maximum"] = *o.Max
+		}
+	case OptionString:
+		s["type"] = "string"
+		if len(o.Choices) > 0 {
+			s["enum"] = o.Choices
+		}
+	case OptionStringList:
+		s["type"] = "array"
+		s["items"] = map[string]interface{}{"type": "string"}
+	}
+	return s
+}
+
+// SettingsSchema returns a JSON Schema of settings.json, which editors can
+// use to validate and complete the file
+func SettingsSchema() ([]byte, error) {
+	props := map[string]interface{}{
+		"$schema": map[string]interface{}{"type": "string"},
+		"filetypes": map[string]interface{}{
+			"description":          "Maps file name globs and #!interpreter keys to filetypes",
+			"type":                 "object",
+			"additionalProperties": map[string]interface{}{"type": "string"},
+		},
+	}
+	localProps := make(map[string]interface{})
+	for _, o := range Options() {
+		props[o.Name] = o.schema()
+		if o.Scope != ScopeGlobal {
+			localProps[o.Name] = map[string]interface{}{"$ref": "#/properties/" + o.Name}
+		}
+	}
+
+	schema := map[string]interface{}{
+		"$schema":     "http://json-schema.org/draft-07/schema#",
+		"title":       "micro settings.json",
+		"type":        "object",
+		"properties":  props,
+		"definitions": map[string]interface{}{"local": map[string]interface{}{"type": "object", "properties": localProps}},
+		// "ft:filetype" and glob sections
+		"additionalProperties": map[string]interface{}{"$ref": "#/definitions/local"},
+	}
+	return json.MarshalIndent(schema, "", "    ")
+}
@@ -0,0 +1,89 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOptionsHelpUpToDate(t *testing.T) {
+	data, err := os.ReadFile("../../runtime/help/options.md")
+	assert.Nil(t, err)
+	doc := string(data)
+	assert.Equal(t, doc, GenerateOptionsHelp(doc, true), "options.md is out of date, run go generate ./internal/config")
+
+	// the example settings.json lists every option with its default value
+	start := strings.Index(doc, "```json\n{") + len("```json\n")
+	end := strings.Index(doc[start:], "```") + start
+	var example map[string]interface{}
+	assert.Nil(t, json.Unmarshal([]byte(doc[start:end]), &example))
+	for _, o := range builtinOptions {
+		if o.Name == "fileformat" {
+			continue
+		}
+		v, ok := example[o.Name]
+		if assert.True(t, ok, o.Name) && o.Type == OptionStringList {
+			l1, _ := stringList(v)
+			l2, _ := stringList(o.Default)
+			assert.Equal(t, l2, l1, o.Name)
+		} else if ok {
+			assert.Equal(t, o.Default, v, o.Name)
+		}
+	}
+}
+
+func TestOptionValidate(t *testing.T) {
+	assert.Nil(t, OptionIsValid("tabsize", 2.0))
+	assert.NotNil(t, OptionIsValid("tabsize", 0.0))
+	assert.NotNil(t, OptionIsValid("tabsize", "2"))
+	assert.Nil(t, OptionIsValid("clipboard", "internal"))
+	assert.NotNil(t, OptionIsValid("clipboard", "none"))
+	assert.NotNil(t, OptionIsValid("ruler", "yes"))
+	assert.Nil(t, OptionIsValid("pluginrepos", []interface{}{"a"}))
+	assert.NotNil(t, OptionIsValid("pluginrepos", []interface{}{1.0}))
+	// unknown options are not checked
+	assert.Nil(t, OptionIsValid("nosuchoption", 1.0))
+}
+
+func TestRegisterOptionPlug(t *testing.T) {
+	defer func() {
+		delete(optionRegistry, "test.width")
+		delete(defaultCommonSettings, "test.width")
+	}()
+
+	assert.NotNil(t, RegisterCommonOptionPlug("test", "width", 5.0, map[string]interface{}{"min": 10.0}))
+	assert.Nil(t, RegisterCommonOptionPlug("test", "width", 80.0, map[string]interface{}{
+		"help": "the width",
+		"min":  10.0,
+	}))
+
+	o := LookupOption("test.width")
+	assert.Equal(t, OptionNumber, o.Type)
+	assert.Equal(t, "test", o.Plugin)
+	assert.NotNil(t, OptionIsValid("test.width", 5.0))
+	assert.Equal(t, 80.0, DefaultCommonSettings()["test.width"])
+	assert.Contains(t, OptionsHelp(true), "* `test.width`: the width")
+	assert.NotContains(t, OptionsHelp(false), "test.width")
+
+	UnregisterOption("test.width")
+	assert.Nil(t, LookupOption("test.width"))
+	assert.NotContains(t, DefaultCommonSettings(), "test.width")
+	assert.NotContains(t, GlobalSettings, "test.width")
+}
+
+func TestSettingsSchema(t *testing.T) {
+	data, err := SettingsSchema()
+	assert.Nil(t, err)
+
+	var schema struct {
+		Properties map[string]map[string]interface{}
+	}
+	assert.Nil(t, json.Unmarshal(data, &schema))
+	assert.Equal(t, "number", schema.Properties["tabsize"]["type"])
+	assert.Equal(t, 1.0, schema.Properties["tabsize"]["minimum"])
+	assert.Equal(t, []interface{}{"unix", "dos"}, schema.Properties["fileformat"]["enum"])
+	assert.Equal(t, "array", schema.Properties["pluginrepos"]["type"])
+}
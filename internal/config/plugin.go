@@ -2,10 +2,13 @@ package config
 
 import (
 	"errors"
+	"fmt"
 	"log"
+	"time"
 
 	lua "github.com/yuin/gopher-lua"
 	ulua "github.com/zyedidia/micro/v2/internal/lua"
+	luar "layeh.com/gopher-luar"
 )
 
 // ErrNoSuchFunction is returned when Call is executed on a function that does not exist
@@ -71,17 +74,67 @@ type Plugin struct {
 	Info    *PluginInfo   // json file containing info
 	Srcs    []RuntimeFile // lua files
 	Loaded  bool
-	Default bool          // pre-installed plugin
+	Default bool // pre-installed plugin
+	Project bool // init.lua of the project, only loaded if trusted
+
+	// Disabled is set when the plugin's callbacks fail too many times in a
+	// row (see the pluginmaxerrors option)
+	Disabled bool
+
+	env      *lua.LTable // the plugin's global variables
+	exports  *lua.LTable // the table that other plugins can access
+	errors   int         // number of errors in a row
+	cleanups []func()    // undo what the plugin registered
 }
 
 // IsLoaded returns if a plugin is enabled
 func (p *Plugin) IsLoaded() bool {
+	if (p.Project && !p.Loaded) || p.Disabled {
+		return false
+	}
 	if v, ok := GlobalSettings[p.Name]; ok {
 		return v.(bool) && p.Loaded
 	}
 	return true
 }
 
+// Capabilities returns the capabilities granted to the plugin: the ones
+// declared in its repo.json, or all of them if it does not declare any
+// (like the built-in plugins and init.lua)
+func (p *Plugin) Capabilities() []string {
+	if p.Info == nil || p.Info.Capabilities == nil {
+		return ulua.Capabilities
+	}
+	var caps []string
+	for _, c := range p.Info.Capabilities {
+		if ulua.IsCapability(c) {
+			caps = append(caps, c)
+		}
+	}
+	return caps
+}
+
+// HasCapability returns whether the plugin was granted the given capability
+func (p *Plugin) HasCapability(capability string) bool {
+	for _, c := range p.Capabilities() {
+		if c == capability {
+			return true
+		}
+	}
+	return false
+}
+
+// PluginImporter imports a package for the given plugin. It is set by
+// the main package, which knows the micro packages, and is given to each
+// plugin as its own import function.
+var PluginImporter func(p *Plugin, pkg string) *lua.LTable
+
+// OnCapabilityDenied is called with the error of a plugin which used a
+// function needing a capability that it was not granted
+var OnCapabilityDenied = func(msg string) {
+	log.Println(msg)
+}
+
 // Plugins is a list of all detected plugins (enabled or disabled)
 var Plugins []*Plugin
 
@@ -90,43 +143,190 @@ func (p *Plugin) Load() error {
 	if v, ok := GlobalSettings[p.Name]; ok && !v.(bool) {
 		return nil
 	}
+	if p.Project && !ProjectTrusted() {
+		return nil
+	}
+
+	// the plugin's code runs in its own environment, and the global with
+	// the plugin's name is its exports table, which is all that other
+	// plugins can see of it
+	p.env = ulua.NewEnvironment()
+	ulua.RestrictEnvironment(p.env, p.Name, p.HasCapability, OnCapabilityDenied)
+	p.exports = ulua.L.NewTable()
+	ulua.L.SetField(p.env, "exports", p.exports)
+	ulua.L.SetGlobal(p.Name, p.exports)
+	if PluginImporter != nil {
+		ulua.L.SetField(p.env, "import", luar.New(ulua.L, func(pkg string) *lua.LTable {
+			return PluginImporter(p, pkg)
+		}))
+	}
+	p.Disabled = false
+	p.errors = 0
+
+	timeout := pluginTimeout()
 	for _, f := range p.Srcs {
 		dat, err := f.Data()
 		if err != nil {
 			return err
 		}
-		err = ulua.LoadFile(p.Name, f.Name(), dat)
+		err = ulua.CallLimited(timeout, func() error {
+			return ulua.LoadFile(p.env, f.Name(), dat)
+		})
+		if err == ulua.ErrTimeLimit {
+			err = fmt.Errorf("%s ran for more than %v and was stopped", f.Name(), timeout)
+		}
 		if err != nil {
 			return err
 		}
 	}
 	p.Loaded = true
-	RegisterCommonOption(p.Name, true)
+
+	help := "enables the `" + p.Name + "` plugin."
+	if p.Info != nil && p.Info.Desc != "" {
+		help += " " + p.Info.Desc
+	}
+	RegisterOption(&Option{
+		Name:    p.Name,
+		Type:    OptionBool,
+		Default: true,
+		Help:    help,
+		Plugin:  p.Name,
+	})
 	return nil
 }
 
-// Call calls a given function in this plugin
+// OnUnload registers a function undoing something that the plugin
+// registered (a command, a binding, a runtime file...), which is called when
+// the plugin is unloaded
+func (p *Plugin) OnUnload(f func()) {
+	p.cleanups = append(p.cleanups, f)
+}
+
+// Unload calls the plugin's deinit callback, then removes everything that
+// the plugin registered, its options (except the one enabling it) and its
+// global variables
+func (p *Plugin) Unload() error {
+	if !p.Loaded {
+		return nil
+	}
+	_, err := p.Call("deinit")
+	if err == ErrNoSuchFunction {
+		err = nil
+	}
+
+	for i := len(p.cleanups) - 1; i >= 0; i-- {
+		p.cleanups[i]()
+	}
+	p.cleanups = nil
+	for _, o := range Options() {
+		if o.Plugin == p.Name && o.Name != p.Name {
+			UnregisterOption(o.Name)
+		}
+	}
+
+	ulua.L.SetGlobal(p.Name, lua.LNil)
+	p.env, p.exports = nil, nil
+	p.Loaded = false
+	return err
+}
+
+// OnPluginDisabled is called when a plugin is disabled because its
+// callbacks failed too many times in a row
+var OnPluginDisabled func(p *Plugin, err error)
+
+// Global returns the value of one of the plugin's global variables
+func (p *Plugin) Global(name string) lua.LValue {
+	if p.env == nil {
+		return lua.LNil
+	}
+	return p.env.RawGetString(name)
+}
+
+// pluginTimeout returns the time limit of the plugins' code set by the
+// plugintimeout option, 0 meaning no limit
+func pluginTimeout() time.Duration {
+	timeout, _ := GetGlobalOption("plugintimeout").(float64)
+	return time.Duration(timeout * float64(time.Second))
+}
+
+// Call calls a given function in this plugin, stopping it if it runs for
+// longer than the plugintimeout option allows
 func (p *Plugin) Call(fn string, args ...lua.LValue) (lua.LValue, error) {
-	plug := ulua.L.GetGlobal(p.Name)
-	if plug == lua.LNil {
-		log.Println("Plugin does not exist:", p.Name, "at", p.DirName, ":", p)
+	if p.env == nil {
+		log.Println("Plugin is not loaded:", p.Name, "at", p.DirName, ":", p)
 		return nil, nil
 	}
-	luafn := ulua.L.GetField(plug, fn)
+	if p.Disabled {
+		return nil, nil
+	}
+	luafn := p.env.RawGetString(fn)
 	if luafn == lua.LNil {
 		return nil, ErrNoSuchFunction
 	}
-	err := ulua.L.CallByParam(lua.P{
-		Fn:      luafn,
-		NRet:    1,
-		Protect: true,
-	}, args...)
-	if err != nil {
-		return nil, err
-	}
-	ret := ulua.L.Get(-1)
-	ulua.L.Pop(1)
-	return ret, nil
+	return p.CallFunction(fn, luafn, args...)
+}
+
+// CallFunction calls a Lua function of the plugin, such as a callback that
+// it registered, with the plugin's time limit, and counts its errors like
+// Call. The name of the function is used in the error messages.
+func (p *Plugin) CallFunction(fn string, luafn lua.LValue, args ...lua.LValue) (lua.LValue, error) {
+	if p.Disabled {
+		return nil, nil
+	}
+
+	timeout := pluginTimeout()
+	var ret lua.LValue
+	err := ulua.CallLimited(timeout, func() error {
+		err := ulua.L.CallByParam(lua.P{
+			Fn:      luafn,
+			NRet:    1,
+			Protect: true,
+		}, args...)
+		if err == nil {
+			ret = ulua.L.Get(-1)
+			ulua.L.Pop(1)
+		}
+		return err
+	})
+	if err == ulua.ErrTimeLimit {
+		err = fmt.Errorf("%s ran for more than %v and was stopped", fn, timeout)
+	}
+	p.countError(err)
+	return ret, err
+}
+
+// Resume resumes a coroutine of the plugin running the Lua function fn,
+// such as a task, with the plugin's time limit for the time it runs before
+// yielding, and counts its errors like Call
+func (p *Plugin) Resume(name string, co *lua.LState, fn *lua.LFunction, args ...lua.LValue) (lua.ResumeState, error) {
+	if p.Disabled {
+		return lua.ResumeOK, nil
+	}
+
+	timeout := pluginTimeout()
+	state, err := ulua.ResumeLimited(timeout, co, fn, args...)
+	if err == ulua.ErrTimeLimit {
+		err = fmt.Errorf("%s ran for more than %v without yielding and was stopped", name, timeout)
+	}
+	p.countError(err)
+	return state, err
+}
+
+// countError keeps track of the errors of the plugin's callbacks, and
+// disables the plugin when they fail too many times in a row
+func (p *Plugin) countError(err error) {
+	if err == nil {
+		p.errors = 0
+		return
+	}
+	p.errors++
+	if max := int(GetGlobalOption("pluginmaxerrors").(float64)); max > 0 && p.errors >= max {
+		p.Disabled = true
+		log.Println("Plugin", p.Name, "disabled after", p.errors, "errors:", err)
+		if OnPluginDisabled != nil {
+			OnPluginDisabled(p, err)
+		}
+	}
 }
 
 // FindPlugin returns the plugin with the given name
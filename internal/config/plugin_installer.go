@@ -3,11 +3,17 @@ package config
 import (
 	"archive/zip"
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
 	"sort"
 	"strings"
 	"sync"
@@ -15,7 +21,6 @@ import (
 	"github.com/blang/semver"
 	lua "github.com/yuin/gopher-lua"
 	"github.com/micro-editor/json5"
-	ulua "github.com/zyedidia/micro/v2/internal/lua"
 	"github.com/zyedidia/micro/v2/internal/util"
 )
 
@@ -37,11 +42,12 @@ type PluginRepository string
 
 // PluginPackage contains the meta-data of a plugin and all available versions
 type PluginPackage struct {
-	Name        string
-	Description string
-	Author      string
-	Tags        []string
-	Versions    PluginVersions
+	Name         string
+	Description  string
+	Author       string
+	Tags         []string
+	Capabilities []string
+	Versions     PluginVersions
 }
 
 // PluginPackages is a list of PluginPackage instances.
@@ -52,6 +58,7 @@ type PluginVersion struct {
 	pack    *PluginPackage
 	Version semver.Version
 	Url     string
+	Sha256  string
 	Require PluginDependencies
 }
 
@@ -170,6 +177,7 @@ func (pv *PluginVersion) UnmarshalJSON(data []byte) error {
 	var values struct {
 		Version semver.Version
 		Url     string
+		Sha256  string
 		Require map[string]string
 	}
 
@@ -178,6 +186,7 @@ func (pv *PluginVersion) UnmarshalJSON(data []byte) error {
 	}
 	pv.Version = values.Version
 	pv.Url = values.Url
+	pv.Sha256 = values.Sha256
 	pv.Require = make(PluginDependencies, 0)
 
 	for k, v := range values.Require {
@@ -196,11 +205,12 @@ func (pv *PluginVersion) UnmarshalJSON(data []byte) error {
 // UnmarshalJSON unmarshals raw json to a PluginPackage
 func (pp *PluginPackage) UnmarshalJSON(data []byte) error {
 	var values struct {
-		Name        string
-		Description string
-		Author      string
-		Tags        []string
-		Versions    PluginVersions
+		Name         string
+		Description  string
+		Author       string
+		Tags         []string
+		Capabilities []string
+		Versions     PluginVersions
 	}
 	if err := json5.Unmarshal(data, &values); err != nil {
 		return err
@@ -209,6 +219,7 @@ func (pp *PluginPackage) UnmarshalJSON(data []byte) error {
 	pp.Description = values.Description
 	pp.Author = values.Author
 	pp.Tags = values.Tags
+	pp.Capabilities = values.Capabilities
 	pp.Versions = values.Versions
 	for _, v := range pp.Versions {
 		v.pack = pp
@@ -376,12 +387,11 @@ func GetInstalledVersions(withCore bool) PluginVersions {
 
 // GetInstalledPluginVersion returns the string of the exported VERSION variable of a loaded plugin
 func GetInstalledPluginVersion(name string) string {
-	plugin := ulua.L.GetGlobal(name)
-	if plugin != lua.LNil {
-		version := ulua.L.GetField(plugin, "VERSION")
-		if str, ok := version.(lua.LString); ok {
-			return string(str)
-
+	for _, p := range Plugins {
+		if p.Name == name {
+			if str, ok := p.Global("VERSION").(lua.LString); ok {
+				return string(str)
+			}
 		}
 	}
 	return ""
@@ -390,24 +400,85 @@ func GetInstalledPluginVersion(name string) string {
 // DownloadAndInstall downloads and installs the given plugin and version
 func (pv *PluginVersion) DownloadAndInstall(out io.Writer) error {
 	fmt.Fprintf(out, "Downloading %q (%s) from %q\n", pv.pack.Name, pv.Version, pv.Url)
-	resp, err := http.Get(pv.Url)
+	files, sum, err := readPluginSource(pv.Url)
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
-	data, err := io.ReadAll(resp.Body)
+	if pv.Sha256 != "" && !strings.EqualFold(pv.Sha256, sum) {
+		return fmt.Errorf("the sha256 sum of %s (%s) does not match the repository's one", pv.pack.Name, pv.Version)
+	}
+	return installPluginFiles(pv.pack.Name, pv.Version.String(), pv.Url, files)
+}
+
+// isLocalPluginSource returns whether the argument of `plugin install` is
+// a zip archive or a directory rather than the name of a plugin
+func isLocalPluginSource(src string) bool {
+	return strings.HasPrefix(src, "file://") || strings.HasSuffix(src, ".zip") ||
+		strings.HasPrefix(src, "~") || strings.ContainsRune(src, '/') || strings.ContainsRune(src, filepath.Separator)
+}
+
+// localPluginPath returns the absolute path of a local plugin source, or
+// an empty string if src is an http(s) url
+func localPluginPath(src string) (string, error) {
+	if strings.HasPrefix(src, "http://") || strings.HasPrefix(src, "https://") {
+		return "", nil
+	}
+	if strings.HasPrefix(src, "file://") {
+		u, err := url.Parse(src)
+		if err != nil {
+			return "", err
+		}
+		src = filepath.FromSlash(u.Path)
+	}
+	src, err := util.ReplaceHome(src)
 	if err != nil {
-		return err
+		return "", err
+	}
+	return filepath.Abs(src)
+}
+
+// readPluginSource reads the files of a plugin from a zip archive, which is
+// downloaded if src is an url, or from a directory. It also returns the
+// sha256 sum of the archive, or an empty string for a directory.
+func readPluginSource(src string) (map[string][]byte, string, error) {
+	path, err := localPluginPath(src)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var data []byte
+	if path == "" {
+		resp, err := http.Get(src)
+		if err != nil {
+			return nil, "", err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, "", fmt.Errorf("downloading %s: %s", src, resp.Status)
+		}
+		if data, err = io.ReadAll(resp.Body); err != nil {
+			return nil, "", err
+		}
+	} else if info, err := os.Stat(path); err != nil {
+		return nil, "", err
+	} else if info.IsDir() {
+		files, err := readPluginDir(path)
+		return files, "", err
+	} else if data, err = os.ReadFile(path); err != nil {
+		return nil, "", err
 	}
+
+	sum := sha256.Sum256(data)
+	files, err := unzipPlugin(data)
+	return files, hex.EncodeToString(sum[:]), err
+}
+
+// unzipPlugin returns the files of a plugin's zip archive by their path
+func unzipPlugin(data []byte) (map[string][]byte, error) {
 	zipbuf := bytes.NewReader(data)
 	z, err := zip.NewReader(zipbuf, zipbuf.Size())
 	if err != nil {
-		return err
-	}
-	targetDir := filepath.Join(ConfigDir, "plug", pv.pack.Name)
-	dirPerm := os.FileMode(0755)
-	if err = os.MkdirAll(targetDir, dirPerm); err != nil {
-		return err
+		return nil, err
 	}
 
 	// Check if all files in zip are in the same directory.
@@ -428,43 +499,178 @@ func (pv *PluginVersion) DownloadAndInstall(out io.Writer) error {
 		}
 	}
 
-	// Install files and directory's
+	files := make(map[string][]byte)
 	for _, f := range z.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
 		parts := strings.Split(f.Name, "/")
 		if allPrefixed {
 			parts = parts[1:]
 		}
+		name := strings.Join(parts, "/")
+		if name == "" || strings.HasPrefix(name, "/") || containsString(parts, "..") {
+			return nil, errors.New("invalid file name in archive: " + f.Name)
+		}
 
-		targetName := filepath.Join(targetDir, filepath.Join(parts...))
-		if f.FileInfo().IsDir() {
-			if err := os.MkdirAll(targetName, dirPerm); err != nil {
-				return err
-			}
-		} else {
-			basepath := filepath.Dir(targetName)
+		content, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(content)
+		content.Close()
+		if err != nil {
+			return nil, err
+		}
+		files[name] = data
+	}
+	return files, nil
+}
 
-			if err := os.MkdirAll(basepath, dirPerm); err != nil {
-				return err
-			}
+// readPluginDir returns the files of a plugin's directory by their path
+func readPluginDir(dir string) (map[string][]byte, error) {
+	files := make(map[string][]byte)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)], err = os.ReadFile(path)
+		return err
+	})
+	return files, err
+}
 
-			content, err := f.Open()
-			if err != nil {
-				return err
-			}
-			defer content.Close()
-			target, err := os.Create(targetName)
-			if err != nil {
-				return err
+// pluginFilesSum returns a sha256 sum of the names and contents of a
+// plugin's files, which is recorded in plugins.lock whatever the source of
+// the plugin, so that the installed files can be checked against it
+func pluginFilesSum(files map[string][]byte) string {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	h := sha256.New()
+	for _, name := range names {
+		sum := sha256.Sum256(files[name])
+		fmt.Fprintf(h, "%s\x00%x\n", name, sum)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+var versionRegex = regexp.MustCompile(`(?m)^\s*VERSION\s*=\s*["']([^"']+)["']`)
+
+// pluginPackageFromFiles returns the package of a plugin read from a local
+// source: its name and capabilities come from its info json file, and
+// its version from the VERSION variable of its Lua files. The name
+// defaults to the name of the archive or directory, without any version.
+func pluginPackageFromFiles(src string, files map[string][]byte) (*PluginPackage, string) {
+	name := strings.TrimSuffix(filepath.Base(src), ".zip")
+	if i := strings.LastIndex(name, "-"); i > 0 {
+		if _, err := semver.ParseTolerant(name[i+1:]); err == nil {
+			name = name[:i]
+		}
+	}
+	pp := &PluginPackage{Name: name}
+	version := "0.0.0"
+	for file, data := range files {
+		if strings.Contains(file, "/") {
+			continue
+		}
+		if strings.HasSuffix(file, ".json") && file != "repo.json" {
+			if info, err := NewPluginInfo(data); err == nil && info.Name != "" {
+				pp.Name = info.Name
+				pp.Description = info.Desc
+				pp.Capabilities = info.Capabilities
 			}
-			defer target.Close()
-			if _, err = io.Copy(target, content); err != nil {
-				return err
+		} else if strings.HasSuffix(file, ".lua") {
+			if m := versionRegex.FindSubmatch(data); m != nil {
+				version = string(m[1])
 			}
 		}
 	}
+	return pp, version
+}
+
+// installPluginFiles writes the files of a plugin to its directory in
+// ConfigDir/plug, replacing the previous ones, and records it in
+// plugins.lock, replacing its previous entry
+func installPluginFiles(name, version, source string, files map[string][]byte) error {
+	locks, err := ReadPluginLocks()
+	if err != nil {
+		return err
+	}
+	if err := writePluginFiles(name, files); err != nil {
+		return err
+	}
+
+	locks[name] = &PluginLock{Version: version, Source: source, Sha256: pluginFilesSum(files)}
+	return locks.Write()
+}
+
+// writePluginFiles replaces the files of the plugin's directory
+func writePluginFiles(name string, files map[string][]byte) error {
+	if !isPluginName(name) {
+		return fmt.Errorf("%q is not a valid plugin name", name)
+	}
+	targetDir := filepath.Join(ConfigDir, "plug", name)
+	dirPerm := os.FileMode(0755)
+	if err := os.RemoveAll(targetDir); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(targetDir, dirPerm); err != nil {
+		return err
+	}
+
+	for file, data := range files {
+		targetName := filepath.Join(targetDir, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(targetName), dirPerm); err != nil {
+			return err
+		}
+		if err := os.WriteFile(targetName, data, 0644); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// InstallLocalPlugin installs a plugin from a zip archive or a directory,
+// after the user confirms the capabilities that it requests
+func InstallLocalPlugin(out io.Writer, src string, confirm ConfirmFunc) {
+	files, _, err := readPluginSource(src)
+	if err != nil {
+		fmt.Fprintln(out, "Error installing", src+":", err)
+		return
+	}
+	pp, version := pluginPackageFromFiles(src, files)
+	if !isPluginName(pp.Name) {
+		fmt.Fprintf(out, "Error installing %s: %q is not a valid plugin name\n", src, pp.Name)
+		return
+	}
+	if path, err := localPluginPath(src); err == nil && path != "" {
+		src = path
+	}
+
+	fmt.Fprintf(out, "%s (%s) declares the capabilities: %s\n", pp.Name, version, pp.capabilitiesString())
+	doInstall := func() {
+		if err := installPluginFiles(pp.Name, version, src, files); err != nil {
+			fmt.Fprintln(out, err)
+			return
+		}
+		fmt.Fprintln(out, "Installed", pp.Name, "from", src)
+	}
+
+	if confirm != nil {
+		confirm("Only install plugins that you trust. Install the plugin?", doInstall)
+	} else {
+		doInstall()
+	}
+}
+
 func (pl PluginPackages) Get(name string) *PluginPackage {
 	for _, p := range pl {
 		if p.Name == name {
@@ -536,35 +742,69 @@ func (all PluginPackages) Resolve(selectedVersions PluginVersions, open PluginDe
 	return selectedVersions, nil
 }
 
-func (pv PluginVersions) install(out io.Writer) {
-	anyInstalled := false
+// A ConfirmFunc asks the user the given question, and calls yes if they
+// accept
+type ConfirmFunc func(question string, yes func())
+
+// capabilitiesString describes the capabilities requested by a plugin
+// package
+func (pp *PluginPackage) capabilitiesString() string {
+	if pp.Capabilities == nil {
+		return "all (the plugin does not declare its capabilities)"
+	}
+	if len(pp.Capabilities) == 0 {
+		return "none"
+	}
+	return strings.Join(pp.Capabilities, ", ")
+}
+
+func (pv PluginVersions) install(out io.Writer, confirm ConfirmFunc) {
 	currentlyInstalled := GetInstalledVersions(true)
 
+	var toInstall PluginVersions
+	var reinstall []bool
 	for _, sel := range pv {
 		if sel.pack.Name != CorePluginName {
 			shouldInstall := true
+			uninstall := false
 			if pv := currentlyInstalled.find(sel.pack.Name); pv != nil {
 				if pv.Version.NE(sel.Version) {
-					fmt.Fprintln(out, "Uninstalling", sel.pack.Name)
-					UninstallPlugin(out, sel.pack.Name)
+					uninstall = true
 				} else {
 					shouldInstall = false
 				}
 			}
 
 			if shouldInstall {
-				if err := sel.DownloadAndInstall(out); err != nil {
-					fmt.Fprintln(out, err)
-					return
-				}
-				anyInstalled = true
+				toInstall = append(toInstall, sel)
+				reinstall = append(reinstall, uninstall)
+				fmt.Fprintf(out, "%s (%s) declares the capabilities: %s\n", sel.pack.Name, sel.Version, sel.pack.capabilitiesString())
 			}
 		}
 	}
-	if anyInstalled {
+	if len(toInstall) == 0 {
+		fmt.Fprintln(out, "Nothing to install / update")
+		return
+	}
+
+	doInstall := func() {
+		for i, sel := range toInstall {
+			if reinstall[i] {
+				fmt.Fprintln(out, "Uninstalling", sel.pack.Name)
+				UninstallPlugin(out, sel.pack.Name)
+			}
+			if err := sel.DownloadAndInstall(out); err != nil {
+				fmt.Fprintln(out, err)
+				return
+			}
+		}
 		fmt.Fprintln(out, "One or more plugins installed.")
+	}
+
+	if confirm != nil {
+		confirm("Only install plugins that you trust. Install the plugins?", doInstall)
 	} else {
-		fmt.Fprintln(out, "Nothing to install / update")
+		doInstall()
 	}
 }
 
@@ -580,13 +820,21 @@ func UninstallPlugin(out io.Writer, name string) {
 				fmt.Fprintln(out, err)
 				return
 			}
+			if locks, err := ReadPluginLocks(); err == nil && locks[name] != nil {
+				delete(locks, name)
+				if err := locks.Write(); err != nil {
+					fmt.Fprintln(out, err)
+				}
+			}
 			break
 		}
 	}
 }
 
 // Install installs the plugin
-func (pl PluginPackage) Install(out io.Writer) {
+// The user is asked to confirm the capabilities requested by the plugin
+// and its dependencies with confirm
+func (pl PluginPackage) Install(out io.Writer, confirm ConfirmFunc) {
 	selected, err := GetAllPluginPackages(out).Resolve(GetInstalledVersions(true), PluginDependencies{
 		&PluginDependency{
 			Name:  pl.Name,
@@ -596,11 +844,11 @@ func (pl PluginPackage) Install(out io.Writer) {
 		fmt.Fprintln(out, err)
 		return
 	}
-	selected.install(out)
+	selected.install(out, confirm)
 }
 
 // UpdatePlugins updates the given plugins
-func UpdatePlugins(out io.Writer, plugins []string) {
+func UpdatePlugins(out io.Writer, plugins []string, confirm ConfirmFunc) {
 	// if no plugins are specified, update all installed plugins.
 	if len(plugins) == 0 {
 		for _, p := range Plugins {
@@ -633,14 +881,20 @@ func UpdatePlugins(out io.Writer, plugins []string) {
 		fmt.Fprintln(out, err)
 		return
 	}
-	selected.install(out)
+	selected.install(out, confirm)
 }
 
-func PluginCommand(out io.Writer, cmd string, args []string) {
+// PluginCommand runs the given plugin command, using confirm to ask the
+// user before installing plugins which request capabilities
+func PluginCommand(out io.Writer, cmd string, args []string, confirm ConfirmFunc) {
 	switch cmd {
 	case "install":
 		installedVersions := GetInstalledVersions(false)
 		for _, plugin := range args {
+			if isLocalPluginSource(plugin) {
+				InstallLocalPlugin(out, plugin, confirm)
+				continue
+			}
 			pp := GetAllPluginPackages(out).Get(plugin)
 			if pp == nil {
 				fmt.Fprintln(out, "Unknown plugin \""+plugin+"\"")
@@ -656,7 +910,7 @@ func PluginCommand(out io.Writer, cmd string, args []string) {
 						}
 					}
 				}
-				pp.Install(out)
+				pp.Install(out, confirm)
 			}
 		}
 
@@ -682,7 +936,13 @@ func PluginCommand(out io.Writer, cmd string, args []string) {
 			fmt.Fprintln(out, "No plugins removed")
 		}
 	case "update":
-		UpdatePlugins(out, args)
+		UpdatePlugins(out, args, confirm)
+	case "sync":
+		mirror := ""
+		if len(args) > 0 {
+			mirror = args[0]
+		}
+		SyncPlugins(out, mirror, confirm)
 	case "list":
 		plugins := GetInstalledVersions(false)
 		fmt.Fprintln(out, "The following plugins are currently installed:")
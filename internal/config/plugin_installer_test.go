@@ -1,11 +1,16 @@
 package config
 
 import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/blang/semver"
 
 	"github.com/micro-editor/json5"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestDependencyResolving(t *testing.T) {
@@ -54,3 +59,112 @@ func TestDependencyResolving(t *testing.T) {
 		t.Error("Unresolvable package resolved:", selected)
 	}
 }
+
+func TestPluginCapabilities(t *testing.T) {
+	var all PluginPackages
+	err := json5.Unmarshal([]byte(`[
+		{"Name": "Foo", "Capabilities": ["network", "exec"], "Versions": [{"Version": "1.0.0"}]},
+		{"Name": "Bar", "Capabilities": [], "Versions": [{"Version": "1.0.0"}]},
+		{"Name": "Baz", "Versions": [{"Version": "1.0.0"}]}
+	]`), &all)
+	assert.Nil(t, err)
+	assert.Equal(t, "network, exec", all.Get("Foo").capabilitiesString())
+	assert.Equal(t, "none", all.Get("Bar").capabilitiesString())
+	assert.Contains(t, all.Get("Baz").capabilitiesString(), "all")
+
+	info, err := NewPluginInfo([]byte(`[{"Name": "foo", "Capabilities": ["network", "bogus"]}]`))
+	assert.Nil(t, err)
+	p := &Plugin{Name: "foo", Info: info}
+	assert.Equal(t, []string{"network"}, p.Capabilities())
+	assert.True(t, p.HasCapability("network"))
+	assert.False(t, p.HasCapability("exec"))
+
+	p.Info = nil
+	assert.True(t, p.HasCapability("exec"))
+}
+
+func TestLocalPluginInstall(t *testing.T) {
+	oldConfig := ConfigDir
+	defer func() { ConfigDir = oldConfig }()
+	ConfigDir = t.TempDir()
+
+	src := filepath.Join(t.TempDir(), "foo-1.2.0")
+	os.MkdirAll(filepath.Join(src, "help"), 0755)
+	os.WriteFile(filepath.Join(src, "foo.lua"), []byte("VERSION = \"1.2.0\"\n"), 0644)
+	os.WriteFile(filepath.Join(src, "help", "foo.md"), []byte("# Foo\n"), 0644)
+
+	var out bytes.Buffer
+	InstallLocalPlugin(&out, src, nil)
+	data, err := os.ReadFile(filepath.Join(ConfigDir, "plug", "foo", "help", "foo.md"))
+	assert.Nil(t, err)
+	assert.Equal(t, "# Foo\n", string(data))
+
+	locks, err := ReadPluginLocks()
+	assert.Nil(t, err)
+	if assert.Contains(t, locks, "foo") {
+		assert.Equal(t, "1.2.0", locks["foo"].Version)
+		assert.Equal(t, src, locks["foo"].Source)
+	}
+
+	// a zip of the same files in a mirror directory
+	mirror := t.TempDir()
+	var zipped bytes.Buffer
+	z := zip.NewWriter(&zipped)
+	for _, name := range []string{"foo/foo.lua", "foo/help/foo.md"} {
+		w, _ := z.Create(name)
+		data, _ := os.ReadFile(filepath.Join(src, filepath.FromSlash(name[4:])))
+		w.Write(data)
+	}
+	z.Close()
+	os.WriteFile(filepath.Join(mirror, "foo-1.2.0.zip"), zipped.Bytes(), 0644)
+
+	// the lock has the sum of the files, whatever their source
+	os.RemoveAll(filepath.Join(ConfigDir, "plug", "foo"))
+	out.Reset()
+	SyncPlugins(&out, mirror, nil)
+	assert.NotContains(t, out.String(), "does not match")
+	assert.FileExists(t, filepath.Join(ConfigDir, "plug", "foo", "foo.lua"))
+
+	out.Reset()
+	SyncPlugins(&out, mirror, nil)
+	assert.Contains(t, out.String(), "foo (1.2.0) is up to date")
+
+	// installed files which were modified are reinstalled
+	os.WriteFile(filepath.Join(ConfigDir, "plug", "foo", "foo.lua"), []byte("VERSION = \"1.2.0\"\nevil()\n"), 0644)
+	out.Reset()
+	SyncPlugins(&out, mirror, nil)
+	assert.Contains(t, out.String(), "do not match plugins.lock")
+	data, err = os.ReadFile(filepath.Join(ConfigDir, "plug", "foo", "foo.lua"))
+	assert.Nil(t, err)
+	assert.Equal(t, "VERSION = \"1.2.0\"\n", string(data))
+
+	// an explicit install of a modified plugin replaces its lock
+	os.WriteFile(filepath.Join(src, "foo.lua"), []byte("VERSION = \"1.2.0\"\nfixed()\n"), 0644)
+	out.Reset()
+	InstallLocalPlugin(&out, src, nil)
+	assert.Contains(t, out.String(), "Installed foo")
+	newLocks, err := ReadPluginLocks()
+	assert.Nil(t, err)
+	assert.NotEqual(t, locks["foo"].Sha256, newLocks["foo"].Sha256)
+
+	// the mirror's archive has the previous files
+	os.RemoveAll(filepath.Join(ConfigDir, "plug", "foo"))
+	out.Reset()
+	SyncPlugins(&out, mirror, nil)
+	assert.Contains(t, out.String(), "does not match")
+	_, err = os.Stat(filepath.Join(ConfigDir, "plug", "foo"))
+	assert.True(t, os.IsNotExist(err))
+
+	// the names of plugins.lock cannot reach outside of the plug directory
+	victim := filepath.Join(ConfigDir, "victim")
+	os.MkdirAll(victim, 0755)
+	os.WriteFile(filepath.Join(victim, "file"), []byte("data"), 0644)
+	newLocks["../victim"] = newLocks["foo"]
+	assert.Nil(t, newLocks.Write())
+	out.Reset()
+	SyncPlugins(&out, "", nil)
+	assert.Contains(t, out.String(), `"../victim" is not a valid plugin name`)
+	assert.FileExists(t, filepath.Join(victim, "file"))
+	assert.Error(t, writePluginFiles("../victim", nil))
+	assert.FileExists(t, filepath.Join(victim, "file"))
+}
@@ -0,0 +1,159 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// A PluginLock records the exact version of an installed plugin, where it
+// was installed from and the sha256 sum of its files (see pluginFilesSum)
+type PluginLock struct {
+	Version string `json:"version"`
+	Source  string `json:"source"`
+	Sha256  string `json:"sha256"`
+}
+
+// PluginLocks maps the names of the installed plugins to their lock,
+// as stored in ConfigDir/plugins.lock
+type PluginLocks map[string]*PluginLock
+
+func pluginLockFile() string {
+	return filepath.Join(ConfigDir, "plugins.lock")
+}
+
+// ReadPluginLocks reads plugins.lock, which may not exist
+func ReadPluginLocks() (PluginLocks, error) {
+	locks := make(PluginLocks)
+	data, err := os.ReadFile(pluginLockFile())
+	if os.IsNotExist(err) {
+		return locks, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &locks); err != nil {
+		return nil, NewJSONError(pluginLockFile(), data, err)
+	}
+	return locks, nil
+}
+
+// Write writes plugins.lock
+func (locks PluginLocks) Write() error {
+	txt, err := json.MarshalIndent(locks, "", "    ")
+	if err != nil {
+		return err
+	}
+	return writeFile(pluginLockFile(), append(txt, '\n'))
+}
+
+// findInMirror returns the zip archive or directory of the locked plugin
+// in the mirror directory: a file with the same name as its source,
+// name-version.zip, name.zip or a directory called name
+func findInMirror(mirror, name string, l *PluginLock) string {
+	candidates := []string{
+		path.Base(filepath.ToSlash(l.Source)),
+		name + "-" + l.Version + ".zip",
+		name + ".zip",
+		name,
+	}
+	for _, c := range candidates {
+		p := filepath.Join(mirror, c)
+		if _, err := os.Stat(p); err == nil {
+			return p
+		}
+	}
+	return ""
+}
+
+// SyncPlugins installs the plugins recorded in plugins.lock whose installed
+// files do not match the sha256 sum of the lock, from their source or from
+// the given mirror directory if it isn't empty, after checking the sum of
+// the new files. The plugins which are installed but not in plugins.lock
+// are removed if the user confirms it.
+func SyncPlugins(out io.Writer, mirror string, confirm ConfirmFunc) {
+	locks, err := ReadPluginLocks()
+	if err != nil {
+		fmt.Fprintln(out, err)
+		return
+	}
+	if mirror != "" {
+		if mirror, err = localPluginPath(mirror); err != nil {
+			fmt.Fprintln(out, err)
+			return
+		}
+	}
+
+	names := make([]string, 0, len(locks))
+	for name := range locks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	plugdir := filepath.Join(ConfigDir, "plug")
+	for _, name := range names {
+		l := locks[name]
+		// the names come from a file which may be shared, and are used as
+		// the directories of the plugins
+		if !isPluginName(name) {
+			fmt.Fprintf(out, "%q is not a valid plugin name\n", name)
+			continue
+		}
+		if installed, err := readPluginDir(filepath.Join(plugdir, name)); err == nil {
+			if strings.EqualFold(pluginFilesSum(installed), l.Sha256) {
+				fmt.Fprintf(out, "%s (%s) is up to date\n", name, l.Version)
+				continue
+			}
+			fmt.Fprintf(out, "The installed files of %s do not match plugins.lock\n", name)
+		}
+
+		src := l.Source
+		if mirror != "" {
+			if src = findInMirror(mirror, name, l); src == "" {
+				fmt.Fprintf(out, "%s (%s) was not found in %s\n", name, l.Version, mirror)
+				continue
+			}
+		}
+		fmt.Fprintf(out, "Installing %q (%s) from %q\n", name, l.Version, src)
+		files, _, err := readPluginSource(src)
+		if err != nil {
+			fmt.Fprintln(out, err)
+			continue
+		}
+		if !strings.EqualFold(pluginFilesSum(files), l.Sha256) {
+			fmt.Fprintf(out, "The sha256 sum of %s (%s) does not match the one in plugins.lock\n", name, l.Version)
+			continue
+		}
+		if err := writePluginFiles(name, files); err != nil {
+			fmt.Fprintln(out, err)
+		}
+	}
+
+	var extra []string
+	dirs, _ := os.ReadDir(plugdir)
+	for _, d := range dirs {
+		if _, ok := locks[d.Name()]; d.IsDir() && !ok {
+			extra = append(extra, d.Name())
+		}
+	}
+	if len(extra) == 0 {
+		return
+	}
+	fmt.Fprintln(out, "Not in plugins.lock:", strings.Join(extra, ", "))
+	remove := func() {
+		for _, name := range extra {
+			if err := os.RemoveAll(filepath.Join(plugdir, name)); err != nil {
+				fmt.Fprintln(out, err)
+				return
+			}
+		}
+		fmt.Fprintln(out, "Removed", strings.Join(extra, ", "))
+	}
+	if confirm != nil {
+		confirm("Remove the plugins which are not in plugins.lock?", remove)
+	}
+}
@@ -23,10 +23,13 @@ var (
 // Install: install link for plugin (can be link to repo or zip file)
 // Vstr: version
 // Require: list of dependencies and requirements
+// Capabilities: what the plugin needs access to (filesystem-read,
+// filesystem-write, network and exec), nil if it does not say
 type PluginInfo struct {
-	Name string `json:"Name"`
-	Desc string `json:"Description"`
-	Site string `json:"Website"`
+	Name         string   `json:"Name"`
+	Desc         string   `json:"Description"`
+	Site         string   `json:"Website"`
+	Capabilities []string `json:"Capabilities"`
 }
 
 // NewPluginInfo parses a JSON input into a valid PluginInfo struct
@@ -0,0 +1,121 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ProjectDir is the project's configuration directory (a .micro directory
+// in the working directory or one of its parents), or an empty string if
+// there is none. Its settings, bindings, syntax files and colorschemes
+// take precedence over the ones in ConfigDir.
+var ProjectDir string
+
+// InitProjectDir looks for a .micro directory in the working directory and
+// its parents
+func InitProjectDir() {
+	ProjectDir = ""
+
+	dir, err := os.Getwd()
+	if err != nil {
+		return
+	}
+	configDir, _ := filepath.Abs(ConfigDir)
+	for {
+		d := filepath.Join(dir, ".micro")
+		if stat, err := os.Stat(d); err == nil && stat.IsDir() && d != configDir {
+			ProjectDir = d
+			return
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return
+		}
+		dir = parent
+	}
+}
+
+// ProjectInitLua returns the path of the project's init.lua, or an empty
+// string if the project has none
+func ProjectInitLua() string {
+	if ProjectDir == "" {
+		return ""
+	}
+	initlua := filepath.Join(ProjectDir, "init.lua")
+	if _, err := os.Stat(initlua); err != nil {
+		return ""
+	}
+	return initlua
+}
+
+// projectTrustedFiles are the files of the project's configuration whose
+// content the user trusts. The init.lua and the bindings.json can run
+// commands, and editing any of them asks for the trust again.
+var projectTrustedFiles = []string{"init.lua", "bindings.json", "settings.json"}
+
+// ProjectNeedsTrust returns whether the project has files which can run
+// commands, an init.lua or a bindings.json, and which are only used once
+// the user trusts the project
+func ProjectNeedsTrust() bool {
+	if ProjectDir == "" {
+		return false
+	}
+	for _, name := range []string{"init.lua", "bindings.json"} {
+		if _, err := os.Stat(filepath.Join(ProjectDir, name)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// projectHash returns the hash of the trusted files of the project
+func projectHash() string {
+	h := sha256.New()
+	for _, name := range projectTrustedFiles {
+		data, err := os.ReadFile(filepath.Join(ProjectDir, name))
+		if err != nil {
+			continue
+		}
+		fmt.Fprintf(h, "%s %d\n", name, len(data))
+		h.Write(data)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// readTrusted reads the list of trusted project directories and the hashes
+// of their files from ConfigDir/trusted.json
+func readTrusted() map[string]string {
+	trusted := make(map[string]string)
+	if data, err := os.ReadFile(filepath.Join(ConfigDir, "trusted.json")); err == nil {
+		json.Unmarshal(data, &trusted)
+	}
+	return trusted
+}
+
+// ProjectTrusted returns whether the user has allowed the project's
+// init.lua and bindings.json to run commands, in the current content of
+// the project's files
+func ProjectTrusted() bool {
+	if !ProjectNeedsTrust() {
+		return false
+	}
+	return readTrusted()[ProjectDir] == projectHash()
+}
+
+// TrustProject remembers that the user trusts the current content of the
+// project's files
+func TrustProject() error {
+	if ProjectDir == "" {
+		return errors.New("No project directory")
+	}
+	trusted := readTrusted()
+	trusted[ProjectDir] = projectHash()
+
+	txt, _ := json.MarshalIndent(trusted, "", "    ")
+	return writeFile(filepath.Join(ConfigDir, "trusted.json"), append(txt, '\n'))
+}
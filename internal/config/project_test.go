@@ -0,0 +1,75 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProjectSettings(t *testing.T) {
+	oldConfig, oldProject := ConfigDir, ProjectDir
+	defer func() {
+		ConfigDir, ProjectDir = oldConfig, oldProject
+		ReadSettings()
+	}()
+
+	ConfigDir = t.TempDir()
+	ProjectDir = t.TempDir()
+	os.WriteFile(filepath.Join(ConfigDir, "settings.json"),
+		[]byte(`{"tabsize": 2, "ruler": false, "*.go": {"tabsize": 3, "ruler": true}}`), 0644)
+	os.WriteFile(filepath.Join(ProjectDir, "settings.json"),
+		[]byte(`{"tabsize": 8, "*.go": {"tabsize": 5}}`), 0644)
+
+	assert.Nil(t, ReadSettings())
+	parsed := ParsedSettings()
+	assert.Equal(t, 8.0, parsed["tabsize"])
+	assert.Equal(t, false, parsed["ruler"])
+	assert.Equal(t, map[string]interface{}{"tabsize": 5.0, "ruler": true}, parsed["*.go"])
+
+	settings := make(map[string]interface{})
+	UpdatePathGlobLocals(settings, SettingsTarget{Path: "/src/main.go"})
+	assert.Equal(t, 5.0, settings["tabsize"])
+	assert.Equal(t, true, settings["ruler"])
+	assert.Equal(t, filepath.Join(ProjectDir, "settings.json")+" [*.go]",
+		SettingOrigin("tabsize", 5.0, SettingsTarget{Path: "/src/main.go", FileType: "go"}))
+	assert.Equal(t, "settings.json [*.go]", SettingOrigin("ruler", true, SettingsTarget{Path: "/src/main.go", FileType: "go"}))
+}
+
+func TestProjectTrust(t *testing.T) {
+	oldConfig, oldProject := ConfigDir, ProjectDir
+	defer func() { ConfigDir, ProjectDir = oldConfig, oldProject }()
+
+	ConfigDir = t.TempDir()
+	ProjectDir = t.TempDir()
+	assert.False(t, ProjectNeedsTrust())
+	assert.False(t, ProjectTrusted())
+
+	initlua := filepath.Join(ProjectDir, "init.lua")
+	os.WriteFile(initlua, []byte("print(1)"), 0644)
+	assert.Equal(t, initlua, ProjectInitLua())
+	assert.True(t, ProjectNeedsTrust())
+	assert.False(t, ProjectTrusted())
+
+	assert.Nil(t, TrustProject())
+	assert.True(t, ProjectTrusted())
+
+	// changing any of the files revokes the trust
+	os.WriteFile(initlua, []byte("print(2)"), 0644)
+	assert.False(t, ProjectTrusted())
+
+	assert.Nil(t, TrustProject())
+	os.WriteFile(filepath.Join(ProjectDir, "bindings.json"), []byte(`{"F5": "command:run make"}`), 0644)
+	assert.False(t, ProjectTrusted())
+
+	assert.Nil(t, TrustProject())
+	os.WriteFile(filepath.Join(ProjectDir, "settings.json"), []byte(`{"tabsize": 2}`), 0644)
+	assert.False(t, ProjectTrusted())
+
+	// bindings alone need the trust too
+	os.Remove(initlua)
+	assert.True(t, ProjectNeedsTrust())
+	assert.Nil(t, TrustProject())
+	assert.True(t, ProjectTrusted())
+}
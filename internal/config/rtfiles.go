@@ -18,10 +18,11 @@ const (
 	RTHelp         = 2
 	RTPlugin       = 3
 	RTSyntaxHeader = 4
+	RTSnippet      = 5
 )
 
 var (
-	NumTypes = 5 // How many filetypes are there
+	NumTypes = 6 // How many filetypes are there
 )
 
 type RTFiletype int
@@ -164,6 +165,10 @@ func ListRealRuntimeFiles(fileType RTFiletype) []RuntimeFile {
 // initializes asset files only.
 func InitRuntimeFiles(user bool) {
 	add := func(fileType RTFiletype, dir, pattern string) {
+		if user && ProjectDir != "" {
+			// the project's runtime files take precedence over the user's
+			AddRuntimeFilesFromDirectory(fileType, filepath.Join(ProjectDir, dir), pattern)
+		}
 		if user {
 			AddRuntimeFilesFromDirectory(fileType, filepath.Join(ConfigDir, dir), pattern)
 		}
@@ -176,8 +181,12 @@ func InitRuntimeFiles(user bool) {
 	add(RTSyntax, "syntax", "*.yaml")
 	add(RTSyntaxHeader, "syntax", "*.hdr")
 	add(RTHelp, "help", "*.md")
+	add(RTSnippet, "snippets", "*.json")
 }
 
+// isPluginName returns whether the given name can be the name of a plugin
+var isPluginName = regexp.MustCompile(`^[_A-Za-z0-9]+$`).MatchString
+
 // InitPlugins initializes the plugins
 func InitPlugins() {
 	Plugins = Plugins[:0]
@@ -191,12 +200,20 @@ func InitPlugins() {
 		Plugins = append(Plugins, p)
 	}
 
+	// the project's init.lua is only loaded once the user trusts it
+	if projectlua := ProjectInitLua(); projectlua != "" {
+		p := new(Plugin)
+		p.Name = "projectlua"
+		p.DirName = "projectlua"
+		p.Project = true
+		p.Srcs = append(p.Srcs, realFile(projectlua))
+		Plugins = append(Plugins, p)
+	}
+
 	// Search ConfigDir for plugin-scripts
 	plugdir := filepath.Join(ConfigDir, "plug")
 	files, _ := os.ReadDir(plugdir)
 
-	isID := regexp.MustCompile(`^[_A-Za-z0-9]+$`).MatchString
-
 	for _, d := range files {
 		plugpath := filepath.Join(plugdir, d.Name())
 		if stat, err := os.Stat(plugpath); err == nil && stat.IsDir() {
@@ -205,7 +222,10 @@ func InitPlugins() {
 			p.Name = d.Name()
 			p.DirName = d.Name()
 			for _, f := range srcs {
-				if strings.HasSuffix(f.Name(), ".lua") {
+				if strings.HasSuffix(f.Name(), "_test.lua") {
+					// tests are run by micro -test-plugin
+					continue
+				} else if strings.HasSuffix(f.Name(), ".lua") {
 					p.Srcs = append(p.Srcs, realFile(filepath.Join(plugdir, d.Name(), f.Name())))
 				} else if strings.HasSuffix(f.Name(), ".json") {
 					data, err := os.ReadFile(filepath.Join(plugdir, d.Name(), f.Name()))
@@ -220,7 +240,7 @@ func InitPlugins() {
 				}
 			}
 
-			if !isID(p.Name) || len(p.Srcs) <= 0 {
+			if !isPluginName(p.Name) || len(p.Srcs) <= 0 {
 				log.Println(p.Name, "is not a plugin")
 				continue
 			}
@@ -245,7 +265,9 @@ func InitPlugins() {
 				p.DirName = d
 				p.Default = true
 				for _, f := range srcs {
-					if strings.HasSuffix(f, ".lua") {
+					if strings.HasSuffix(f, "_test.lua") {
+						continue
+					} else if strings.HasSuffix(f, ".lua") {
 						p.Srcs = append(p.Srcs, assetFile(filepath.Join(plugdir, d, f)))
 					} else if strings.HasSuffix(f, ".json") {
 						data, err := rt.Asset(filepath.Join(plugdir, d, f))
@@ -259,7 +281,7 @@ func InitPlugins() {
 						p.Name = p.Info.Name
 					}
 				}
-				if !isID(p.Name) || len(p.Srcs) <= 0 {
+				if !isPluginName(p.Name) || len(p.Srcs) <= 0 {
 					log.Println(p.Name, "is not a plugin")
 					continue
 				}
@@ -289,6 +311,35 @@ func PluginListRuntimeFiles(fileType RTFiletype) []string {
 	return result
 }
 
+// TrackRuntimeFiles calls add, which adds runtime files of the given type
+// for the plugin, and removes these files when the plugin is unloaded
+func (p *Plugin) TrackRuntimeFiles(fileType RTFiletype, add func()) {
+	n := len(allFiles[fileType])
+	add()
+	added := append([]RuntimeFile{}, allFiles[fileType][n:]...)
+	if len(added) > 0 {
+		p.OnUnload(func() {
+			allFiles[fileType] = removeRuntimeFiles(allFiles[fileType], added)
+			realFiles[fileType] = removeRuntimeFiles(realFiles[fileType], added)
+		})
+	}
+}
+
+// removeRuntimeFiles returns the files which are not in removed
+func removeRuntimeFiles(files, removed []RuntimeFile) []RuntimeFile {
+	var kept []RuntimeFile
+outer:
+	for _, f := range files {
+		for _, r := range removed {
+			if f == r {
+				continue outer
+			}
+		}
+		kept = append(kept, f)
+	}
+	return kept
+}
+
 // PluginAddRuntimeFile adds a file to the runtime files for a plugin
 func PluginAddRuntimeFile(plugin string, filetype RTFiletype, filePath string) error {
 	pl := FindPlugin(plugin)
@@ -297,12 +348,14 @@ func PluginAddRuntimeFile(plugin string, filetype RTFiletype, filePath string) e
 	}
 	pldir := pl.DirName
 	fullpath := filepath.Join(ConfigDir, "plug", pldir, filePath)
-	if _, err := os.Stat(fullpath); err == nil {
-		AddRealRuntimeFile(filetype, realFile(fullpath))
-	} else {
-		fullpath = path.Join("runtime", "plugins", pldir, filePath)
-		AddRuntimeFile(filetype, assetFile(fullpath))
-	}
+	pl.TrackRuntimeFiles(filetype, func() {
+		if _, err := os.Stat(fullpath); err == nil {
+			AddRealRuntimeFile(filetype, realFile(fullpath))
+		} else {
+			fullpath = path.Join("runtime", "plugins", pldir, filePath)
+			AddRuntimeFile(filetype, assetFile(fullpath))
+		}
+	})
 	return nil
 }
 
@@ -314,16 +367,18 @@ func PluginAddRuntimeFilesFromDirectory(plugin string, filetype RTFiletype, dire
 	}
 	pldir := pl.DirName
 	fullpath := filepath.Join(ConfigDir, "plug", pldir, directory)
-	if _, err := os.Stat(fullpath); err == nil {
-		AddRuntimeFilesFromDirectory(filetype, fullpath, pattern)
-	} else {
-		fullpath = path.Join("runtime", "plugins", pldir, directory)
-		AddRuntimeFilesFromAssets(filetype, fullpath, pattern)
-	}
+	pl.TrackRuntimeFiles(filetype, func() {
+		if _, err := os.Stat(fullpath); err == nil {
+			AddRuntimeFilesFromDirectory(filetype, fullpath, pattern)
+		} else {
+			fullpath = path.Join("runtime", "plugins", pldir, directory)
+			AddRuntimeFilesFromAssets(filetype, fullpath, pattern)
+		}
+	})
 	return nil
 }
 
 // PluginAddRuntimeFileFromMemory adds a file to the runtime files for a plugin from a given string
 func PluginAddRuntimeFileFromMemory(filetype RTFiletype, filename, data string) {
-	AddRealRuntimeFile(filetype, memoryFile{filename, []byte(data)})
+	AddRealRuntimeFile(filetype, &memoryFile{filename, []byte(data)})
 }
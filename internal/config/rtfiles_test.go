@@ -40,3 +40,18 @@ func TestFindFile(t *testing.T) {
 	e := FindRuntimeFile(RTSyntax, "foobar")
 	assert.Nil(t, e)
 }
+
+func TestTrackRuntimeFiles(t *testing.T) {
+	p := &Plugin{Name: "test"}
+	n := len(ListRuntimeFiles(RTHelp))
+	p.TrackRuntimeFiles(RTHelp, func() {
+		PluginAddRuntimeFileFromMemory(RTHelp, "test", "help")
+	})
+	assert.NotNil(t, FindRuntimeFile(RTHelp, "test"))
+
+	for _, f := range p.cleanups {
+		f()
+	}
+	assert.Nil(t, FindRuntimeFile(RTHelp, "test"))
+	assert.Len(t, ListRuntimeFiles(RTHelp), n)
+}
@@ -0,0 +1,279 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/zyedidia/glob"
+	"github.com/zyedidia/micro/v2/internal/util"
+)
+
+// A SettingsTarget describes the buffer that the selectors of the sections
+// of settings.json are matched against
+type SettingsTarget struct {
+	// Path is the absolute path of the buffer's file
+	Path string
+	// FileType is the filetype of the buffer, which is empty while it is
+	// not known yet
+	FileType string
+	// Size is the size of the buffer in bytes
+	Size int64
+	// Readonly is true if the buffer cannot be edited or its file cannot
+	// be written
+	Readonly bool
+	// Scheme is the kind of buffer: file, help, log, scratch, raw, info or
+	// stdout
+	Scheme string
+}
+
+// A condition is one of the selectors of a section key, which are
+// separated by "&&"
+type condition struct {
+	kind  string
+	value string
+	glob  *glob.Glob
+	size  int64
+}
+
+// A section is a map of settings.json whose key is a selector
+type section struct {
+	key      string
+	layer    int
+	conds    []condition
+	priority float64
+	settings map[string]interface{}
+}
+
+var sizeUnits = []struct {
+	suffix string
+	mult   int64
+}{
+	{"GB", 1 << 30},
+	{"MB", 1 << 20},
+	{"KB", 1 << 10},
+	{"B", 1},
+}
+
+// parseSize parses a size such as "10MB"
+func parseSize(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	mult := int64(1)
+	for _, u := range sizeUnits {
+		if strings.HasSuffix(strings.ToUpper(s), u.suffix) {
+			s = strings.TrimSpace(s[:len(s)-len(u.suffix)])
+			mult = u.mult
+			break
+		}
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || f < 0 {
+		return 0, errors.New("invalid size " + s)
+	}
+	return int64(f * float64(mult)), nil
+}
+
+// parseSelector parses the key of a section of settings.json into its
+// conditions
+func parseSelector(key string) ([]condition, error) {
+	var conds []condition
+	for _, part := range strings.Split(key, "&&") {
+		part = strings.TrimSpace(part)
+		var c condition
+		switch {
+		case part == "":
+			return nil, errors.New("empty selector")
+		case strings.HasPrefix(part, "ft:"):
+			c = condition{kind: "ft", value: part[3:]}
+		case strings.HasPrefix(part, "scheme:"):
+			c = condition{kind: "scheme", value: part[7:]}
+		case strings.HasPrefix(part, "readonly:"):
+			v := part[9:]
+			if v != "true" && v != "false" {
+				return nil, errors.New("readonly: must be followed by true or false")
+			}
+			c = condition{kind: "readonly", value: v}
+		case strings.HasPrefix(part, "dir:"):
+			dir, err := util.ReplaceHome(part[4:])
+			if err != nil {
+				return nil, err
+			}
+			if abs, err := filepath.Abs(dir); err == nil {
+				dir = abs
+			}
+			c = condition{kind: "dir", value: filepath.Clean(dir)}
+		case strings.HasPrefix(part, "size>"), strings.HasPrefix(part, "size<"):
+			size, err := parseSize(part[5:])
+			if err != nil {
+				return nil, err
+			}
+			c = condition{kind: part[:5], size: size}
+		default:
+			g, err := glob.Compile(part)
+			if err != nil {
+				return nil, err
+			}
+			c = condition{kind: "glob", value: part, glob: g}
+		}
+		conds = append(conds, c)
+	}
+	return conds, nil
+}
+
+func (c condition) match(t SettingsTarget) bool {
+	switch c.kind {
+	case "ft":
+		return t.FileType == c.value
+	case "scheme":
+		return t.Scheme == c.value
+	case "readonly":
+		return strconv.FormatBool(t.Readonly) == c.value
+	case "dir":
+		return t.Path == c.value || strings.HasPrefix(t.Path, strings.TrimSuffix(c.value, string(filepath.Separator))+string(filepath.Separator))
+	case "size>":
+		return t.Size > c.size
+	case "size<":
+		return t.Size < c.size
+	case "glob":
+		return c.glob.MatchString(t.Path)
+	}
+	return false
+}
+
+// hasFileType returns whether the section depends on the filetype
+func (s *section) hasFileType() bool {
+	for _, c := range s.conds {
+		if c.kind == "ft" {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *section) match(t SettingsTarget) bool {
+	for _, c := range s.conds {
+		if !c.match(t) {
+			return false
+		}
+	}
+	return true
+}
+
+// isSection returns whether the given entry of settings.json is a section
+func isSection(k string, v interface{}) bool {
+	return k != "filetypes" && strings.HasPrefix(reflect.TypeOf(v).String(), "map")
+}
+
+// matchingSections returns the sections of settings.json matching the
+// target, in the order in which they are applied: by priority, then the
+// project's settings.json after the user's one, then the more specific
+// sections (with more selectors) and the filetype sections last
+func matchingSections(t SettingsTarget) []*section {
+	var sections []*section
+	for i, layer := range settingsLayers() {
+		for k, v := range layer {
+			if !isSection(k, v) {
+				continue
+			}
+			conds, err := parseSelector(k)
+			if err != nil {
+				continue
+			}
+			s := &section{key: k, layer: i, conds: conds, settings: v.(map[string]interface{})}
+			if p, ok := s.settings["priority"].(float64); ok {
+				s.priority = p
+			}
+			if s.match(t) {
+				sections = append(sections, s)
+			}
+		}
+	}
+
+	sort.SliceStable(sections, func(i, j int) bool {
+		a, b := sections[i], sections[j]
+		if a.priority != b.priority {
+			return a.priority < b.priority
+		}
+		if a.layer != b.layer {
+			return a.layer < b.layer
+		}
+		if len(a.conds) != len(b.conds) {
+			return len(a.conds) < len(b.conds)
+		}
+		if a.hasFileType() != b.hasFileType() {
+			return b.hasFileType()
+		}
+		return a.key < b.key
+	})
+	return sections
+}
+
+// winningSections returns, for each option set by the matching sections,
+// the section whose value is used
+func winningSections(t SettingsTarget) map[string]*section {
+	winners := make(map[string]*section)
+	for _, s := range matchingSections(t) {
+		for k := range s.settings {
+			if k != "priority" {
+				winners[k] = s
+			}
+		}
+	}
+	return winners
+}
+
+// UpdatePathGlobLocals scans the already parsed settings and sets the options locally
+// based on the sections of settings.json matching the target which do not
+// depend on its filetype (globs, "dir:", "size>", "readonly:" and "scheme:")
+// Must be called after ReadSettings
+func UpdatePathGlobLocals(settings map[string]interface{}, t SettingsTarget) {
+	for _, s := range matchingSections(t) {
+		if s.hasFileType() {
+			continue
+		}
+		for k, v := range s.settings {
+			if k != "priority" {
+				settings[k] = v
+			}
+		}
+	}
+}
+
+// UpdateFileTypeLocals scans the already parsed settings and sets the options locally
+// based on the sections of settings.json which select the target's filetype
+// with "ft:", unless another section with a higher priority sets the option
+// Must be called after ReadSettings
+func UpdateFileTypeLocals(settings map[string]interface{}, t SettingsTarget) {
+	for k, s := range winningSections(t) {
+		if s.hasFileType() && k != "filetype" {
+			settings[k] = s.settings[k]
+		}
+	}
+}
+
+// ExplainSetting returns a description of every section of settings.json
+// matching the target which sets the given option, in the order in which
+// they are applied (so that the last one wins)
+func ExplainSetting(option string, t SettingsTarget) []string {
+	names := settingsLayerNames()
+	var lines []string
+	for _, s := range matchingSections(t) {
+		if v, ok := s.settings[option]; ok {
+			desc := names[s.layer] + " [" + s.key + "]: " + fmt.Sprint(v)
+			if s.priority != 0 {
+				desc += " (priority " + strconv.FormatFloat(s.priority, 'f', -1, 64) + ")"
+			}
+			lines = append(lines, desc)
+		}
+	}
+	return lines
+}
+
+// settingsLayerNames returns the names of the files of settingsLayers
+func settingsLayerNames() []string {
+	return []string{"settings.json", filepath.Join(ProjectDir, "settings.json")}
+}
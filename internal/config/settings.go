@@ -1,6 +1,7 @@
 package config
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -10,6 +11,7 @@ import (
 	"runtime"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/zyedidia/glob"
 	"github.com/micro-editor/json5"
@@ -17,123 +19,21 @@ import (
 	"golang.org/x/text/encoding/htmlindex"
 )
 
-type optionValidator func(string, interface{}) error
-
-// a list of settings that need option validators
-var optionValidators = map[string]optionValidator{
-	"autosave":        validateNonNegativeValue,
-	"clipboard":       validateChoice,
-	"colorcolumn":     validateNonNegativeValue,
-	"colorscheme":     validateColorscheme,
-	"detectlimit":     validateNonNegativeValue,
-	"encoding":        validateEncoding,
-	"fileformat":      validateChoice,
-	"helpsplit":       validateChoice,
-	"matchbracestyle": validateChoice,
-	"multiopen":       validateChoice,
-	"pageoverlap":     validateNonNegativeValue,
-	"reload":          validateChoice,
-	"scrollmargin":    validateNonNegativeValue,
-	"scrollspeed":     validateNonNegativeValue,
-	"tabsize":         validatePositiveValue,
-}
+// The following are filled from the option registry (see RegisterOption)
 
 // a list of settings with pre-defined choices
-var OptionChoices = map[string][]string{
-	"clipboard":       {"internal", "external", "terminal"},
-	"fileformat":      {"unix", "dos"},
-	"helpsplit":       {"hsplit", "vsplit"},
-	"matchbracestyle": {"underline", "highlight"},
-	"multiopen":       {"tab", "hsplit", "vsplit"},
-	"reload":          {"prompt", "auto", "disabled"},
-}
+var OptionChoices = make(map[string][]string)
 
 // a list of settings that can be globally and locally modified and their
 // default values
-var defaultCommonSettings = map[string]interface{}{
-	"autoindent":      true,
-	"autosu":          false,
-	"backup":          true,
-	"backupdir":       "",
-	"basename":        false,
-	"colorcolumn":     float64(0),
-	"cursorline":      true,
-	"detectlimit":     float64(100),
-	"diffgutter":      false,
-	"encoding":        "utf-8",
-	"eofnewline":      true,
-	"fastdirty":       false,
-	"fileformat":      defaultFileFormat(),
-	"filetype":        "unknown",
-	"hlsearch":        false,
-	"hltaberrors":     false,
-	"hltrailingws":    false,
-	"ignorecase":      true,
-	"incsearch":       true,
-	"indentchar":      " ",
-	"keepautoindent":  false,
-	"matchbrace":      true,
-	"matchbraceleft":  true,
-	"matchbracestyle": "underline",
-	"mkparents":       false,
-	"pageoverlap":     float64(2),
-	"permbackup":      false,
-	"readonly":        false,
-	"relativeruler":   false,
-	"reload":          "prompt",
-	"rmtrailingws":    false,
-	"ruler":           true,
-	"savecursor":      false,
-	"saveundo":        false,
-	"scrollbar":       false,
-	"scrollmargin":    float64(3),
-	"scrollspeed":     float64(2),
-	"smartpaste":      true,
-	"softwrap":        false,
-	"splitbottom":     true,
-	"splitright":      true,
-	"statusformatl":   "$(filename) $(modified)$(overwrite)($(line),$(col)) $(status.paste)| ft:$(opt:filetype) | $(opt:fileformat) | $(opt:encoding)",
-	"statusformatr":   "$(bind:ToggleKeyMenu): bindings, $(bind:ToggleHelp): help",
-	"statusline":      true,
-	"syntax":          true,
-	"tabmovement":     false,
-	"tabsize":         float64(4),
-	"tabstospaces":    false,
-	"useprimary":      true,
-	"wordwrap":        false,
-}
+var defaultCommonSettings = make(map[string]interface{})
 
 // a list of settings that should only be globally modified and their
 // default values
-var DefaultGlobalOnlySettings = map[string]interface{}{
-	"autosave":       float64(0),
-	"clipboard":      "external",
-	"colorscheme":    "default",
-	"divchars":       "|-",
-	"divreverse":     true,
-	"fakecursor":     false,
-	"helpsplit":      "hsplit",
-	"infobar":        true,
-	"keymenu":        false,
-	"mouse":          true,
-	"multiopen":      "tab",
-	"parsecursor":    false,
-	"paste":          false,
-	"pluginchannels": []string{"https://raw.githubusercontent.com/micro-editor/plugin-channel/master/channel.json"},
-	"pluginrepos":    []string{},
-	"savehistory":    true,
-	"scrollbarchar":  "|",
-	"sucmd":          "sudo",
-	"tabhighlight":   false,
-	"tabreverse":     true,
-	"xterm":          false,
-}
+var DefaultGlobalOnlySettings = make(map[string]interface{})
 
 // a list of settings that should never be globally modified
-var LocalSettings = []string{
-	"filetype",
-	"readonly",
-}
+var LocalSettings []string
 
 var (
 	ErrInvalidOption = errors.New("Invalid option")
@@ -146,6 +46,10 @@ var (
 	parsedSettings     map[string]interface{}
 	settingsParseError bool
 
+	// projectSettings is the parsed settings.json of ProjectDir, which is
+	// layered over parsedSettings and never written
+	projectSettings map[string]interface{}
+
 	// ModifiedSettings is a map of settings which should be written to disk
 	// because they have been modified by the user in this session
 	ModifiedSettings map[string]bool
@@ -156,7 +60,9 @@ var (
 )
 
 func writeFile(name string, txt []byte) error {
-	return util.SafeWrite(name, txt, false)
+	err := util.SafeWrite(name, txt, false)
+	IgnoreConfigWrite(name)
+	return err
 }
 
 func init() {
@@ -164,34 +70,36 @@ func init() {
 	VolatileSettings = make(map[string]bool)
 }
 
-func validateParsedSettings() error {
+func validateParsedSettings(parsed map[string]interface{}) error {
 	var err error
 	defaults := DefaultAllSettings()
-	for k, v := range parsedSettings {
-		if strings.HasPrefix(reflect.TypeOf(v).String(), "map") {
-			if strings.HasPrefix(k, "ft:") {
-				for k1, v1 := range v.(map[string]interface{}) {
-					if _, ok := defaults[k1]; ok {
-						if e := verifySetting(k1, v1, defaults[k1]); e != nil {
-							err = e
-							parsedSettings[k].(map[string]interface{})[k1] = defaults[k1]
-							continue
-						}
-					}
-				}
-			} else {
-				if _, e := glob.Compile(k); e != nil {
-					err = errors.New("Error with glob setting " + k + ": " + e.Error())
-					delete(parsedSettings, k)
-					continue
+	for k, v := range parsed {
+		if k == "filetypes" {
+			if e := validateFileTypeMap(v); e != nil {
+				err = e
+				delete(parsed, k)
+			}
+			continue
+		}
+		if isSection(k, v) {
+			if _, e := parseSelector(k); e != nil {
+				err = errors.New("Error with section " + k + ": " + e.Error())
+				delete(parsed, k)
+				continue
+			}
+			section := v.(map[string]interface{})
+			if p, ok := section["priority"]; ok {
+				if _, ok := p.(float64); !ok {
+					err = errors.New("Error with section " + k + ": priority must be a number")
+					delete(section, "priority")
 				}
-				for k1, v1 := range v.(map[string]interface{}) {
-					if _, ok := defaults[k1]; ok {
-						if e := verifySetting(k1, v1, defaults[k1]); e != nil {
-							err = e
-							parsedSettings[k].(map[string]interface{})[k1] = defaults[k1]
-							continue
-						}
+			}
+			for k1, v1 := range section {
+				if _, ok := defaults[k1]; ok {
+					if e := verifySetting(k1, v1, defaults[k1]); e != nil {
+						err = e
+						section[k1] = defaults[k1]
+						continue
 					}
 				}
 			}
@@ -203,9 +111,9 @@ func validateParsedSettings() error {
 			s, ok := v.(bool)
 			if ok {
 				if s {
-					parsedSettings["autosave"] = 8.0
+					parsed["autosave"] = 8.0
 				} else {
-					parsedSettings["autosave"] = 0.0
+					parsed["autosave"] = 0.0
 				}
 			}
 			continue
@@ -213,7 +121,7 @@ func validateParsedSettings() error {
 		if _, ok := defaults[k]; ok {
 			if e := verifySetting(k, v, defaults[k]); e != nil {
 				err = e
-				parsedSettings[k] = defaults[k]
+				parsed[k] = defaults[k]
 				continue
 			}
 		}
@@ -221,35 +129,164 @@ func validateParsedSettings() error {
 	return err
 }
 
+// ReadSettings reads the settings.json files of ConfigDir and ProjectDir.
+// If a file cannot be parsed, the settings previously read from it are kept.
 func ReadSettings() error {
-	parsedSettings = make(map[string]interface{})
-	filename := filepath.Join(ConfigDir, "settings.json")
-	if _, e := os.Stat(filename); e == nil {
-		input, err := os.ReadFile(filename)
-		if err != nil {
-			settingsParseError = true
-			return errors.New("Error reading settings.json file: " + err.Error())
+	projectErr := readProjectSettings()
+
+	parsed, err := readSettingsFile(filepath.Join(ConfigDir, "settings.json"))
+	if err != nil {
+		// don't write the settings, which would delete the content of
+		// the file, until the user fixes it
+		settingsParseError = true
+		if parsedSettings == nil {
+			parsedSettings = make(map[string]interface{})
 		}
-		if !strings.HasPrefix(string(input), "null") {
-			// Unmarshal the input into the parsed map
-			err = json5.Unmarshal(input, &parsedSettings)
-			if err != nil {
-				settingsParseError = true
-				return errors.New("Error reading settings.json: " + err.Error())
-			}
-			err = validateParsedSettings()
-			if err != nil {
-				return err
-			}
+		return err
+	}
+	settingsParseError = false
+	parsedSettings = parsed
+
+	if err = validateParsedSettings(parsedSettings); err != nil {
+		return err
+	}
+	return projectErr
+}
+
+// readSettingsFile parses a settings.json file, which is empty if it does
+// not exist
+func readSettingsFile(filename string) (map[string]interface{}, error) {
+	parsed := make(map[string]interface{})
+	if _, e := os.Stat(filename); e != nil {
+		return parsed, nil
+	}
+	input, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, errors.New("Error reading " + filepath.Base(filename) + " file: " + err.Error())
+	}
+	if strings.HasPrefix(string(input), "null") {
+		return parsed, nil
+	}
+	if err = json5.Unmarshal(input, &parsed); err != nil {
+		return nil, NewJSONError(filename, input, err)
+	}
+	return parsed, nil
+}
+
+// A JSONError is an error at a given position of a JSON configuration file
+type JSONError struct {
+	Path string
+	// Line and Col start at 1, Col counts characters
+	Line, Col int
+	Err       error
+}
+
+func (e *JSONError) Error() string {
+	return fmt.Sprintf("Error reading %s at line %d, column %d: %v", filepath.Base(e.Path), e.Line, e.Col, e.Err)
+}
+
+// NewJSONError returns a JSONError giving the position of a JSON5 syntax or
+// type error in data, the content of the file at path. Other errors are
+// returned with the name of the file.
+func NewJSONError(path string, data []byte, err error) error {
+	var offset int64
+	switch e := err.(type) {
+	case *json5.SyntaxError:
+		offset = e.Offset
+	case *json5.UnmarshalTypeError:
+		offset = e.Offset
+	default:
+		return errors.New("Error reading " + filepath.Base(path) + ": " + err.Error())
+	}
+
+	// the offset is just after the character which caused the error
+	before := data[:util.Clamp(int(offset)-1, 0, len(data))]
+	line := bytes.Count(before, []byte{'\n'}) + 1
+	col := utf8.RuneCount(before[bytes.LastIndexByte(before, '\n')+1:]) + 1
+	return &JSONError{path, line, col, err}
+}
+
+// readProjectSettings reads the settings.json of ProjectDir
+func readProjectSettings() error {
+	if ProjectDir == "" {
+		projectSettings = make(map[string]interface{})
+		return nil
+	}
+	parsed, err := readSettingsFile(filepath.Join(ProjectDir, "settings.json"))
+	if err != nil {
+		// keep the previous settings, as for the user's settings.json
+		if projectSettings == nil {
+			projectSettings = make(map[string]interface{})
+		}
+		return err
+	}
+	projectSettings = parsed
+	return validateParsedSettings(projectSettings)
+}
+
+// settingsLayers returns the parsed settings of the user followed by the
+// ones of the project, which take precedence
+func settingsLayers() []map[string]interface{} {
+	return []map[string]interface{}{parsedSettings, projectSettings}
+}
+
+// validateFileTypeMap checks the "filetypes" section of settings.json,
+// which maps filename globs (or "#!interpreter" keys) to filetypes
+func validateFileTypeMap(v interface{}) error {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return errors.New("Error: filetypes must be a map of patterns to filetypes")
+	}
+	for k, ft := range m {
+		if _, ok := ft.(string); !ok {
+			return errors.New("Error: filetype for " + k + " in filetypes must be a string")
+		}
+		if strings.HasPrefix(k, "#!") {
+			continue
+		}
+		if _, e := glob.Compile(k); e != nil {
+			return errors.New("Error with glob " + k + " in filetypes: " + e.Error())
 		}
 	}
 	return nil
 }
 
+// FileTypeMap returns the "filetypes" section of settings.json, mapping
+// filename globs and "#!interpreter" keys to filetypes
+func FileTypeMap() map[string]string {
+	ftmap := make(map[string]string)
+	for _, layer := range settingsLayers() {
+		if m, ok := layer["filetypes"].(map[string]interface{}); ok {
+			for k, v := range m {
+				ftmap[k] = v.(string)
+			}
+		}
+	}
+	return ftmap
+}
+
+// ParsedSettings returns the settings read from settings.json, with the
+// project's settings layered over the user's
 func ParsedSettings() map[string]interface{} {
 	s := make(map[string]interface{})
-	for k, v := range parsedSettings {
-		s[k] = v
+	for _, layer := range settingsLayers() {
+		for k, v := range layer {
+			m, ok := v.(map[string]interface{})
+			if !ok {
+				s[k] = v
+				continue
+			}
+			merged := make(map[string]interface{})
+			if prev, ok := s[k].(map[string]interface{}); ok {
+				for k1, v1 := range prev {
+					merged[k1] = v1
+				}
+			}
+			for k1, v1 := range m {
+				merged[k1] = v1
+			}
+			s[k] = merged
+		}
 	}
 	return s
 }
@@ -283,45 +320,46 @@ func InitGlobalSettings() error {
 	var err error
 	GlobalSettings = DefaultAllSettings()
 
-	for k, v := range parsedSettings {
-		if !strings.HasPrefix(reflect.TypeOf(v).String(), "map") {
-			GlobalSettings[k] = v
+	for _, layer := range settingsLayers() {
+		for k, v := range layer {
+			if k == "$schema" {
+				// only used by other editors to validate the file
+				continue
+			}
+			if !strings.HasPrefix(reflect.TypeOf(v).String(), "map") {
+				GlobalSettings[k] = v
+			}
 		}
 	}
 	return err
 }
 
-// UpdatePathGlobLocals scans the already parsed settings and sets the options locally
-// based on whether the path matches a glob
-// Must be called after ReadSettings
-func UpdatePathGlobLocals(settings map[string]interface{}, path string) {
-	for k, v := range parsedSettings {
-		if strings.HasPrefix(reflect.TypeOf(v).String(), "map") && !strings.HasPrefix(k, "ft:") {
-			g, _ := glob.Compile(k)
-			if g.MatchString(path) {
-				for k1, v1 := range v.(map[string]interface{}) {
-					settings[k1] = v1
-				}
-			}
+// SettingOrigin returns a description of where the given value of an
+// option comes from, for the given buffer (or for the global settings if
+// the target is empty)
+func SettingOrigin(option string, value interface{}, t SettingsTarget) string {
+	if _, ok := VolatileSettings[option]; ok {
+		return "command line flag"
+	}
+
+	layers := settingsLayers()
+	names := settingsLayerNames()
+
+	if t != (SettingsTarget{}) {
+		if s, ok := winningSections(t)[option]; ok && reflect.DeepEqual(s.settings[option], value) {
+			return names[s.layer] + " [" + s.key + "]"
 		}
 	}
-}
 
-// UpdateFileTypeLocals scans the already parsed settings and sets the options locally
-// based on whether the filetype matches to "ft:"
-// Must be called after ReadSettings
-func UpdateFileTypeLocals(settings map[string]interface{}, filetype string) {
-	for k, v := range parsedSettings {
-		if strings.HasPrefix(reflect.TypeOf(v).String(), "map") && strings.HasPrefix(k, "ft:") {
-			if filetype == k[3:] {
-				for k1, v1 := range v.(map[string]interface{}) {
-					if k1 != "filetype" {
-						settings[k1] = v1
-					}
-				}
-			}
+	for i := len(layers) - 1; i >= 0; i-- {
+		if v, ok := layers[i][option]; ok && reflect.DeepEqual(v, value) {
+			return names[i]
 		}
 	}
+	if reflect.DeepEqual(DefaultAllSettings()[option], value) {
+		return "default"
+	}
+	return "set in this session"
 }
 
 // WriteSettings writes the settings to the specified filename as JSON
@@ -343,7 +381,8 @@ func WriteSettings(filename string) error {
 			if !strings.HasPrefix(reflect.TypeOf(v).String(), "map") {
 				cur, okcur := GlobalSettings[k]
 				_, vol := VolatileSettings[k]
-				if def, ok := defaults[k]; ok && okcur && !vol && reflect.DeepEqual(cur, def) {
+				_, proj := projectSettings[k]
+				if def, ok := defaults[k]; ok && okcur && !vol && !proj && reflect.DeepEqual(cur, def) {
 					delete(parsedSettings, k)
 				}
 			}
@@ -389,31 +428,44 @@ func OverwriteSettings(filename string) error {
 }
 
 // RegisterCommonOptionPlug creates a new option (called pl.name). This is meant to be called by plugins to add options.
-func RegisterCommonOptionPlug(pl string, name string, defaultvalue interface{}) error {
-	return RegisterCommonOption(pl+"."+name, defaultvalue)
+// The optional meta table documents and constrains the option (see optionFromMeta).
+func RegisterCommonOptionPlug(pl string, name string, defaultvalue interface{}, meta ...map[string]interface{}) error {
+	return registerOptionPlug(pl, name, ScopeCommon, defaultvalue, meta)
 }
 
 // RegisterGlobalOptionPlug creates a new global-only option (named pl.name)
-func RegisterGlobalOptionPlug(pl string, name string, defaultvalue interface{}) error {
-	return RegisterGlobalOption(pl+"."+name, defaultvalue)
+func RegisterGlobalOptionPlug(pl string, name string, defaultvalue interface{}, meta ...map[string]interface{}) error {
+	return registerOptionPlug(pl, name, ScopeGlobal, defaultvalue, meta)
+}
+
+func registerOptionPlug(pl string, name string, scope OptionScope, defaultvalue interface{}, meta []map[string]interface{}) error {
+	var m map[string]interface{}
+	if len(meta) > 0 {
+		m = meta[0]
+	}
+	o, err := optionFromMeta(pl, pl+"."+name, scope, defaultvalue, m)
+	if err != nil {
+		return err
+	}
+	return RegisterOption(o)
 }
 
 // RegisterCommonOption creates a new option
 func RegisterCommonOption(name string, defaultvalue interface{}) error {
-	if _, ok := GlobalSettings[name]; !ok {
-		GlobalSettings[name] = defaultvalue
+	o, err := optionFromMeta("", name, ScopeCommon, defaultvalue, nil)
+	if err != nil {
+		return err
 	}
-	defaultCommonSettings[name] = defaultvalue
-	return nil
+	return RegisterOption(o)
 }
 
 // RegisterGlobalOption creates a new global-only option
 func RegisterGlobalOption(name string, defaultvalue interface{}) error {
-	if _, ok := GlobalSettings[name]; !ok {
-		GlobalSettings[name] = defaultvalue
+	o, err := optionFromMeta("", name, ScopeGlobal, defaultvalue, nil)
+	if err != nil {
+		return err
 	}
-	DefaultGlobalOnlySettings[name] = defaultvalue
-	return nil
+	return RegisterOption(o)
 }
 
 // GetGlobalOption returns the global value of the given option
@@ -489,8 +541,8 @@ func GetNativeValue(option string, realValue interface{}, value string) (interfa
 
 // OptionIsValid checks if a value is valid for a certain option
 func OptionIsValid(option string, value interface{}) error {
-	if validator, ok := optionValidators[option]; ok {
-		return validator(option, value)
+	if o := LookupOption(option); o != nil {
+		return o.Validate(value)
 	}
 
 	return nil
@@ -498,54 +550,6 @@ func OptionIsValid(option string, value interface{}) error {
 
 // Option validators
 
-func validatePositiveValue(option string, value interface{}) error {
-	nativeValue, ok := value.(float64)
-
-	if !ok {
-		return errors.New("Expected numeric type for " + option)
-	}
-
-	if nativeValue < 1 {
-		return errors.New(option + " must be greater than 0")
-	}
-
-	return nil
-}
-
-func validateNonNegativeValue(option string, value interface{}) error {
-	nativeValue, ok := value.(float64)
-
-	if !ok {
-		return errors.New("Expected numeric type for " + option)
-	}
-
-	if nativeValue < 0 {
-		return errors.New(option + " must be non-negative")
-	}
-
-	return nil
-}
-
-func validateChoice(option string, value interface{}) error {
-	if choices, ok := OptionChoices[option]; ok {
-		val, ok := value.(string)
-		if !ok {
-			return errors.New("Expected string type for " + option)
-		}
-
-		for _, v := range choices {
-			if val == v {
-				return nil
-			}
-		}
-
-		choicesStr := strings.Join(choices, ", ")
-		return errors.New(option + " must be one of: " + choicesStr)
-	}
-
-	return errors.New("Option has no pre-defined choices")
-}
-
 func validateColorscheme(option string, value interface{}) error {
 	colorscheme, ok := value.(string)
 
@@ -0,0 +1,75 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/micro-editor/json5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONErrorPosition(t *testing.T) {
+	data := []byte("{\n    \"ruler\": false,\n    \"tabsize\" 3\n}\n")
+	var parsed map[string]interface{}
+	err := NewJSONError("/tmp/settings.json", data, json5.Unmarshal(data, &parsed))
+
+	jerr, ok := err.(*JSONError)
+	if assert.True(t, ok) {
+		assert.Equal(t, 3, jerr.Line)
+		assert.Equal(t, 15, jerr.Col)
+		assert.Contains(t, jerr.Error(), "settings.json at line 3")
+	}
+}
+
+func TestSectionSelectors(t *testing.T) {
+	oldParsed, oldProject := parsedSettings, projectSettings
+	defer func() { parsedSettings, projectSettings = oldParsed, oldProject }()
+
+	dir, _ := filepath.Abs("/src/work")
+	parsedSettings = map[string]interface{}{
+		"*.go":                   map[string]interface{}{"tabsize": 2.0, "ruler": false},
+		"ft:go":                  map[string]interface{}{"tabsize": 3.0},
+		"dir:/src/work":          map[string]interface{}{"tabsize": 4.0},
+		"dir:/src/work && ft:go": map[string]interface{}{"tabsize": 5.0},
+		"size>1KB":               map[string]interface{}{"syntax": false, "priority": 10.0},
+		"readonly:true":          map[string]interface{}{"ruler": true},
+		"scheme:help":            map[string]interface{}{"softwrap": true},
+	}
+	projectSettings = map[string]interface{}{}
+
+	target := SettingsTarget{Path: filepath.Join(dir, "main.go"), Size: 100, Scheme: "file"}
+	settings := make(map[string]interface{})
+	UpdatePathGlobLocals(settings, target)
+	assert.Equal(t, 4.0, settings["tabsize"])
+	assert.Equal(t, false, settings["ruler"])
+	assert.NotContains(t, settings, "syntax")
+	assert.NotContains(t, settings, "priority")
+
+	target.FileType = "go"
+	UpdateFileTypeLocals(settings, target)
+	assert.Equal(t, 5.0, settings["tabsize"])
+	assert.Equal(t, "settings.json [dir:/src/work && ft:go]", SettingOrigin("tabsize", 5.0, target))
+	assert.Len(t, ExplainSetting("tabsize", target), 4)
+
+	target = SettingsTarget{Path: "/tmp/big.txt", Size: 2048, Readonly: true, Scheme: "help"}
+	settings = make(map[string]interface{})
+	UpdatePathGlobLocals(settings, target)
+	assert.Equal(t, false, settings["syntax"])
+	assert.Equal(t, true, settings["ruler"])
+	assert.Equal(t, true, settings["softwrap"])
+	assert.NotContains(t, settings, "tabsize")
+}
+
+func TestParseSelector(t *testing.T) {
+	_, err := parseSelector("size>10MB && ft:go && *.go")
+	assert.Nil(t, err)
+	_, err = parseSelector("size>big")
+	assert.NotNil(t, err)
+	_, err = parseSelector("readonly:yes")
+	assert.NotNil(t, err)
+	_, err = parseSelector("ft:go && ")
+	assert.NotNil(t, err)
+
+	size, _ := parseSize("1.5KB")
+	assert.Equal(t, int64(1536), size)
+}
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/micro-editor/json5"
+)
+
+// A Snippet is a template which is expanded in place of its prefix. Its
+// body uses the snippet syntax of VS Code and of the language server
+// protocol, with tabstops ($1, ${2:placeholder}, ${3|one,two|}, $0) and
+// variables ($TM_FILENAME, ${CURRENT_YEAR}...).
+type Snippet struct {
+	Name        string
+	Prefixes    []string
+	Body        string
+	Description string
+}
+
+// snippetDef is a snippet as written in a snippet file, where the prefix
+// and the body may be a string or a list of strings
+type snippetDef struct {
+	Prefix      interface{} `json:"prefix"`
+	Body        interface{} `json:"body"`
+	Description string      `json:"description"`
+}
+
+// stringOrList converts a string or a list of strings of a snippet file
+func stringOrList(v interface{}) ([]string, bool) {
+	if s, ok := v.(string); ok {
+		return []string{s}, true
+	}
+	return stringList(v)
+}
+
+// ParseSnippets parses the snippets of a snippet file, a JSON object which
+// maps the names of the snippets to their prefix, body and description
+func ParseSnippets(data []byte) ([]*Snippet, error) {
+	var defs map[string]snippetDef
+	if err := json5.Unmarshal(data, &defs); err != nil {
+		return nil, err
+	}
+
+	snippets := make([]*Snippet, 0, len(defs))
+	for name, def := range defs {
+		prefixes, ok := stringOrList(def.Prefix)
+		if !ok || len(prefixes) == 0 {
+			return nil, errors.New("Snippet " + name + " has no valid prefix")
+		}
+		body, ok := stringOrList(def.Body)
+		if !ok {
+			return nil, errors.New("Snippet " + name + " has no valid body")
+		}
+		snippets = append(snippets, &Snippet{
+			Name:        name,
+			Prefixes:    prefixes,
+			Body:        strings.Join(body, "\n"),
+			Description: def.Description,
+		})
+	}
+	return snippets, nil
+}
+
+// ListSnippets returns the snippets of the given filetype, which are in the
+// snippet runtime files named after it, such as snippets/go.json
+func ListSnippets(filetype string) ([]*Snippet, error) {
+	var all []*Snippet
+	for _, f := range ListRuntimeFiles(RTSnippet) {
+		if f.Name() != filetype {
+			continue
+		}
+		data, err := f.Data()
+		if err != nil {
+			return nil, err
+		}
+		snippets, err := ParseSnippets(data)
+		if err != nil {
+			if path, ok := f.(realFile); ok {
+				err = NewJSONError(string(path), data, err)
+			}
+			return nil, err
+		}
+		sort.Slice(snippets, func(i, j int) bool {
+			return snippets[i].Name < snippets[j].Name
+		})
+		all = append(all, snippets...)
+	}
+	return all, nil
+}
+
+// FindSnippet returns the snippet of the given filetype with the given
+// prefix, or nil if there is none. When several snippets have the same
+// prefix, the first one by name in the first snippet file is used.
+func FindSnippet(filetype, prefix string) (*Snippet, error) {
+	snippets, err := ListSnippets(filetype)
+	for _, s := range snippets {
+		if containsString(s.Prefixes, prefix) {
+			return s, nil
+		}
+	}
+	return nil, err
+}
@@ -0,0 +1,37 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnippets(t *testing.T) {
+	snippets, err := ParseSnippets([]byte(`{
+		// comments are allowed
+		"b": {"prefix": ["x", "y"], "body": ["line 1", "\tline 2"]},
+		"a": {"prefix": "y", "body": "$0", "description": "a snippet"}
+	}`))
+	assert.NoError(t, err)
+	assert.Len(t, snippets, 2)
+
+	_, err = ParseSnippets([]byte(`{"c": {"body": "text"}}`))
+	assert.Error(t, err)
+
+	InitRuntimeFiles(false)
+	AddRuntimeFile(RTSnippet, memoryFile{"test", []byte(`{
+		"b": {"prefix": ["x", "y"], "body": ["line 1", "\tline 2"]},
+		"a": {"prefix": "y", "body": "$0"}
+	}`)})
+	s, err := FindSnippet("test", "x")
+	assert.NoError(t, err)
+	assert.Equal(t, "line 1\n\tline 2", s.Body)
+	s, _ = FindSnippet("test", "y")
+	assert.Equal(t, "a", s.Name)
+	s, _ = FindSnippet("test", "z")
+	assert.Nil(t, s)
+
+	s, err = FindSnippet("go", "iferr")
+	assert.NoError(t, err)
+	assert.NotNil(t, s)
+}
@@ -0,0 +1,89 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ConfigFileChanged receives the path of a settings.json or bindings.json
+// file of ConfigDir or ProjectDir when it is modified on disk
+var ConfigFileChanged chan string
+
+// fileState is what the watcher compares to notice that a file changed
+type fileState struct {
+	modTime time.Time
+	size    int64
+	exists  bool
+}
+
+var (
+	watchLock    sync.Mutex
+	watchedFiles map[string]fileState
+)
+
+func init() {
+	ConfigFileChanged = make(chan string)
+}
+
+func statFile(path string) fileState {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileState{}
+	}
+	return fileState{info.ModTime(), info.Size(), true}
+}
+
+// watchedPaths returns the configuration files which are reloaded when
+// they change
+func watchedPaths() []string {
+	var paths []string
+	for _, dir := range []string{ConfigDir, ProjectDir} {
+		if dir != "" {
+			paths = append(paths, filepath.Join(dir, "settings.json"), filepath.Join(dir, "bindings.json"))
+		}
+	}
+	return paths
+}
+
+// StartConfigWatcher checks the settings.json and bindings.json files every
+// second and sends the files which have changed to ConfigFileChanged
+func StartConfigWatcher() {
+	paths := watchedPaths()
+
+	watchLock.Lock()
+	watchedFiles = make(map[string]fileState)
+	for _, p := range paths {
+		watchedFiles[p] = statFile(p)
+	}
+	watchLock.Unlock()
+
+	go func() {
+		for range time.Tick(time.Second) {
+			for _, p := range paths {
+				state := statFile(p)
+
+				watchLock.Lock()
+				changed := state != watchedFiles[p]
+				watchedFiles[p] = state
+				watchLock.Unlock()
+
+				if changed {
+					ConfigFileChanged <- p
+				}
+			}
+		}
+	}()
+}
+
+// IgnoreConfigWrite tells the watcher that micro itself has written a
+// configuration file, so that it is not reloaded
+func IgnoreConfigWrite(path string) {
+	watchLock.Lock()
+	defer watchLock.Unlock()
+
+	if _, ok := watchedFiles[path]; ok {
+		watchedFiles[path] = statFile(path)
+	}
+}
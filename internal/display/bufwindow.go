@@ -28,6 +28,15 @@ type BufWindow struct {
 	hasMessage       bool
 	maxLineNumLength int
 	drawDivider      bool
+	scrollbarWidth   int
+	minimapWidth     int
+
+	// overview holds the marks of the rows of the scroll bar
+	overview []overviewMark
+
+	// Floating is true if the window is drawn over the other windows, in
+	// which case it never draws a divider below the buffer
+	Floating bool
 }
 
 // NewBufWindow creates a new window at a location in the screen with a width and height
@@ -113,7 +122,7 @@ func (w *BufWindow) updateDisplayInfo() {
 	b := w.Buf
 
 	w.drawDivider = false
-	if !b.Settings["statusline"].(bool) {
+	if !b.Settings["statusline"].(bool) && !w.Floating {
 		_, h := screen.Screen.Size()
 		infoY := h
 		if config.GetGlobalOption("infobar").(bool) {
@@ -129,9 +138,9 @@ func (w *BufWindow) updateDisplayInfo() {
 		w.bufHeight--
 	}
 
-	scrollbarWidth := 0
+	w.scrollbarWidth = 0
 	if w.Buf.Settings["scrollbar"].(bool) && w.Buf.LinesNum() > w.Height && w.Width > 0 {
-		scrollbarWidth = 1
+		w.scrollbarWidth = 1
 	}
 
 	w.hasMessage = len(b.Messages) > 0
@@ -151,12 +160,17 @@ func (w *BufWindow) updateDisplayInfo() {
 		w.gutterOffset += w.maxLineNumLength + 1
 	}
 
-	if w.gutterOffset > w.Width-scrollbarWidth {
-		w.gutterOffset = w.Width - scrollbarWidth
+	if w.gutterOffset > w.Width-w.scrollbarWidth {
+		w.gutterOffset = w.Width - w.scrollbarWidth
+	}
+
+	w.minimapWidth = 0
+	if b.Settings["minimap"].(bool) && w.Width-w.gutterOffset-w.scrollbarWidth-minimapWidth >= minimapMinBufWidth {
+		w.minimapWidth = minimapWidth
 	}
 
 	prevBufWidth := w.bufWidth
-	w.bufWidth = w.Width - w.gutterOffset - scrollbarWidth
+	w.bufWidth = w.Width - w.gutterOffset - w.scrollbarWidth - w.minimapWidth
 
 	if w.bufWidth != prevBufWidth && w.Buf.Settings["softwrap"].(bool) {
 		for _, c := range w.Buf.GetCursors() {
@@ -168,8 +182,10 @@ func (w *BufWindow) updateDisplayInfo() {
 func (w *BufWindow) getStartInfo(n, lineN int) ([]byte, int, int, *tcell.Style) {
 	tabsize := util.IntOpt(w.Buf.Settings["tabsize"])
 	width := 0
+	textwidth := 0
 	bloc := buffer.Loc{0, lineN}
 	b := w.Buf.LineBytes(lineN)
+	inline := w.Buf.InlineWidths(lineN)
 	curStyle := config.DefStyle
 	var s *tcell.Style
 	for len(b) > 0 {
@@ -183,15 +199,18 @@ func (w *BufWindow) getStartInfo(n, lineN int) ([]byte, int, int, *tcell.Style)
 		w := 0
 		switch r {
 		case '\t':
-			ts := tabsize - (width % tabsize)
+			ts := tabsize - (textwidth % tabsize)
 			w = ts
 		default:
 			w = runewidth.RuneWidth(r)
 		}
-		if width+w > n {
+		// the inline virtual text before the character is skipped along
+		// with it
+		if width+inline[bloc.X]+w > n {
 			return b, n - width, bloc.X, s
 		}
-		width += w
+		width += inline[bloc.X] + w
+		textwidth += w
 		b = b[size:]
 		bloc.X++
 	}
@@ -238,7 +257,7 @@ func (w *BufWindow) Relocate() bool {
 
 	// horizontal relocation (scrolling)
 	if !b.Settings["softwrap"].(bool) {
-		cx := activeC.GetVisualX(false)
+		cx := w.VLocFromLoc(activeC.Loc).VisualX
 		rw := runewidth.RuneWidth(activeC.RuneUnder(activeC.X))
 		if rw == 0 {
 			rw = 1 // tab or newline
@@ -248,8 +267,8 @@ func (w *BufWindow) Relocate() bool {
 			w.StartCol = cx
 			ret = true
 		}
-		if cx+w.gutterOffset+rw > w.StartCol+w.Width {
-			w.StartCol = cx - w.Width + w.gutterOffset + rw
+		if cx+rw > w.StartCol+w.bufWidth {
+			w.StartCol = cx - w.bufWidth + rw
 			ret = true
 		}
 	}
@@ -362,6 +381,17 @@ func (w *BufWindow) getStyle(style tcell.Style, bloc buffer.Loc) (tcell.Style, b
 	return style, false
 }
 
+// getOverlayStyle returns the style of the highest priority overlay
+// covering the given character position, if there is one
+func getOverlayStyle(overlays []*buffer.Overlay, bloc buffer.Loc) (tcell.Style, bool) {
+	for _, o := range overlays {
+		if bloc.GreaterEqual(o.Start) && bloc.LessThan(o.End) {
+			return config.GetColor(o.Group.String()), true
+		}
+	}
+	return config.DefStyle, false
+}
+
 func (w *BufWindow) showCursor(x, y int, main bool) {
 	if w.active {
 		if main {
@@ -478,6 +508,12 @@ func (w *BufWindow) displayBuffer() {
 
 		bline := b.LineBytes(bloc.Y)
 		blineLen := util.CharacterCount(bline)
+		overlays := b.LineOverlays(bloc.Y)
+		inline := w.Buf.InlineWidths(bloc.Y)
+		var inlineText []*buffer.VirtualText
+		if inline != nil {
+			inlineText = b.LineVirtualText(bloc.Y, buffer.VTInline)
+		}
 
 		leadingwsEnd := len(util.GetLeadingWhitespace(bline))
 		trailingwsStart := blineLen - util.CharacterCount(util.GetTrailingWhitespace(bline))
@@ -633,10 +669,29 @@ func (w *BufWindow) displayBuffer() {
 		}
 
 		type glyph struct {
-			r     rune
-			combc []rune
-			style tcell.Style
-			width int
+			r          rune
+			combc      []rune
+			style      tcell.Style
+			width      int
+			virtual    bool
+			showcursor bool
+		}
+
+		// virtual returns the glyphs of the inline virtual text anchored at
+		// the given character, which are width wide. The cursor at this
+		// character is drawn at the start of the virtual text.
+		virtual := func(x, width int) []glyph {
+			var glyphs []glyph
+			for i, vg := range virtualGlyphs(virtualTextAt(inlineText, x), width, true) {
+				glyphs = append(glyphs, glyph{vg.r, nil, virtualTextStyle(vg.vt), vg.width, true, i == 0})
+			}
+			return glyphs
+		}
+		drawVirtual := func(g glyph) {
+			draw(g.r, nil, g.style, false, g.showcursor)
+			for i := 1; i < g.width; i++ {
+				draw('@', nil, g.style, false, false)
+			}
 		}
 
 		var word []glyph
@@ -646,14 +701,21 @@ func (w *BufWindow) displayBuffer() {
 			word = make([]glyph, 0, 1)
 		}
 		wordwidth := 0
+		// the number of characters of the line in word
+		wordchars := 0
 
-		totalwidth := w.StartCol - nColsBeforeStart
+		// tab stops don't take the inline virtual text into account
+		totalwidth := w.StartCol - nColsBeforeStart - buffer.InlineWidthBefore(inline, bslice)
 		for len(line) > 0 && vloc.X < maxWidth {
 			r, combc, size := util.DecodeCharacter(line)
 			line = line[size:]
 
-			loc := buffer.Loc{X: bloc.X + len(word), Y: bloc.Y}
+			loc := buffer.Loc{X: bloc.X + wordchars, Y: bloc.Y}
 			curStyle, _ = w.getStyle(curStyle, loc)
+			style := curStyle
+			if s, ok := getOverlayStyle(overlays, loc); ok {
+				style = s
+			}
 
 			width := 0
 
@@ -667,13 +729,28 @@ func (w *BufWindow) displayBuffer() {
 				totalwidth += width
 			}
 
-			word = append(word, glyph{r, combc, curStyle, width})
+			vwidth := w.inlineWidth(inline, loc.X, width)
+			if vwidth > 0 && softwrap {
+				// the virtual text wraps along with the character
+				word = append(word, virtual(loc.X, vwidth)...)
+				wordwidth += vwidth
+			} else if vwidth > 0 {
+				for _, g := range virtual(loc.X, vwidth) {
+					if vloc.X+g.width > maxWidth {
+						break
+					}
+					drawVirtual(g)
+				}
+			}
+			word = append(word, glyph{r, combc, style, width, false, vwidth == 0})
+			wordchars++
 			wordwidth += width
 
 			// Collect a complete word to know its width.
 			// If wordwrap is off, every single character is a complete "word".
+			// A word also ends before inline virtual text.
 			if wordwrap {
-				if !util.IsWhitespace(r) && len(line) > 0 && wordwidth < w.bufWidth {
+				if !util.IsWhitespace(r) && len(line) > 0 && wordwidth < w.bufWidth && inline[loc.X+1] == 0 {
 					continue
 				}
 			}
@@ -697,7 +774,11 @@ func (w *BufWindow) displayBuffer() {
 			}
 
 			for _, r := range word {
-				draw(r.r, r.combc, r.style, true, true)
+				if r.virtual {
+					drawVirtual(r)
+					continue
+				}
+				draw(r.r, r.combc, r.style, true, r.showcursor)
 
 				// Draw any extra characters either spaces for tabs or @ for incomplete wide runes
 				if r.width > 1 {
@@ -715,6 +796,7 @@ func (w *BufWindow) displayBuffer() {
 
 			word = word[:0]
 			wordwidth = 0
+			wordchars = 0
 
 			// If we reach the end of the window then we either stop or we wrap for softwrap
 			if vloc.X >= maxWidth {
@@ -752,8 +834,31 @@ func (w *BufWindow) displayBuffer() {
 		}
 
 		if vloc.X != maxWidth {
-			// Display newline within a selection
-			draw(' ', nil, config.DefStyle, true, true)
+			// the inline virtual text at the end of the line, which is cut
+			// at the end of the window
+			trailing := 0
+			if len(line) == 0 {
+				trailing = util.Min(inline[blineLen], maxWidth-vloc.X)
+				for _, g := range virtual(blineLen, trailing) {
+					drawVirtual(g)
+				}
+			}
+
+			if vloc.X < maxWidth {
+				// Display newline within a selection
+				draw(' ', nil, config.DefStyle, true, trailing == 0)
+			}
+
+			// the end of line virtual text follows after a space
+			eol := b.LineVirtualText(bloc.Y, buffer.VTEndOfLine)
+			for i, vt := range eol {
+				if i > 0 && vloc.X < maxWidth {
+					draw(' ', nil, config.DefStyle, false, false)
+				}
+				for _, g := range virtualGlyphs([]*buffer.VirtualText{vt}, maxWidth-vloc.X, false) {
+					drawVirtual(glyph{g.r, nil, virtualTextStyle(vt), g.width, true, false})
+				}
+			}
 		}
 
 		bloc.X = w.StartCol
@@ -815,6 +920,8 @@ func (w *BufWindow) displayScrollBar() {
 		for y := barstart; y < util.Min(barstart+barsize, w.Y+w.bufHeight); y++ {
 			screen.SetContent(scrollX, y, scrollBarRune[0], nil, scrollBarStyle)
 		}
+
+		w.displayOverview(barstart, barstart+barsize, scrollBarStyle)
 	}
 }
 
@@ -824,5 +931,6 @@ func (w *BufWindow) Display() {
 
 	w.displayStatusLine()
 	w.displayScrollBar()
+	w.displayMinimap()
 	w.displayBuffer()
 }
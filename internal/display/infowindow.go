@@ -83,6 +83,11 @@ func (i *InfoWindow) BufView() View {
 	}
 }
 
+// ScrollBarLine returns false since the infobar has no scroll bar
+func (i *InfoWindow) ScrollBarLine(x, y int) (int, bool) {
+	return 0, false
+}
+
 func (i *InfoWindow) Scroll(s SLoc, n int) SLoc        { return s }
 func (i *InfoWindow) Diff(s1, s2 SLoc) int             { return 0 }
 func (i *InfoWindow) SLocFromLoc(loc buffer.Loc) SLoc  { return SLoc{0, 0} }
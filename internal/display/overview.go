@@ -0,0 +1,306 @@
+package display
+
+import (
+	runewidth "github.com/mattn/go-runewidth"
+	"github.com/micro-editor/tcell/v2"
+	"github.com/zyedidia/micro/v2/internal/buffer"
+	"github.com/zyedidia/micro/v2/internal/config"
+	"github.com/zyedidia/micro/v2/internal/screen"
+	"github.com/zyedidia/micro/v2/internal/util"
+	"github.com/zyedidia/micro/v2/pkg/highlight"
+)
+
+const (
+	// minimapWidth is the width of the minimap in cells
+	minimapWidth = 10
+	// minimapColumns is the number of columns of text shown by a dot of
+	// the minimap, a cell showing two dots horizontally and four lines
+	minimapColumns = 4
+	// minimapMinBufWidth is the minimal width left to the buffer for the
+	// minimap to be shown
+	minimapMinBufWidth = 2 * minimapWidth
+
+	// overviewRune is the character of the marks of the scroll bar
+	overviewRune = '━' // Heavy horizontal line
+)
+
+// The kinds of marks of the overview ruler drawn on the scroll bar, in
+// increasing order of priority
+const (
+	markNone = iota
+	markDiff
+	markCursor
+	markSearch
+	markWarning
+	markError
+)
+
+// overviewMark is the mark of a row of the scroll bar: the line of the
+// highest priority mark among the lines of the row
+type overviewMark struct {
+	kind  int
+	line  int
+	style tcell.Style
+}
+
+// brailleDots are the bits of the dots of a braille character, by line
+// and column
+var brailleDots = [4][2]rune{
+	{0x01, 0x08},
+	{0x02, 0x10},
+	{0x04, 0x20},
+	{0x40, 0x80},
+}
+
+// markStyle returns the style of a mark of the scroll bar: the foreground
+// color of the first of the given colorscheme groups which is defined, or
+// its background color if the group is prefixed with "bg:"
+func markStyle(groups ...string) tcell.Style {
+	for _, g := range groups {
+		bg := false
+		if len(g) > 3 && g[:3] == "bg:" {
+			g, bg = g[3:], true
+		}
+		if s, ok := config.Colorscheme[g]; ok {
+			fg, b, _ := s.Decompose()
+			if bg {
+				fg = b
+			}
+			return config.DefStyle.Foreground(fg)
+		}
+	}
+	return config.DefStyle
+}
+
+// updateOverview computes the marks of the rows of the scroll bar
+func (w *BufWindow) updateOverview() {
+	b := w.Buf
+	rows := w.bufHeight
+	n := b.LinesNum()
+	if cap(w.overview) >= rows {
+		w.overview = w.overview[:rows]
+	} else {
+		w.overview = make([]overviewMark, rows)
+	}
+	for i := range w.overview {
+		w.overview[i] = overviewMark{}
+	}
+	if rows <= 0 || n == 0 {
+		return
+	}
+
+	mark := func(line, kind int, style tcell.Style) {
+		if line < 0 || line >= n {
+			return
+		}
+		m := &w.overview[line*rows/n]
+		if kind > m.kind {
+			*m = overviewMark{kind, line, style}
+		}
+	}
+
+	if b.Settings["diffgutter"].(bool) {
+		added := markStyle("diff-added")
+		modified := markStyle("diff-modified")
+		deleted := markStyle("diff-deleted")
+		for line := 0; line < n; line++ {
+			switch b.DiffStatus(line) {
+			case buffer.DSAdded:
+				mark(line, markDiff, added)
+			case buffer.DSModified:
+				mark(line, markDiff, modified)
+			case buffer.DSDeletedAbove:
+				mark(line, markDiff, deleted)
+			}
+		}
+	}
+
+	if b.NumCursors() > 1 {
+		style := markStyle("scrollbar.cursor")
+		for _, c := range b.GetCursors() {
+			mark(c.Y, markCursor, style)
+		}
+	}
+
+	if b.HighlightSearch && b.LastSearch != "" {
+		style := markStyle("scrollbar.search", "bg:hlsearch")
+		for _, line := range b.SearchMatchLines() {
+			mark(line, markSearch, style)
+		}
+	}
+
+	warning := markStyle("scrollbar.warning", "gutter-warning")
+	errorStyle := markStyle("scrollbar.error", "gutter-error")
+	for _, m := range b.Messages {
+		var kind int
+		var style tcell.Style
+		switch m.Kind {
+		case buffer.MTWarning:
+			kind, style = markWarning, warning
+		case buffer.MTError:
+			kind, style = markError, errorStyle
+		default:
+			continue
+		}
+		for line := m.Start.Y; line <= m.End.Y; line++ {
+			mark(line, kind, style)
+		}
+	}
+}
+
+// displayOverview draws the marks of the scroll bar over it. The marks on
+// the thumb, between the given screen rows, keep the style of the thumb
+// with the color of the mark, so that the thumb stays visible.
+func (w *BufWindow) displayOverview(thumbStart, thumbEnd int, thumbStyle tcell.Style) {
+	w.updateOverview()
+	scrollX := w.X + w.Width - 1
+	for row, m := range w.overview {
+		if m.kind == markNone {
+			continue
+		}
+		style := m.style
+		if y := w.Y + row; y >= thumbStart && y < thumbEnd {
+			fg, _, _ := m.style.Decompose()
+			if _, _, attr := thumbStyle.Decompose(); attr&tcell.AttrReverse != 0 {
+				style = thumbStyle.Background(fg)
+			} else {
+				style = thumbStyle.Foreground(fg)
+			}
+		}
+		screen.SetContent(scrollX, w.Y+row, overviewRune, nil, style)
+	}
+}
+
+// minimapStart returns the first line shown by the minimap, which scrolls
+// along with the view when the buffer has more lines than it can show
+func (w *BufWindow) minimapStart() int {
+	n := w.Buf.LinesNum()
+	lines := len(brailleDots) * w.bufHeight
+	if n <= lines || n <= w.bufHeight {
+		return 0
+	}
+	start := w.StartLine.Line * (n - lines) / (n - w.bufHeight)
+	return util.Clamp(start, 0, n-lines)
+}
+
+// minimapLine adds the dots and colors of the given line to the cells of
+// a row of the minimap, the line being the dot line i of the cells
+func (w *BufWindow) minimapLine(line, i int, dots []rune, styles []*tcell.Style) {
+	tabsize := util.IntOpt(w.Buf.Settings["tabsize"])
+	match := w.Buf.Match(line)
+	b := w.Buf.LineBytes(line)
+
+	var group highlight.Group
+	hasGroup := false
+	vx := 0
+	for x := 0; len(b) > 0; x++ {
+		r, _, size := util.DecodeCharacter(b)
+		b = b[size:]
+
+		if g, ok := match[x]; ok {
+			group, hasGroup = g, true
+		}
+
+		width := runewidth.RuneWidth(r)
+		if r == '\t' {
+			width = tabsize - vx%tabsize
+		}
+		if !util.IsWhitespace(r) {
+			col := vx / minimapColumns
+			cell := col / 2
+			if cell >= len(dots) {
+				return
+			}
+			dots[cell] |= brailleDots[i][col%2]
+			if styles[cell] == nil {
+				style := config.DefStyle
+				if hasGroup {
+					fg, _, _ := config.GetColor(group.String()).Decompose()
+					style = style.Foreground(fg)
+				}
+				styles[cell] = &style
+			}
+		}
+		vx += width
+	}
+}
+
+// displayMinimap draws the minimap, a downsampled view of the lines around
+// the view in braille characters, the lines in the view being highlighted
+func (w *BufWindow) displayMinimap() {
+	if w.minimapWidth == 0 {
+		return
+	}
+
+	minimapX := w.X + w.Width - w.scrollbarWidth - w.minimapWidth
+	n := w.Buf.LinesNum()
+	start := w.minimapStart()
+
+	// like for cursor-line, the foreground color of the group is the
+	// background of the lines in the view
+	_, viewBg, _ := config.DefStyle.Decompose()
+	for _, g := range []string{"minimap.view", "cursor-line"} {
+		if s, ok := config.Colorscheme[g]; ok {
+			viewBg, _, _ = s.Decompose()
+			break
+		}
+	}
+
+	dots := make([]rune, w.minimapWidth)
+	styles := make([]*tcell.Style, w.minimapWidth)
+	for row := 0; row < w.bufHeight; row++ {
+		for i := range dots {
+			dots[i], styles[i] = 0, nil
+		}
+
+		inView := false
+		for i := range brailleDots {
+			line := start + len(brailleDots)*row + i
+			if line >= n {
+				break
+			}
+			if line >= w.StartLine.Line && line < w.StartLine.Line+w.bufHeight {
+				inView = true
+			}
+			w.minimapLine(line, i, dots, styles)
+		}
+
+		for i := range dots {
+			r, style := ' ', config.DefStyle
+			if dots[i] != 0 {
+				r = 0x2800 + dots[i]
+			}
+			if styles[i] != nil {
+				style = *styles[i]
+			}
+			if inView {
+				style = style.Background(viewBg)
+			}
+			screen.SetContent(minimapX+i, w.Y+row, r, nil, style)
+		}
+	}
+}
+
+// ScrollBarLine returns the line shown at the given screen location and
+// true if it is on the scroll bar or on the minimap. On the scroll bar it
+// is the line of the mark of the row, if it has one, or the line at the
+// same proportion of the buffer as the row.
+func (w *BufWindow) ScrollBarLine(x, y int) (int, bool) {
+	row := y - w.Y
+	n := w.Buf.LinesNum()
+	if row < 0 || row >= w.bufHeight || n == 0 {
+		return 0, false
+	}
+
+	minimapX := w.X + w.Width - w.scrollbarWidth - w.minimapWidth
+	switch {
+	case w.scrollbarWidth > 0 && x == w.X+w.Width-1:
+		if row < len(w.overview) && w.overview[row].kind != markNone {
+			return w.overview[row].line, true
+		}
+		return util.Min(row*n/w.bufHeight, n-1), true
+	case w.minimapWidth > 0 && x >= minimapX && x < minimapX+w.minimapWidth:
+		return util.Min(w.minimapStart()+len(brailleDots)*row, n-1), true
+	}
+	return 0, false
+}
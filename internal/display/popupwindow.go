@@ -0,0 +1,111 @@
+package display
+
+import (
+	runewidth "github.com/mattn/go-runewidth"
+	"github.com/micro-editor/tcell/v2"
+	"github.com/zyedidia/micro/v2/internal/buffer"
+	"github.com/zyedidia/micro/v2/internal/config"
+	"github.com/zyedidia/micro/v2/internal/screen"
+	"github.com/zyedidia/micro/v2/internal/util"
+)
+
+// A PopupWindow is a BufWindow drawn over the other windows, optionally
+// surrounded by a border with a title
+type PopupWindow struct {
+	*BufWindow
+
+	Border bool
+	Title  string
+}
+
+// NewPopupWindow creates a popup window showing the given buffer
+func NewPopupWindow(buf *buffer.Buffer) *PopupWindow {
+	w := new(PopupWindow)
+	w.BufWindow = NewBufWindow(0, 0, 0, 0, buf)
+	w.Floating = true
+	w.Border = true
+	return w
+}
+
+// Outer returns the rectangle covered by the window, border included
+func (w *PopupWindow) Outer() View {
+	v := View{X: w.X, Y: w.Y, Width: w.Width, Height: w.Height}
+	if w.Border {
+		v.X, v.Y = v.X-1, v.Y-1
+		v.Width, v.Height = v.Width+2, v.Height+2
+	}
+	return v
+}
+
+// Place moves and resizes the window so that it covers the given
+// rectangle, border included
+func (w *PopupWindow) Place(x, y, width, height int) {
+	if w.Border {
+		x, y = x+1, y+1
+		width, height = width-2, height-2
+	}
+	w.X, w.Y = x, y
+	w.Resize(util.Max(width, 0), util.Max(height, 0))
+}
+
+// Contains returns whether the given screen location is covered by the
+// window, border included
+func (w *PopupWindow) Contains(x, y int) bool {
+	v := w.Outer()
+	return x >= v.X && x < v.X+v.Width && y >= v.Y && y < v.Y+v.Height
+}
+
+// Display clears the area of the window, draws its border and then the
+// buffer
+func (w *PopupWindow) Display() {
+	v := w.Outer()
+	for y := v.Y; y < v.Y+v.Height; y++ {
+		for x := v.X; x < v.X+v.Width; x++ {
+			screen.SetContent(x, y, ' ', nil, config.DefStyle)
+		}
+	}
+	if w.Border {
+		w.displayBorder(v)
+	}
+	w.BufWindow.Display()
+}
+
+func (w *PopupWindow) displayBorder(v View) {
+	style := config.DefStyle
+	if s, ok := config.Colorscheme["divider"]; ok {
+		style = s
+	}
+	if s, ok := config.Colorscheme["popup-border"]; ok {
+		style = s
+	}
+	if w.IsActive() {
+		style = style.Bold(true)
+	}
+
+	right, bottom := v.X+v.Width-1, v.Y+v.Height-1
+	for x := v.X + 1; x < right; x++ {
+		screen.SetContent(x, v.Y, tcell.RuneHLine, nil, style)
+		screen.SetContent(x, bottom, tcell.RuneHLine, nil, style)
+	}
+	for y := v.Y + 1; y < bottom; y++ {
+		screen.SetContent(v.X, y, tcell.RuneVLine, nil, style)
+		screen.SetContent(right, y, tcell.RuneVLine, nil, style)
+	}
+	screen.SetContent(v.X, v.Y, tcell.RuneULCorner, nil, style)
+	screen.SetContent(right, v.Y, tcell.RuneURCorner, nil, style)
+	screen.SetContent(v.X, bottom, tcell.RuneLLCorner, nil, style)
+	screen.SetContent(right, bottom, tcell.RuneLRCorner, nil, style)
+
+	if w.Title == "" || v.Width < 5 {
+		return
+	}
+	title := []rune(" " + w.Title + " ")
+	x := v.X + 2
+	for _, r := range title {
+		if x >= right-1 {
+			break
+		}
+		screen.SetContent(x, v.Y, r, nil, style)
+		x += util.Max(runewidth.RuneWidth(r), 1)
+	}
+}
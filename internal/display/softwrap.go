@@ -82,7 +82,9 @@ func (w *BufWindow) getVLocFromLoc(loc buffer.Loc) VLoc {
 	tabsize := util.IntOpt(w.Buf.Settings["tabsize"])
 
 	line := w.Buf.LineBytes(loc.Y)
+	inline := w.Buf.InlineWidths(loc.Y)
 	x := 0
+	i := 0
 	totalwidth := 0
 
 	wordwidth := 0
@@ -102,13 +104,18 @@ func (w *BufWindow) getVLocFromLoc(loc buffer.Loc) VLoc {
 			width = runewidth.RuneWidth(r)
 			totalwidth += width
 		}
+		// the inline virtual text is drawn before the character, and the
+		// cursor at the start of the virtual text
+		width += w.inlineWidth(inline, i, width)
+		i++
 
 		wordwidth += width
 
 		// Collect a complete word to know its width.
 		// If wordwrap is off, every single character is a complete "word".
+		// A word also ends before inline virtual text.
 		if wordwrap {
-			if !util.IsWhitespace(r) && len(line) > 0 && wordwidth < w.bufWidth {
+			if !util.IsWhitespace(r) && len(line) > 0 && wordwidth < w.bufWidth && inline[i] == 0 {
 				if x < loc.X {
 					wordoffset += width
 					x++
@@ -153,8 +160,10 @@ func (w *BufWindow) getLocFromVLoc(svloc VLoc) buffer.Loc {
 	tabsize := util.IntOpt(w.Buf.Settings["tabsize"])
 
 	line := w.Buf.LineBytes(svloc.Line)
+	inline := w.Buf.InlineWidths(svloc.Line)
 	vloc := VLoc{SLoc: SLoc{svloc.Line, 0}, VisualX: 0}
 
+	i := 0
 	totalwidth := 0
 
 	var widths []int
@@ -179,14 +188,17 @@ func (w *BufWindow) getLocFromVLoc(svloc VLoc) buffer.Loc {
 			width = runewidth.RuneWidth(r)
 			totalwidth += width
 		}
+		width += w.inlineWidth(inline, i, width)
+		i++
 
 		widths = append(widths, width)
 		wordwidth += width
 
 		// Collect a complete word to know its width.
 		// If wordwrap is off, every single character is a complete "word".
+		// A word also ends before inline virtual text.
 		if wordwrap {
-			if !util.IsWhitespace(r) && len(line) > 0 && wordwidth < w.bufWidth {
+			if !util.IsWhitespace(r) && len(line) > 0 && wordwidth < w.bufWidth && inline[i] == 0 {
 				continue
 			}
 		}
@@ -324,6 +336,7 @@ func (w *BufWindow) VLocFromLoc(loc buffer.Loc) VLoc {
 		tabsize := util.IntOpt(w.Buf.Settings["tabsize"])
 
 		visualx := util.StringWidth(w.Buf.LineBytes(loc.Y), loc.X, tabsize)
+		visualx += buffer.InlineWidthBefore(w.Buf.InlineWidths(loc.Y), loc.X)
 		return VLoc{SLoc{loc.Y, 0}, visualx}
 	}
 	return w.getVLocFromLoc(loc)
@@ -335,7 +348,7 @@ func (w *BufWindow) LocFromVLoc(vloc VLoc) buffer.Loc {
 	if !w.Buf.Settings["softwrap"].(bool) {
 		tabsize := util.IntOpt(w.Buf.Settings["tabsize"])
 
-		x := util.GetCharPosInLine(w.Buf.LineBytes(vloc.Line), vloc.VisualX, tabsize)
+		x := buffer.CharPosInLine(w.Buf.LineBytes(vloc.Line), vloc.VisualX, tabsize, w.Buf.InlineWidths(vloc.Line))
 		return buffer.Loc{x, vloc.Line}
 	}
 	return w.getLocFromVLoc(vloc)
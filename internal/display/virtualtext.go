@@ -0,0 +1,72 @@
+package display
+
+import (
+	runewidth "github.com/mattn/go-runewidth"
+	"github.com/micro-editor/tcell/v2"
+	"github.com/zyedidia/micro/v2/internal/buffer"
+	"github.com/zyedidia/micro/v2/internal/config"
+	"github.com/zyedidia/micro/v2/internal/util"
+)
+
+// inlineWidth returns the width of the inline virtual text drawn before the
+// x-th character of a line, whose own width is width. With softwrap, the
+// virtual text is cut so that it fits in a row along with the character.
+func (w *BufWindow) inlineWidth(inline map[int]int, x, width int) int {
+	vw := inline[x]
+	if vw > 0 && w.Buf.Settings["softwrap"].(bool) {
+		vw = util.Max(util.Min(vw, w.bufWidth-width), 0)
+	}
+	return vw
+}
+
+// virtualGlyph is a glyph of virtual text
+type virtualGlyph struct {
+	r     rune
+	width int
+	vt    *buffer.VirtualText
+}
+
+// virtualGlyphs returns the glyphs of the given virtual text, cut so that
+// their width is at most the given width, or padded with spaces so that it
+// is exactly the given width
+func virtualGlyphs(vts []*buffer.VirtualText, width int, pad bool) []virtualGlyph {
+	var glyphs []virtualGlyph
+	var last *buffer.VirtualText
+loop:
+	for _, vt := range vts {
+		last = vt
+		for _, r := range vt.Text {
+			rw := runewidth.RuneWidth(r)
+			if rw == 0 {
+				// combining characters are not drawn
+				continue
+			}
+			if rw > width {
+				break loop
+			}
+			glyphs = append(glyphs, virtualGlyph{r, rw, vt})
+			width -= rw
+		}
+	}
+	for ; pad && width > 0; width-- {
+		glyphs = append(glyphs, virtualGlyph{' ', 1, last})
+	}
+	return glyphs
+}
+
+// virtualTextAt returns the virtual text of the given list anchored at the
+// given character of its line
+func virtualTextAt(vts []*buffer.VirtualText, x int) []*buffer.VirtualText {
+	var at []*buffer.VirtualText
+	for _, vt := range vts {
+		if vt.Loc.X == x {
+			at = append(at, vt)
+		}
+	}
+	return at
+}
+
+// virtualTextStyle returns the style of virtual text
+func virtualTextStyle(vt *buffer.VirtualText) tcell.Style {
+	return config.GetColor(vt.Group.String())
+}
@@ -34,4 +34,5 @@ type BWindow interface {
 	SoftWrap
 	SetBuffer(b *buffer.Buffer)
 	BufView() View
+	ScrollBarLine(x, y int) (int, bool)
 }
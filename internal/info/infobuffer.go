@@ -30,14 +30,28 @@ type InfoBuf struct {
 	HistorySearch       bool
 	HistorySearchPrefix string
 
+	// Recent holds the absolute paths of the recently opened files, the
+	// most recent first
+	Recent []string
+
 	// Is the current message a message from the gutter
 	HasGutter bool
 
+	// Link is the location in a file which the current error refers to,
+	// opened when the user clicks the message
+	Link *MessageLink
+
 	PromptCallback func(resp string, canceled bool)
 	EventCallback  func(resp string)
 	YNCallback     func(yes bool, canceled bool)
 }
 
+// A MessageLink is a location in a file
+type MessageLink struct {
+	Path string
+	Loc  buffer.Loc
+}
+
 // NewBuffer returns a new infobuffer
 func NewBuffer() *InfoBuf {
 	ib := new(InfoBuf)
@@ -45,6 +59,7 @@ func NewBuffer() *InfoBuf {
 
 	ib.Buffer = buffer.NewBufferFromString("", "", buffer.BTInfo)
 	ib.LoadHistory()
+	ib.LoadRecent()
 
 	return ib
 }
@@ -52,6 +67,7 @@ func NewBuffer() *InfoBuf {
 // Close performs any cleanup necessary when shutting down the infobuffer
 func (i *InfoBuf) Close() {
 	i.SaveHistory()
+	i.SaveRecent()
 }
 
 // Message sends a message to the user
@@ -63,6 +79,7 @@ func (i *InfoBuf) Message(msg ...interface{}) {
 		// if there is no active prompt then style and display the message as normal
 		i.Msg = displayMessage
 		i.HasMessage, i.HasError = true, false
+		i.Link = nil
 	}
 }
 
@@ -86,10 +103,20 @@ func (i *InfoBuf) Error(msg ...interface{}) {
 		// if there is no active prompt then style and display the message as normal
 		i.Msg = fmt.Sprint(msg...)
 		i.HasMessage, i.HasError = false, true
+		i.Link = nil
 	}
 	// TODO: add to log?
 }
 
+// ErrorAt sends an error message about a location in a file to the user.
+// Clicking the message opens the file at that location.
+func (i *InfoBuf) ErrorAt(path string, loc buffer.Loc, msg ...interface{}) {
+	i.Error(msg...)
+	if !i.HasPrompt {
+		i.Link = &MessageLink{path, loc}
+	}
+}
+
 // Prompt starts a prompt for the user, it takes a prompt, a possibly partially filled in msg
 // and callbacks executed when the user executes an event and when the user finishes the prompt
 // The eventcb passes the current user response as the argument and donecb passes the user's message
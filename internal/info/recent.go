@@ -0,0 +1,73 @@
+package info
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/zyedidia/micro/v2/internal/config"
+	"github.com/zyedidia/micro/v2/internal/screen"
+	"github.com/zyedidia/micro/v2/internal/util"
+)
+
+// maxRecent is the number of recent files which are remembered
+const maxRecent = 100
+
+// LoadRecent loads the list of recently opened files from
+// configDir/buffers/recent
+// The savehistory option must be on
+func (i *InfoBuf) LoadRecent() {
+	if config.GetGlobalOption("savehistory").(bool) {
+		file, err := os.Open(filepath.Join(config.ConfigDir, "buffers", "recent"))
+		if err != nil {
+			if !errors.Is(err, fs.ErrNotExist) {
+				i.Error("Error loading recent files: ", err)
+			}
+			return
+		}
+
+		defer file.Close()
+		var recent []string
+		err = gob.NewDecoder(file).Decode(&recent)
+		if err != nil {
+			i.Error("Error decoding recent files: ", err)
+			return
+		}
+		i.Recent = recent
+	}
+}
+
+// SaveRecent saves the list of recently opened files to
+// configDir/buffers/recent only if the savehistory option is on
+func (i *InfoBuf) SaveRecent() {
+	if config.GetGlobalOption("savehistory").(bool) {
+		var buf bytes.Buffer
+		err := gob.NewEncoder(&buf).Encode(i.Recent)
+		if err != nil {
+			screen.TermMessage("Error encoding recent files: ", err)
+			return
+		}
+
+		filename := filepath.Join(config.ConfigDir, "buffers", "recent")
+		err = util.SafeWrite(filename, buf.Bytes(), true)
+		if err != nil {
+			screen.TermMessage("Error saving recent files: ", err)
+			return
+		}
+	}
+}
+
+// AddRecent moves the file with the given absolute path to the front of
+// the recent files
+func (i *InfoBuf) AddRecent(path string) {
+	recent := []string{path}
+	for _, p := range i.Recent {
+		if p != path && len(recent) < maxRecent {
+			recent = append(recent, p)
+		}
+	}
+	i.Recent = recent
+}
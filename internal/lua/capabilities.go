@@ -0,0 +1,373 @@
+package lua
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// The capabilities which can be granted to a plugin
+const (
+	CapFilesystemRead  = "filesystem-read"
+	CapFilesystemWrite = "filesystem-write"
+	CapNetwork         = "network"
+	CapExec            = "exec"
+)
+
+// Capabilities is the list of all the capabilities
+var Capabilities = []string{CapFilesystemRead, CapFilesystemWrite, CapNetwork, CapExec}
+
+// aliases maps the short names of the importable packages to their full name
+var aliases = map[string]string{
+	"ioutil":   "io/ioutil",
+	"filepath": "path/filepath",
+	"http":     "net/http",
+	"utf8":     "unicode/utf8",
+}
+
+// restricted maps the functions of the importable packages to the
+// capability they need; "*" stands for every member of the package
+var restricted = map[string]map[string]string{
+	"os": {
+		"Lstat":        CapFilesystemRead,
+		"Open":         CapFilesystemRead,
+		"ReadDir":      CapFilesystemRead,
+		"ReadFile":     CapFilesystemRead,
+		"Readlink":     CapFilesystemRead,
+		"Stat":         CapFilesystemRead,
+		"Chdir":        CapFilesystemWrite,
+		"Chmod":        CapFilesystemWrite,
+		"Chown":        CapFilesystemWrite,
+		"Chtimes":      CapFilesystemWrite,
+		"Create":       CapFilesystemWrite,
+		"Lchown":       CapFilesystemWrite,
+		"Link":         CapFilesystemWrite,
+		"Mkdir":        CapFilesystemWrite,
+		"MkdirAll":     CapFilesystemWrite,
+		"NewFile":      CapFilesystemWrite,
+		"OpenFile":     CapFilesystemWrite,
+		"Remove":       CapFilesystemWrite,
+		"RemoveAll":    CapFilesystemWrite,
+		"Rename":       CapFilesystemWrite,
+		"Symlink":      CapFilesystemWrite,
+		"Truncate":     CapFilesystemWrite,
+		"WriteFile":    CapFilesystemWrite,
+		"Clearenv":     CapExec,
+		"FindProcess":  CapExec,
+		"Setenv":       CapExec,
+		"StartProcess": CapExec,
+		"Unsetenv":     CapExec,
+	},
+	"io/ioutil": {
+		"ReadDir":   CapFilesystemRead,
+		"ReadFile":  CapFilesystemRead,
+		"WriteFile": CapFilesystemWrite,
+	},
+	"path/filepath": {
+		"EvalSymlinks": CapFilesystemRead,
+		"Glob":         CapFilesystemRead,
+	},
+	"archive/zip": {
+		"OpenReader": CapFilesystemRead,
+	},
+	"net":      {"*": CapNetwork},
+	"net/http": {"*": CapNetwork},
+	"micro/buffer": {
+		"NewBufferFromFile": CapFilesystemRead,
+	},
+	"micro/shell": {
+		"ExecCommand":         CapExec,
+		"RunCommand":          CapExec,
+		"RunBackgroundShell":  CapExec,
+		"RunInteractiveShell": CapExec,
+		"JobStart":            CapExec,
+		"JobSpawn":            CapExec,
+		"Spawn":               CapExec,
+		"SpawnWithStdin":      CapExec,
+		"RunTermEmulator":     CapExec,
+	},
+	"micro/util": {
+		"Unzip":       CapFilesystemWrite,
+		"HttpRequest": CapNetwork,
+	},
+}
+
+// IsCapability returns whether the given name is a valid capability
+func IsCapability(name string) bool {
+	for _, c := range Capabilities {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Restrict replaces the members of the imported package pkg which need a
+// capability that the given plugin was not granted with functions which
+// report the denied call with the denied function and raise an error
+func Restrict(pkg string, tbl *lua.LTable, plugin string, granted func(capability string) bool, denied func(msg string)) {
+	if alias, ok := aliases[pkg]; ok {
+		pkg = alias
+	}
+	caps, ok := restricted[pkg]
+	if !ok {
+		return
+	}
+
+	var names []string
+	tbl.ForEach(func(k, v lua.LValue) {
+		names = append(names, k.String())
+	})
+	for _, name := range names {
+		c, ok := caps[name]
+		if !ok {
+			c, ok = caps["*"]
+		}
+		if !ok || granted(c) {
+			continue
+		}
+		msg := fmt.Sprintf("Plugin %s: %s.%s needs the %s capability, which the plugin was not granted", plugin, pkg, name, c)
+		L.SetField(tbl, name, L.NewFunction(func(l *lua.LState) int {
+			denied(msg)
+			l.RaiseError("%s", msg)
+			return 0
+		}))
+	}
+}
+
+// A capCheck returns the capability needed by a call of a function of the
+// Lua standard library with the arguments on the stack, "" if none
+type capCheck func(l *lua.LState) string
+
+// always returns a capCheck needing the given capability for every call
+func always(capability string) capCheck {
+	return func(*lua.LState) string { return capability }
+}
+
+// withName returns a capCheck needing the given capability when the first
+// argument is a file name
+func withName(capability string) capCheck {
+	return func(l *lua.LState) string {
+		if l.Get(1).Type() == lua.LTString {
+			return capability
+		}
+		return ""
+	}
+}
+
+// stdlibRestricted maps the functions of the modules of the Lua standard
+// library to the capability they need
+var stdlibRestricted = map[string]map[string]capCheck{
+	"os": {
+		"execute": always(CapExec),
+		"setenv":  always(CapExec),
+		"remove":  always(CapFilesystemWrite),
+		"rename":  always(CapFilesystemWrite),
+		"tmpname": always(CapFilesystemWrite),
+	},
+	"io": {
+		"open": func(l *lua.LState) string {
+			if strings.ContainsAny(l.OptString(2, "r"), "wa+") {
+				return CapFilesystemWrite
+			}
+			return CapFilesystemRead
+		},
+		"lines":   withName(CapFilesystemRead),
+		"input":   withName(CapFilesystemRead),
+		"output":  withName(CapFilesystemWrite),
+		"popen":   always(CapExec),
+		"tmpfile": always(CapFilesystemWrite),
+	},
+	// the debug functions which reach the values of other environments,
+	// such as the unrestricted modules, need all the capabilities
+	"debug": {
+		"getfenv":      always(CapExec),
+		"getlocal":     always(CapExec),
+		"getmetatable": always(CapExec),
+		"getregistry":  always(CapExec),
+		"getupvalue":   always(CapExec),
+		"setfenv":      always(CapExec),
+		"setlocal":     always(CapExec),
+		"setupvalue":   always(CapExec),
+	},
+}
+
+// safeModules are the modules of the Lua standard library which need no
+// capability
+var safeModules = []string{"string", "table", "math", "coroutine", "channel"}
+
+// RestrictEnvironment shadows the modules and functions of the Lua standard
+// library which need a capability in the environment of a plugin which was
+// not granted all of them, so that their calls needing a missing capability
+// are reported like in Restrict. The Lua code that the plugin loads (with
+// require, loadfile, load...) runs in its environment, and the functions
+// which would give it the shared globals return the environment instead.
+func RestrictEnvironment(env *lua.LTable, plugin string, granted func(capability string) bool, denied func(msg string)) {
+	all := true
+	for _, c := range Capabilities {
+		all = all && granted(c)
+	}
+	if all {
+		return
+	}
+
+	check := func(l *lua.LState, name, c string) {
+		if c == "" || granted(c) {
+			return
+		}
+		msg := fmt.Sprintf("Plugin %s: %s needs the %s capability, which the plugin was not granted", plugin, name, c)
+		denied(msg)
+		l.RaiseError("%s", msg)
+	}
+	// setEnv runs the functions returned by a loading function in env
+	setEnv := func(l *lua.LState, n int) int {
+		for i := l.GetTop() - n + 1; i <= l.GetTop(); i++ {
+			if fn, ok := l.Get(i).(*lua.LFunction); ok && !fn.IsG {
+				fn.Env = env
+			}
+		}
+		return n
+	}
+	global := func(name string) *lua.LFunction {
+		fn, _ := L.GetGlobal(name).(*lua.LFunction)
+		return fn
+	}
+
+	// the modules loaded by the plugin are kept in its own package.loaded
+	loaded := L.NewTable()
+	for _, m := range safeModules {
+		loaded.RawSetString(m, L.GetGlobal(m))
+	}
+	loaded.RawSetString("_G", env)
+	for m, funcs := range stdlibRestricted {
+		orig, ok := L.GetGlobal(m).(*lua.LTable)
+		if !ok {
+			continue
+		}
+		mod := L.NewTable()
+		orig.ForEach(func(k, v lua.LValue) {
+			name := m + "." + k.String()
+			fn, ok := v.(*lua.LFunction)
+			c, restricted := funcs[k.String()]
+			if !ok || !fn.IsG || !restricted {
+				mod.RawSet(k, v)
+				return
+			}
+			mod.RawSet(k, L.NewFunction(func(l *lua.LState) int {
+				check(l, name, c(l))
+				return fn.GFunction(l)
+			}))
+		})
+		env.RawSetString(m, mod)
+		loaded.RawSetString(m, mod)
+	}
+
+	pkg := L.NewTable()
+	pkg.RawSetString("loaded", loaded)
+	pkg.RawSetString("preload", L.NewTable())
+	if orig, ok := L.GetGlobal("package").(*lua.LTable); ok {
+		pkg.RawSetString("path", orig.RawGetString("path"))
+	}
+	env.RawSetString("package", pkg)
+
+	env.RawSetString("require", L.NewFunction(func(l *lua.LState) int {
+		name := l.CheckString(1)
+		if v := loaded.RawGetString(name); v != lua.LNil {
+			l.Push(v)
+			return 1
+		}
+		if pre, ok := pkg.RawGetString("preload").(*lua.LTable); ok {
+			if fn, ok := pre.RawGetString(name).(*lua.LFunction); ok {
+				fn.Env = env
+				l.Push(fn)
+				l.Push(lua.LString(name))
+				l.Call(1, 1)
+				loaded.RawSetString(name, l.Get(-1))
+				return 1
+			}
+		}
+		check(l, "require", CapFilesystemRead)
+		file := findModule(name, lua.LVAsString(pkg.RawGetString("path")))
+		if file == "" {
+			l.RaiseError("module %s not found", name)
+		}
+		fn, err := l.LoadFile(file)
+		if err != nil {
+			l.RaiseError("%s", err.Error())
+		}
+		fn.Env = env
+		l.Push(fn)
+		l.Push(lua.LString(name))
+		l.Call(1, 1)
+		ret := l.Get(-1)
+		if ret == lua.LNil {
+			ret = lua.LTrue
+		}
+		loaded.RawSetString(name, ret)
+		l.Push(ret)
+		return 1
+	}))
+	env.RawSetString("dofile", L.NewFunction(func(l *lua.LState) int {
+		check(l, "dofile", CapFilesystemRead)
+		fn, err := l.LoadFile(l.CheckString(1))
+		if err != nil {
+			l.RaiseError("%s", err.Error())
+		}
+		fn.Env = env
+		top := l.GetTop()
+		l.Push(fn)
+		l.Call(0, lua.MultRet)
+		return l.GetTop() - top
+	}))
+	if loadfile := global("loadfile"); loadfile != nil {
+		env.RawSetString("loadfile", L.NewFunction(func(l *lua.LState) int {
+			check(l, "loadfile", CapFilesystemRead)
+			return setEnv(l, loadfile.GFunction(l))
+		}))
+	}
+	for _, name := range []string{"load", "loadstring"} {
+		if fn := global(name); fn != nil {
+			env.RawSetString(name, L.NewFunction(func(l *lua.LState) int {
+				return setEnv(l, fn.GFunction(l))
+			}))
+		}
+	}
+	if getfenv := global("getfenv"); getfenv != nil {
+		env.RawSetString("getfenv", L.NewFunction(func(l *lua.LState) int {
+			n := getfenv.GFunction(l)
+			if l.Get(-1) == L.G.Global {
+				l.Pop(1)
+				l.Push(env)
+			}
+			return n
+		}))
+	}
+	// module with package.seeall would give the shared globals
+	if module := global("module"); module != nil {
+		env.RawSetString("module", L.NewFunction(func(l *lua.LState) int {
+			check(l, "module", CapExec)
+			return module.GFunction(l)
+		}))
+	}
+	// the metatable of the environment, whose __index is the shared
+	// globals, is hidden from getmetatable
+	if mt, ok := L.GetMetatable(env).(*lua.LTable); ok {
+		mt.RawSetString("__metatable", lua.LFalse)
+	}
+}
+
+// findModule returns the file of a Lua module found with the given
+// package.path, "" if there is none
+func findModule(name, path string) string {
+	name = strings.ReplaceAll(name, ".", string(os.PathSeparator))
+	for _, pattern := range strings.Split(path, ";") {
+		file := filepath.FromSlash(strings.ReplaceAll(pattern, "?", name))
+		if _, err := os.Stat(file); err == nil {
+			return file
+		}
+	}
+	return ""
+}
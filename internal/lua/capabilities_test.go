@@ -0,0 +1,60 @@
+package lua
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	lua "github.com/yuin/gopher-lua"
+)
+
+func TestRestrictEnvironment(t *testing.T) {
+	L = lua.NewState()
+	defer L.Close()
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "mod.lua")
+	assert.NoError(t, os.WriteFile(file, []byte("return {os = os}"), 0644))
+
+	var denied []string
+	env := NewEnvironment()
+	granted := func(c string) bool { return c == CapFilesystemRead }
+	RestrictEnvironment(env, "foo", granted, func(msg string) {
+		denied = append(denied, msg)
+	})
+
+	run := func(code string) error {
+		fn, err := L.LoadString(code)
+		assert.NoError(t, err)
+		L.SetFEnv(fn, env)
+		L.Push(fn)
+		return L.PCall(0, 0, nil)
+	}
+
+	assert.Error(t, run(`os.execute("true")`))
+	assert.Equal(t, []string{"Plugin foo: os.execute needs the exec capability, which the plugin was not granted"}, denied)
+	assert.Error(t, run(`io.popen("true")`))
+	assert.Error(t, run(`io.open("`+file+`", "w")`))
+	assert.Error(t, run(`os.remove("`+file+`")`))
+	assert.FileExists(t, file)
+	assert.NoError(t, run(`assert(io.open("`+file+`"):read("*a") == "return {os = os}")`))
+
+	// the code loaded by the plugin and the functions giving the globals
+	// only see the restricted modules
+	L.SetGlobal("realos", L.GetGlobal("os"))
+	assert.NoError(t, run(`
+		package.path = "`+dir+`/?.lua"
+		assert(require("mod").os == os)
+		assert(dofile("`+file+`").os == os)
+		assert(loadstring("return os")() == os)
+		assert(getfenv(0) == _G)
+		assert(getmetatable(_G) == false)
+		assert(package.loaded.os == os and os ~= realos)`))
+	assert.Error(t, run(`debug.getregistry()`))
+
+	// a plugin granted all the capabilities uses the standard library
+	env = NewEnvironment()
+	RestrictEnvironment(env, "bar", func(string) bool { return true }, nil)
+	assert.Equal(t, lua.LNil, env.RawGetString("os"))
+}
@@ -0,0 +1,84 @@
+package lua
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// ErrTimeLimit is returned when Lua code runs for longer than its time limit
+var ErrTimeLimit = errors.New("time limit exceeded")
+
+// cancelLimit cancels the context of the running call
+var cancelLimit context.CancelFunc
+
+// CallLimited runs f, which calls Lua code, and stops the Lua code with
+// ErrTimeLimit if it runs for longer than the given time limit. Lua code
+// called while f runs shares its time limit.
+func CallLimited(d time.Duration, f func() error) error {
+	if L.Context() != nil || d <= 0 {
+		return f()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	cancelLimit = cancel
+	L.SetContext(ctx)
+
+	err := f()
+
+	timedOut := errors.Is(L.Context().Err(), context.DeadlineExceeded)
+	L.RemoveContext()
+	cancelLimit()
+	if err != nil && timedOut {
+		return ErrTimeLimit
+	}
+	return err
+}
+
+// Unlimited runs f, a Go function called by Lua code which may block for a
+// long time (such as an interactive shell), without counting the time it
+// takes in the time limit of the Lua code. The Lua code gets the time it
+// had left when f returns.
+func Unlimited(f func()) {
+	if L.Context() == nil {
+		f()
+		return
+	}
+	deadline, ok := L.Context().Deadline()
+	if !ok {
+		f()
+		return
+	}
+
+	// the Lua VM is running, so its context must not be removed
+	remaining := time.Until(deadline)
+	cancelLimit()
+	L.SetContext(context.Background())
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), remaining)
+		cancelLimit = cancel
+		L.SetContext(ctx)
+	}()
+	f()
+}
+
+// ResumeLimited resumes the coroutine co running fn with the given
+// arguments, and stops it with ErrTimeLimit if it runs for longer than the
+// given time limit before yielding. It must not be called while Lua code
+// is running.
+func ResumeLimited(d time.Duration, co *lua.LState, fn *lua.LFunction, args ...lua.LValue) (lua.ResumeState, error) {
+	if d > 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), d)
+		defer cancel()
+		co.SetContext(ctx)
+		defer co.RemoveContext()
+	}
+
+	state, err, _ := L.Resume(co, fn, args...)
+	if err != nil && co.Context() != nil && errors.Is(co.Context().Err(), context.DeadlineExceeded) {
+		return state, ErrTimeLimit
+	}
+	return state, err
+}
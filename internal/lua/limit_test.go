@@ -0,0 +1,35 @@
+package lua
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	lua "github.com/yuin/gopher-lua"
+)
+
+func TestUnlimitedKeepsDeadline(t *testing.T) {
+	L = lua.NewState()
+	defer L.Close()
+
+	L.SetGlobal("block", L.NewFunction(func(l *lua.LState) int {
+		Unlimited(func() { time.Sleep(20 * time.Millisecond) })
+		return 0
+	}))
+	// each iteration runs for less than the time limit, which the
+	// iterations share
+	fn, err := L.LoadString(`
+		while true do
+			local x = 0
+			for i = 1, 100000 do x = x + i end
+			block()
+		end`)
+	assert.Nil(t, err)
+
+	start := time.Now()
+	err = CallLimited(100*time.Millisecond, func() error {
+		return L.CallByParam(lua.P{Fn: fn, NRet: 0, Protect: true})
+	})
+	assert.Equal(t, ErrTimeLimit, err)
+	assert.True(t, time.Since(start) < 2*time.Second)
+}
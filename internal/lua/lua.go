@@ -27,18 +27,29 @@ import (
 
 var L *lua.LState
 
-// LoadFile loads a lua file
-func LoadFile(module string, file string, data []byte) error {
-	pluginDef := []byte("module(\"" + module + "\", package.seeall)")
-
-	if fn, err := L.Load(bytes.NewReader(append(pluginDef, data...)), file); err != nil {
+// LoadFile runs a lua file in the given environment
+func LoadFile(env *lua.LTable, file string, data []byte) error {
+	if fn, err := L.Load(bytes.NewReader(data), file); err != nil {
 		return err
 	} else {
+		L.SetFEnv(fn, env)
 		L.Push(fn)
 		return L.PCall(0, lua.MultRet, nil)
 	}
 }
 
+// NewEnvironment creates an environment for a plugin: the global variables
+// that the plugin sets are stored in it, and the ones that it reads fall
+// back to the shared globals (the Lua standard library and import)
+func NewEnvironment() *lua.LTable {
+	env := L.NewTable()
+	mt := L.NewTable()
+	L.SetField(mt, "__index", L.G.Global)
+	L.SetMetatable(env, mt)
+	L.SetField(env, "_G", env)
+	return env
+}
+
 // Import allows a lua plugin to import a package
 func Import(pkg string) *lua.LTable {
 	switch pkg {
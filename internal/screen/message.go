@@ -15,6 +15,10 @@ import (
 // This will write the message, and wait for the user
 // to press and key to continue
 func TermMessage(msg ...interface{}) {
+	if simulated {
+		TermMessages = append(TermMessages, strings.TrimSuffix(fmt.Sprintln(msg...), "\n"))
+		return
+	}
 	screenb := TempFini()
 
 	fmt.Println(msg...)
@@ -32,6 +36,9 @@ func TermMessage(msg ...interface{}) {
 // If wait is true, the prompt re-prompts until a valid option is
 // chosen, otherwise if wait is false, -1 is returned for no match
 func TermPrompt(prompt string, options []string, wait bool) int {
+	if simulated {
+		return -1
+	}
 	screenb := TempFini()
 
 	idx := -1
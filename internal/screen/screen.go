@@ -6,6 +6,7 @@ import (
 	"os"
 	"sync"
 
+	isatty "github.com/mattn/go-isatty"
 	"github.com/zyedidia/micro/v2/internal/config"
 	"github.com/zyedidia/micro/v2/internal/util"
 	"github.com/micro-editor/tcell/v2"
@@ -22,6 +23,14 @@ var Screen tcell.Screen
 // Events is the channel of tcell events
 var Events chan (tcell.Event)
 
+// TermMessages collects the messages of TermMessage while the screen is a
+// simulation screen, since there is no terminal to print them in
+var TermMessages []string
+
+// simulated is true if Screen is a simulation screen, which cannot be
+// temporarily shut down
+var simulated bool
+
 // RestartCallback is called when the screen is restarted after it was
 // temporarily shut down
 var RestartCallback func()
@@ -155,12 +164,42 @@ func UnregisterRawSeq(r string) {
 	}
 }
 
+// The escape sequences sent by the terminal when it gains or loses the focus
+const (
+	FocusInSeq  = "\x1b[I"
+	FocusOutSeq = "\x1b[O"
+)
+
+// setFocusReporting asks the terminal to send (or to stop sending) the
+// FocusInSeq and FocusOutSeq escape sequences
+func setFocusReporting(enable bool) {
+	if !isatty.IsTerminal(os.Stdout.Fd()) {
+		return
+	}
+	if enable {
+		os.Stdout.WriteString("\x1b[?1004h")
+	} else {
+		os.Stdout.WriteString("\x1b[?1004l")
+	}
+}
+
+// Fini shuts the screen down
+func Fini() {
+	if !simulated {
+		setFocusReporting(false)
+	}
+	Screen.Fini()
+}
+
 // TempFini shuts the screen down temporarily
 func TempFini() bool {
+	if simulated {
+		return true
+	}
 	screenWasNil := Screen == nil
 
 	if !screenWasNil {
-		Screen.Fini()
+		Fini()
 		Lock()
 		Screen = nil
 	}
@@ -218,6 +257,10 @@ func Init() error {
 		return err
 	}
 
+	if !truecolor {
+		config.ColorDepth = Screen.Colors()
+	}
+
 	Screen.SetPaste(config.GetGlobalOption("paste").(bool))
 
 	// restore TERM
@@ -232,6 +275,9 @@ func Init() error {
 	for _, r := range rawSeq {
 		Screen.RegisterRawSeq(r)
 	}
+	Screen.RegisterRawSeq(FocusInSeq)
+	Screen.RegisterRawSeq(FocusOutSeq)
+	setFocusReporting(true)
 
 	return nil
 }
@@ -252,6 +298,7 @@ func InitSimScreen() (tcell.SimulationScreen, error) {
 
 	s.SetSize(80, 24)
 	Screen = s
+	simulated = true
 
 	if config.GetGlobalOption("mouse").(bool) {
 		Screen.EnableMouse()
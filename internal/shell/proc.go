@@ -0,0 +1,104 @@
+package shell
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os/exec"
+)
+
+// A Proc is a process started in the background by Spawn, whose output and
+// exit code can be awaited by a plugin's task
+type Proc struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout bytes.Buffer
+	stderr bytes.Buffer
+	code   int
+	done   chan struct{}
+}
+
+// ErrNoStdin is returned when writing to the standard input of a process
+// which was not started with SpawnWithStdin
+var ErrNoStdin = errors.New("the process was started without a standard input")
+
+// Spawn starts a process with args in the background, with an empty
+// standard input
+func Spawn(name string, args ...string) (*Proc, error) {
+	return spawn(false, name, args...)
+}
+
+// SpawnWithStdin starts a process with args in the background, whose
+// standard input is written with Send and must be closed with CloseStdin
+// for the programs which read it until its end
+func SpawnWithStdin(name string, args ...string) (*Proc, error) {
+	return spawn(true, name, args...)
+}
+
+func spawn(withStdin bool, name string, args ...string) (*Proc, error) {
+	p := &Proc{
+		cmd:  exec.Command(name, args...),
+		done: make(chan struct{}),
+	}
+	p.cmd.Stdout = &p.stdout
+	p.cmd.Stderr = &p.stderr
+	if withStdin {
+		stdin, err := p.cmd.StdinPipe()
+		if err != nil {
+			return nil, err
+		}
+		p.stdin = stdin
+	}
+	if err := p.cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	go func() {
+		err := p.cmd.Wait()
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			p.code = exitErr.ExitCode()
+		} else if err != nil {
+			p.code = -1
+		}
+		close(p.done)
+	}()
+	return p, nil
+}
+
+// Done returns a channel which is closed when the process has exited
+func (p *Proc) Done() <-chan struct{} {
+	return p.done
+}
+
+// Result returns the standard output, exit code and standard error of the
+// process, which must have exited
+func (p *Proc) Result() (string, int, string) {
+	return p.stdout.String(), p.code, p.stderr.String()
+}
+
+// Send writes the given data to the standard input of the process
+func (p *Proc) Send(data string) error {
+	if p.stdin == nil {
+		return ErrNoStdin
+	}
+	_, err := p.stdin.Write([]byte(data))
+	return err
+}
+
+// CloseStdin closes the standard input of the process
+func (p *Proc) CloseStdin() error {
+	if p.stdin == nil {
+		return ErrNoStdin
+	}
+	return p.stdin.Close()
+}
+
+// Kill kills the process if it is still running
+func (p *Proc) Kill() {
+	select {
+	case <-p.done:
+	default:
+		p.cmd.Process.Kill()
+	}
+}
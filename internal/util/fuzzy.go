@@ -0,0 +1,97 @@
+package util
+
+import (
+	"unicode"
+)
+
+// The scores of the characters of a fuzzy match
+const (
+	fuzzyMatch       = 16
+	fuzzyConsecutive = 8
+	fuzzyBoundary    = 8
+	fuzzyFirst       = 4
+	fuzzyGap         = 1
+	fuzzyMaxGap      = 8
+)
+
+// FuzzyMatch returns whether all the characters of pattern appear in str in
+// the same order, with a score which is higher when they are consecutive,
+// at the start of words or at the start of str, and the positions of the
+// matched characters in str (in runes). The match ignores case unless the
+// pattern contains an uppercase letter. An empty pattern matches with a
+// score of 0.
+func FuzzyMatch(pattern, str string) (int, []int, bool) {
+	pat := []rune(pattern)
+	if len(pat) == 0 {
+		return 0, []int{}, true
+	}
+	s := []rune(str)
+
+	ignoreCase := true
+	for _, r := range pat {
+		if unicode.IsUpper(r) {
+			ignoreCase = false
+			break
+		}
+	}
+	eq := func(a, b rune) bool {
+		if ignoreCase {
+			return unicode.ToLower(a) == unicode.ToLower(b)
+		}
+		return a == b
+	}
+
+	// find the end of the first match, then the shortest match ending there
+	i := 0
+	end := -1
+	for j, r := range s {
+		if eq(r, pat[i]) {
+			i++
+			if i == len(pat) {
+				end = j
+				break
+			}
+		}
+	}
+	if end < 0 {
+		return 0, nil, false
+	}
+	positions := make([]int, len(pat))
+	i = len(pat) - 1
+	for j := end; j >= 0 && i >= 0; j-- {
+		if eq(s[j], pat[i]) {
+			positions[i] = j
+			i--
+		}
+	}
+
+	score := 0
+	for k, p := range positions {
+		score += fuzzyMatch
+		if p == 0 {
+			score += fuzzyFirst + fuzzyBoundary
+		} else if isFuzzyBoundary(s[p-1], s[p]) {
+			score += fuzzyBoundary
+		}
+		if k > 0 {
+			if gap := p - positions[k-1] - 1; gap == 0 {
+				score += fuzzyConsecutive
+			} else {
+				score -= Min(gap, fuzzyMaxGap) * fuzzyGap
+			}
+		}
+		if s[p] == pat[k] {
+			// the characters with the same case are slightly better
+			score++
+		}
+	}
+	// the shorter strings are better
+	score -= Min(len(s)-len(pat), fuzzyMaxGap)
+	return score, positions, true
+}
+
+// isFuzzyBoundary returns whether the character r, following prev, starts a
+// word (after a non-word character, or a lowercase to uppercase change)
+func isFuzzyBoundary(prev, r rune) bool {
+	return !IsWordChar(prev) && IsWordChar(r) || unicode.IsLower(prev) && unicode.IsUpper(r)
+}
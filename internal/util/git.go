@@ -0,0 +1,42 @@
+package util
+
+import (
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// GitStatus returns the status of the files of the git repository which
+// contains dir, as the two letter codes of `git status --porcelain` keyed
+// by absolute path. The ignored files and directories have the code "!!".
+func GitStatus(dir string) (map[string]string, error) {
+	top, err := exec.Command("git", "-C", dir, "rev-parse", "--show-toplevel").Output()
+	if err != nil {
+		return nil, err
+	}
+	out, err := exec.Command("git", "-C", dir, "status", "--porcelain", "-z", "--ignored=matching").Output()
+	if err != nil {
+		return nil, err
+	}
+	return parseGitStatus(strings.TrimSpace(string(top)), string(out)), nil
+}
+
+// parseGitStatus parses the output of `git status --porcelain -z` run in
+// the repository whose top level directory is top
+func parseGitStatus(top, out string) map[string]string {
+	status := make(map[string]string)
+	fields := strings.Split(out, "\x00")
+	for i := 0; i < len(fields); i++ {
+		f := fields[i]
+		if len(f) < 4 {
+			continue
+		}
+		xy, p := f[:2], strings.TrimSuffix(f[3:], "/")
+		if xy[0] == 'R' || xy[0] == 'C' {
+			// the original path of a renamed or copied file follows
+			i++
+		}
+		status[filepath.Join(top, filepath.FromSlash(p))] = xy
+	}
+	return status
+}
@@ -0,0 +1,195 @@
+package util
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// A gitignoreRule is a pattern of a .gitignore file
+type gitignoreRule struct {
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// A Gitignore holds the patterns of a .gitignore file, which apply to the
+// paths below its directory
+type Gitignore struct {
+	rules []gitignoreRule
+}
+
+// ParseGitignore parses the content of a .gitignore file
+func ParseGitignore(data string) *Gitignore {
+	g := new(Gitignore)
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if !strings.HasSuffix(line, "\\ ") {
+			line = strings.TrimRight(line, " ")
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		var r gitignoreRule
+		if strings.HasPrefix(line, "!") {
+			r.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, "\\!") || strings.HasPrefix(line, "\\#") {
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			r.dirOnly = true
+			line = strings.TrimSuffix(line, "/")
+		}
+		re, err := regexp.Compile(gitignoreToRegex(line))
+		if err != nil || line == "" {
+			continue
+		}
+		r.re = re
+		g.rules = append(g.rules, r)
+	}
+	return g
+}
+
+// gitignoreToRegex converts a .gitignore pattern to a regular expression
+// matching slash-separated paths relative to the directory of the file.
+// Patterns without a slash (other than a trailing one) match names in any
+// subdirectory, other patterns are relative to the directory.
+func gitignoreToRegex(pattern string) string {
+	var re strings.Builder
+	if strings.Contains(pattern, "/") {
+		re.WriteString("^")
+		pattern = strings.TrimPrefix(pattern, "/")
+	} else {
+		re.WriteString("^(?:.*/)?")
+	}
+
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case c == '\\' && i+1 < len(pattern):
+			i++
+			re.WriteString(regexp.QuoteMeta(string(pattern[i])))
+		case strings.HasPrefix(pattern[i:], "**/"):
+			re.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**") && i+2 == len(pattern):
+			re.WriteString(".*")
+			i++
+		case c == '*':
+			re.WriteString("[^/]*")
+		case c == '?':
+			re.WriteString("[^/]")
+		case c == '[':
+			j := strings.IndexByte(pattern[i+1:], ']')
+			if j < 0 {
+				re.WriteString("\\[")
+				break
+			}
+			class := pattern[i+1 : i+1+j]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			re.WriteString("[" + strings.ReplaceAll(class, "\\", "\\\\") + "]")
+			i += j + 1
+		default:
+			re.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	re.WriteString("$")
+	return re.String()
+}
+
+// Match returns whether the given path, relative to the directory of the
+// .gitignore file, is ignored, and whether a pattern matched it at all (a
+// negated pattern may re-include it)
+func (g *Gitignore) Match(rel string, isDir bool) (ignored, matched bool) {
+	for _, r := range g.rules {
+		if r.dirOnly && !isDir {
+			continue
+		}
+		if r.re.MatchString(rel) {
+			ignored, matched = !r.negate, true
+		}
+	}
+	return ignored, matched
+}
+
+// errStopWalk stops WalkProject
+var errStopWalk = errors.New("stop walk")
+
+// WalkProject calls fn with the path of each file below root, relative to
+// it, skipping the .git directories and the paths ignored by the .gitignore
+// files of the directories. The walk stops when fn returns false or when
+// the done channel is closed.
+func WalkProject(root string, done <-chan struct{}, fn func(rel string) bool) {
+	type ignoreFile struct {
+		dir string
+		g   *Gitignore
+	}
+	var ignores []ignoreFile
+
+	ignored := func(rel string, isDir bool) bool {
+		result := false
+		for _, ig := range ignores {
+			if ig.dir != "." && !strings.HasPrefix(rel, ig.dir+"/") {
+				continue
+			}
+			sub := rel
+			if ig.dir != "." {
+				sub = rel[len(ig.dir)+1:]
+			}
+			// the files deeper in the tree override their parents
+			if ign, ok := ig.g.Match(sub, isDir); ok {
+				result = ign
+			}
+		}
+		return result
+	}
+
+	filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		select {
+		case <-done:
+			return errStopWalk
+		default:
+		}
+		if err != nil {
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+
+		if d.IsDir() {
+			if rel != "." && (d.Name() == ".git" || ignored(rel, true)) {
+				return filepath.SkipDir
+			}
+			// the ignore files of the directories which were left are
+			// dropped, the walk being depth first
+			for len(ignores) > 0 {
+				last := ignores[len(ignores)-1].dir
+				if last == "." || rel == last || strings.HasPrefix(rel, last+"/") {
+					break
+				}
+				ignores = ignores[:len(ignores)-1]
+			}
+			if data, err := os.ReadFile(filepath.Join(p, ".gitignore")); err == nil {
+				ignores = append(ignores, ignoreFile{path.Clean(rel), ParseGitignore(string(data))})
+			}
+			return nil
+		}
+		if ignored(rel, false) {
+			return nil
+		}
+		if !fn(rel) {
+			return errStopWalk
+		}
+		return nil
+	})
+}
@@ -1,6 +1,8 @@
 package util
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -31,3 +33,75 @@ func TestSliceVisualEnd(t *testing.T) {
 	assert.Equal(t, []byte("ello"), slc)
 	assert.Equal(t, 0, n)
 }
+
+func TestFuzzyMatch(t *testing.T) {
+	_, pos, ok := FuzzyMatch("fb", "fooBar")
+	assert.True(t, ok)
+	assert.Equal(t, []int{0, 3}, pos)
+
+	_, _, ok = FuzzyMatch("fB", "foobar")
+	assert.False(t, ok)
+	_, _, ok = FuzzyMatch("abc", "acb")
+	assert.False(t, ok)
+
+	prefix, _, _ := FuzzyMatch("buf", "buffer")
+	boundary, _, _ := FuzzyMatch("buf", "NewBufPane")
+	scattered, _, _ := FuzzyMatch("buf", "bottom_useful")
+	assert.Greater(t, prefix, boundary)
+	assert.Greater(t, boundary, scattered)
+
+	score, pos, ok := FuzzyMatch("", "x")
+	assert.True(t, ok)
+	assert.Equal(t, 0, score)
+	assert.Empty(t, pos)
+}
+
+func TestGitignore(t *testing.T) {
+	g := ParseGitignore("# comment\n*.o\nbuild/\n/root.txt\ndocs/**/*.md\n!keep.o\n")
+
+	ign, _ := g.Match("a/b/x.o", false)
+	assert.True(t, ign)
+	ign, _ = g.Match("keep.o", false)
+	assert.False(t, ign)
+	ign, _ = g.Match("src/build", true)
+	assert.True(t, ign)
+	ign, _ = g.Match("src/build", false)
+	assert.False(t, ign)
+	ign, _ = g.Match("root.txt", false)
+	assert.True(t, ign)
+	ign, _ = g.Match("a/root.txt", false)
+	assert.False(t, ign)
+	ign, _ = g.Match("docs/a/b/c.md", false)
+	assert.True(t, ign)
+	_, matched := g.Match("main.go", false)
+	assert.False(t, matched)
+}
+
+func TestWalkProject(t *testing.T) {
+	root := t.TempDir()
+	for _, f := range []string{"main.go", "x.log", "sub/a.go", "sub/b.tmp", "sub/keep.log", "build/out", ".git/HEAD"} {
+		p := filepath.Join(root, f)
+		os.MkdirAll(filepath.Dir(p), 0755)
+		os.WriteFile(p, nil, 0644)
+	}
+	os.WriteFile(filepath.Join(root, ".gitignore"), []byte("*.log\nbuild/\n"), 0644)
+	os.WriteFile(filepath.Join(root, "sub", ".gitignore"), []byte("*.tmp\n!keep.log\n"), 0644)
+
+	var files []string
+	WalkProject(root, nil, func(rel string) bool {
+		files = append(files, rel)
+		return true
+	})
+	assert.Equal(t, []string{".gitignore", "main.go", "sub/.gitignore", "sub/a.go", "sub/keep.log"}, files)
+}
+
+func TestParseGitStatus(t *testing.T) {
+	out := " M main.go\x00?? new.go\x00R  b.go\x00a.go\x00!! build/\x00"
+	status := parseGitStatus("/repo", out)
+	assert.Equal(t, map[string]string{
+		filepath.Join("/repo", "main.go"): " M",
+		filepath.Join("/repo", "new.go"):  "??",
+		filepath.Join("/repo", "b.go"):    "R ",
+		filepath.Join("/repo", "build"):   "!!",
+	}, status)
+}
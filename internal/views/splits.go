@@ -438,6 +438,28 @@ func (n *Node) VSplit(right bool) uint64 {
 	return n.hVSplit(0, right)
 }
 
+// DockLeft creates a vertical split on the left of this node which spans
+// its whole height, whatever the splits inside it, and returns the id of
+// the new split. The new split is as wide as the node's other children.
+func (n *Node) DockLeft() uint64 {
+	if n.IsLeaf() {
+		n.Kind = STHoriz
+		return n.hVSplit(0, false)
+	}
+	if n.Kind == STVert {
+		// the children are moved to a new node so that this one becomes
+		// a horizontal split
+		c := NewNode(STVert, n.X, n.Y, n.W, n.H, n, NewID())
+		c.children = n.children
+		for _, cc := range c.children {
+			cc.parent = c
+		}
+		n.Kind = STHoriz
+		n.children = []*Node{c}
+	}
+	return n.hVSplit(0, false)
+}
+
 // unsplits the child of a split
 func (n *Node) unsplit(i int, h bool) {
 	copy(n.children[i:], n.children[i+1:])
@@ -14,3 +14,27 @@ func TestHSplit(t *testing.T) {
 
 	fmt.Println(root.String())
 }
+
+func TestDockLeft(t *testing.T) {
+	root := NewRoot(0, 0, 80, 24)
+	bottom := root.HSplit(true)
+
+	id := root.DockLeft()
+	dock := root.GetNode(id)
+	if dock == nil || dock.X != 0 || dock.H != 24 {
+		t.Fatalf("dock has the wrong position: %v", root)
+	}
+	if b := root.GetNode(bottom); b.X != dock.W || b.Y != 12 {
+		t.Errorf("bottom split has the wrong position: %v", root)
+	}
+
+	dock.ResizeSplit(20)
+	if dock.W != 20 || root.GetNode(root.id).X != 20 {
+		t.Errorf("dock was not resized: %v", root)
+	}
+
+	dock.Unsplit()
+	if b := root.GetNode(bottom); b.X != 0 || b.W != 80 {
+		t.Errorf("bottom split was not restored: %v", root)
+	}
+}
@@ -0,0 +1,31 @@
+package highlight
+
+import (
+	"regexp"
+	"strings"
+)
+
+// keywordList matches the lists of words of the syntax rules, such as
+// \b(break|case|continue)\b
+var keywordList = regexp.MustCompile(`\\b\((?:\?:)?([A-Za-z_][A-Za-z0-9_|]*)\)\\b`)
+
+var keyword = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]+$`)
+
+// Keywords returns the keywords of the syntax definition, which are the
+// words listed in the patterns of its rules, with the name of their group
+func (d *Def) Keywords() map[string]string {
+	keywords := make(map[string]string)
+	if d == nil || d.rules == nil {
+		return keywords
+	}
+	for _, p := range d.rules.patterns {
+		for _, m := range keywordList.FindAllStringSubmatch(p.regex.String(), -1) {
+			for _, w := range strings.Split(m[1], "|") {
+				if _, ok := keywords[w]; !ok && keyword.MatchString(w) {
+					keywords[w] = p.group.String()
+				}
+			}
+		}
+	}
+	return keywords
+}
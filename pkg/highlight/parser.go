@@ -27,6 +27,17 @@ func (g Group) String() string {
 	return ""
 }
 
+// GetGroup returns the group with the given name, registering it first
+// if no syntax file has defined it yet
+func GetGroup(name string) Group {
+	if g, ok := Groups[name]; ok {
+		return g
+	}
+	numGroups++
+	Groups[name] = numGroups
+	return numGroups
+}
+
 // A Def is a full syntax definition for a language
 // It has a filetype, information about how to detect the filetype based
 // on filename or header (the first line of the file)
@@ -34,6 +45,15 @@ func (g Group) String() string {
 type Def struct {
 	*Header
 	rules *rules
+
+	// IndentRegex matches lines after which the indentation increases and
+	// OutdentRegex matches lines which are indented one level less than
+	// the line before them. Either may be nil.
+	IndentRegex  *regexp.Regexp
+	OutdentRegex *regexp.Regexp
+	// SignificantIndent is true for languages in which the indentation is
+	// part of the syntax, so that it must not be fixed by the rules
+	SignificantIndent bool
 }
 
 type Header struct {
@@ -253,6 +273,22 @@ func ParseDef(f *File, header *Header) (s *Def, err error) {
 			}
 
 			s.rules = rules
+		} else if k == "indent" || k == "outdent" {
+			regex, err := regexp.Compile(v.(string))
+			if err != nil {
+				return nil, err
+			}
+			if k == "indent" {
+				s.IndentRegex = regex
+			} else {
+				s.OutdentRegex = regex
+			}
+		} else if k == "significant-indent" {
+			significant, ok := v.(bool)
+			if !ok {
+				return nil, errors.New("significant-indent must be a boolean")
+			}
+			s.SignificantIndent = significant
 		}
 	}
 
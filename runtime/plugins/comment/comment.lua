@@ -205,7 +205,7 @@ function string.starts(String,Start)
 end
 
 function init()
-    config.MakeCommand("comment", comment, config.NoComplete)
+    config.MakeCommand("comment", comment, config.NoComplete, "Toggle the comment of the current or selected lines")
     config.TryBindKey("Alt-/", "lua:comment.comment", false)
     config.TryBindKey("CtrlUnderscore", "lua:comment.comment", false)
     config.AddRuntimeFile("comment", config.RTHelp, "help/comment.md")
@@ -59,6 +59,10 @@ function removeLinter(name)
     linters[name] = nil
 end
 
+-- other plugins and init.lua can call linter.makeLinter and linter.removeLinter
+exports.makeLinter = makeLinter
+exports.removeLinter = removeLinter
+
 function preinit()
     local devnull = "/dev/null"
     if runtime.GOOS == "windows" then
@@ -94,7 +98,7 @@ function preinit()
     config.MakeCommand("lint", function(bp, args)
         bp:Save()
         runLinter(bp.Buf)
-    end, config.NoComplete)
+    end, config.NoComplete, "Save the buffer and run its linters")
 
     config.AddRuntimeFile("linter", config.RTHelp, "help/linter.md")
 end
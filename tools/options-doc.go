@@ -0,0 +1,27 @@
+//go:build ignore
+// +build ignore
+
+package main
+
+import (
+	"log"
+	"os"
+
+	"github.com/zyedidia/micro/v2/internal/config"
+)
+
+// Regenerates the option list of the given options.md from the option
+// registry
+func main() {
+	if len(os.Args) != 2 {
+		log.Fatal("usage: options-doc path/to/options.md")
+	}
+	doc, err := os.ReadFile(os.Args[1])
+	if err != nil {
+		log.Fatal(err)
+	}
+	out := config.GenerateOptionsHelp(string(doc), true)
+	if err := os.WriteFile(os.Args[1], []byte(out), 0644); err != nil {
+		log.Fatal(err)
+	}
+}